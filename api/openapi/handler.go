@@ -0,0 +1,50 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	swaggerfiles "github.com/swaggo/files/v2"
+)
+
+// DocsPath — префикс страницы Swagger UI.
+const DocsPath = "/docs/"
+
+// swaggerInitializer заменяет одноименный файл дистрибутива, чтобы
+// Swagger UI открывал описание этого API.
+const swaggerInitializer = `window.onload = function () {
+  window.ui = SwaggerUIBundle({
+    url: "/openapi.json",
+    dom_id: "#swagger-ui",
+    deepLinking: true,
+    presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+    layout: "StandaloneLayout"
+  });
+};
+`
+
+// SpecHandler отдает описание API в JSON. Документ сериализуется один раз.
+func SpecHandler() http.Handler {
+	spec, err := json.MarshalIndent(Document(), "", "  ")
+	if err != nil {
+		panic("openapi: marshal document: " + err.Error())
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(spec)
+	})
+}
+
+// UIHandler отдает встроенную в бинарник страницу Swagger UI по пути DocsPath.
+func UIHandler() http.Handler {
+	files := http.StripPrefix(strings.TrimSuffix(DocsPath, "/"), http.FileServerFS(swaggerfiles.FS))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == DocsPath+"swagger-initializer.js" {
+			w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+			w.Write([]byte(swaggerInitializer))
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,118 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// object — узел JSON-документа OpenAPI.
+type object = map[string]interface{}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaSet строит JSON Schema по типам Go так же, как их сериализует
+// encoding/json: учитывает теги json, пропускает поля с "-", указатели
+// допускают null. Именованные компоненты подставляются через $ref.
+type schemaSet struct {
+	names map[reflect.Type]string
+	// fields уточняет схемы полей: "Component.Field" -> дополнительные ключи.
+	fields map[string]object
+	// readOnly — поля, которые заполняет сервер.
+	readOnly map[string]bool
+	// required — обязательные поля компонентов.
+	required map[string][]string
+}
+
+// components возвращает схемы всех именованных типов.
+func (s *schemaSet) components() object {
+	out := object{}
+	for t, name := range s.names {
+		out[name] = s.structSchema(name, t)
+	}
+	return out
+}
+
+// ref возвращает ссылку на компонент.
+func ref(name string) object {
+	return object{"$ref": "#/components/schemas/" + name}
+}
+
+// of возвращает схему типа t.
+func (s *schemaSet) of(t reflect.Type) object {
+	if name, ok := s.names[t]; ok {
+		return ref(name)
+	}
+	switch {
+	case t == timeType:
+		return object{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Pointer:
+		inner := s.of(t.Elem())
+		if typ, ok := inner["type"].(string); ok {
+			inner["type"] = []interface{}{typ, "null"}
+			return inner
+		}
+		return object{"oneOf": []interface{}{inner, object{"type": "null"}}}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return object{"type": "string"}
+	case reflect.Bool:
+		return object{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return object{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return object{"type": "number"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return object{"type": "string", "contentEncoding": "base64"}
+		}
+		return object{"type": "array", "items": s.of(t.Elem())}
+	case reflect.Map:
+		return object{"type": "object", "additionalProperties": s.of(t.Elem())}
+	case reflect.Struct:
+		return s.structSchema("", t)
+	}
+	return object{}
+}
+
+// structSchema описывает поля структуры; name — имя компонента для уточнений.
+func (s *schemaSet) structSchema(name string, t reflect.Type) object {
+	properties := object{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		jsonName, omit := jsonField(field)
+		if omit {
+			continue
+		}
+		schema := s.of(field.Type)
+		if s.readOnly[field.Name] {
+			schema["readOnly"] = true
+		}
+		for k, v := range s.fields[name+"."+field.Name] {
+			schema[k] = v
+		}
+		properties[jsonName] = schema
+	}
+	schema := object{"type": "object", "properties": properties}
+	if required := s.required[name]; len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// jsonField возвращает имя поля в JSON и признак того, что поле не сериализуется.
+func jsonField(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name, false
+	}
+	return field.Name, false
+}
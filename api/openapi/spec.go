@@ -0,0 +1,263 @@
+// Package openapi описывает публичный HTTP API в формате OpenAPI 3.1
+// и отдает описание вместе со страницей Swagger UI.
+package openapi
+
+import (
+	http2 "Projectapirest/internal/controller/http"
+	"Projectapirest/internal/entity"
+	"Projectapirest/internal/health"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Version — версия описываемого API.
+const Version = "1.0.0"
+
+// operation описывает один маршрут. path использует синтаксис шаблонов
+// http.ServeMux, который совпадает с шаблонами путей OpenAPI.
+type operation struct {
+	method  string
+	path    string
+	id      string
+	summary string
+	tag     string
+	params  []string // имена компонентов parameters
+	body    string   // схема тела запроса
+	status  int
+	result  object // схема успешного ответа; nil — без тела
+	errors  []int
+	public  bool // доступен без API-ключа
+}
+
+// Коды ошибок, общие для всех маршрутов API.
+var commonErrors = []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests, http.StatusInternalServerError}
+
+// operations перечисляет маршруты SetupUserRoutes, SetupProductRoutes,
+// SetupGatewayRoutes, SetupAPIKeyRoutes и SetupHealthRoutes. Тест сверяет
+// список с маршрутами.
+var operations = []operation{
+	{method: "GET", path: "/api/v1/users", id: "listUsers", summary: "List users", tag: "users",
+		params: []string{"Limit", "Offset"}, status: 200, result: arrayOf("User"), errors: []int{400}},
+	{method: "POST", path: "/api/v1/users", id: "createUser", summary: "Create a user", tag: "users",
+		params: []string{"IdempotencyKey"}, body: "User", status: 201, result: ref("User"), errors: []int{400, 409}},
+	{method: "GET", path: "/api/v1/users/{id}", id: "getUser", summary: "Get a user", tag: "users",
+		params: []string{"UserID"}, status: 200, result: ref("User"), errors: []int{400, 404}},
+	{method: "PUT", path: "/api/v1/users/{id}", id: "updateUser", summary: "Update a user", tag: "users",
+		params: []string{"UserID"}, body: "User", status: 200, result: ref("User"), errors: []int{400, 404, 409}},
+	{method: "DELETE", path: "/api/v1/users/{id}", id: "deleteUser", summary: "Delete a user", tag: "users",
+		params: []string{"UserID"}, status: 204, errors: []int{400, 404, 422}},
+	// Только в режиме HTTP_API=gateway
+	{method: "GET", path: "/api/v1/users/{id}/products", id: "listUserProducts", summary: "List products of a user (REST gateway only)", tag: "products",
+		params: []string{"UserID"}, status: 200, result: arrayOf("Product"), errors: []int{400}},
+
+	{method: "GET", path: "/api/v1/users/{id}/api-keys", id: "listAPIKeys", summary: "List API keys of a user", tag: "api-keys",
+		params: []string{"UserID"}, status: 200, result: arrayOf("APIKey"), errors: []int{400}},
+	{method: "POST", path: "/api/v1/users/{id}/api-keys", id: "createAPIKey", summary: "Issue an API key", tag: "api-keys",
+		params: []string{"UserID"}, body: "CreateAPIKeyRequest", status: 201, result: ref("CreateAPIKeyResponse"), errors: []int{400, 404}},
+	{method: "DELETE", path: "/api/v1/users/{id}/api-keys/{keyID}", id: "revokeAPIKey", summary: "Revoke an API key", tag: "api-keys",
+		params: []string{"UserID", "KeyID"}, status: 204, errors: []int{400, 404}},
+
+	{method: "GET", path: "/api/v1/products", id: "listProducts", summary: "List products", tag: "products",
+		params: []string{"Limit", "Offset"}, status: 200, result: arrayOf("Product"), errors: []int{400}},
+	{method: "POST", path: "/api/v1/products", id: "createProduct", summary: "Create a product", tag: "products",
+		params: []string{"IdempotencyKey"}, body: "Product", status: 201, result: ref("Product"), errors: []int{400, 409, 422}},
+	{method: "GET", path: "/api/v1/products/{id}", id: "getProduct", summary: "Get a product", tag: "products",
+		params: []string{"ProductID"}, status: 200, result: ref("Product"), errors: []int{400, 404}},
+	{method: "PUT", path: "/api/v1/products/{id}", id: "updateProduct", summary: "Update a product", tag: "products",
+		params: []string{"ProductID"}, body: "Product", status: 200, result: ref("Product"), errors: []int{400, 404, 422}},
+	{method: "DELETE", path: "/api/v1/products/{id}", id: "deleteProduct", summary: "Delete a product", tag: "products",
+		params: []string{"ProductID"}, status: 204, errors: []int{400, 404}},
+
+	{method: "GET", path: "/healthz", id: "liveness", summary: "Liveness probe", tag: "health",
+		status: 200, result: object{"type": "object", "properties": object{"status": object{"type": "string"}}}, public: true},
+	{method: "GET", path: "/readyz", id: "readiness", summary: "Readiness probe", tag: "health",
+		status: 200, result: ref("HealthReport"), errors: []int{503}, public: true},
+}
+
+// schemas — типы, из которых выводятся схемы компонентов.
+var schemas = &schemaSet{
+	names: map[reflect.Type]string{
+		reflect.TypeOf(entity.User{}):                "User",
+		reflect.TypeOf(entity.Product{}):             "Product",
+		reflect.TypeOf(entity.APIKey{}):              "APIKey",
+		reflect.TypeOf(http2.CreateAPIKeyRequest{}):  "CreateAPIKeyRequest",
+		reflect.TypeOf(http2.CreateAPIKeyResponse{}): "CreateAPIKeyResponse",
+		reflect.TypeOf(health.Report{}):              "HealthReport",
+	},
+	fields: map[string]object{
+		"User.Email":                 {"format": "email"},
+		"Product.UserID":             {"description": "Owner ID; 0 means no owner"},
+		"APIKey.Prefix":              {"description": "First characters of the key for display"},
+		"CreateAPIKeyRequest.Scopes": {"items": object{"type": "string", "enum": []string{"read", "write", "admin"}}},
+		"CreateAPIKeyResponse.Token": {"description": "Plain API key; shown only once"},
+	},
+	readOnly: map[string]bool{"ID": true, "CreatedAt": true, "UpdatedAt": true},
+	required: map[string][]string{
+		"User":                {"Name", "Email"},
+		"Product":             {"Name", "Price"},
+		"CreateAPIKeyRequest": {"Name", "Scopes"},
+	},
+}
+
+// Document строит описание API.
+func Document() object {
+	paths := object{}
+	for _, op := range operations {
+		item, _ := paths[op.path].(object)
+		if item == nil {
+			item = object{}
+			paths[op.path] = item
+		}
+		item[strings.ToLower(op.method)] = op.document()
+	}
+
+	return object{
+		"openapi": "3.1.0",
+		"info": object{
+			"title":       "Projectapirest",
+			"version":     Version,
+			"description": "REST API for users, products and API keys.",
+		},
+		"servers": []interface{}{object{"url": "/"}},
+		"tags": []interface{}{
+			object{"name": "users"},
+			object{"name": "products"},
+			object{"name": "api-keys"},
+			object{"name": "health"},
+		},
+		// Ключ необязателен, пока не включен AUTH_REQUIRED
+		"security": []interface{}{object{"ApiKeyAuth": []string{}}, object{}},
+		"paths":    paths,
+		"components": object{
+			"schemas":         withError(schemas.components()),
+			"parameters":      parameters(),
+			"responses":       errorResponses(),
+			"headers":         headers(),
+			"securitySchemes": object{"ApiKeyAuth": apiKeyScheme()},
+		},
+	}
+}
+
+// document описывает операцию.
+func (op operation) document() object {
+	doc := object{
+		"operationId": op.id,
+		"summary":     op.summary,
+		"tags":        []string{op.tag},
+	}
+	if len(op.params) > 0 {
+		params := make([]interface{}, 0, len(op.params))
+		for _, name := range op.params {
+			params = append(params, object{"$ref": "#/components/parameters/" + name})
+		}
+		doc["parameters"] = params
+	}
+	if op.body != "" {
+		doc["requestBody"] = object{
+			"required": true,
+			"content":  object{"application/json": object{"schema": ref(op.body)}},
+		}
+	}
+
+	success := object{"description": http.StatusText(op.status)}
+	if op.result != nil {
+		success["content"] = object{"application/json": object{"schema": op.result}}
+	}
+	responses := object{strconv.Itoa(op.status): success}
+	errors := op.errors
+	if op.public {
+		doc["security"] = []interface{}{}
+	} else {
+		errors = append(append([]int{}, errors...), commonErrors...)
+	}
+	for _, code := range errors {
+		responses[strconv.Itoa(code)] = object{"$ref": "#/components/responses/" + responseName(code)}
+	}
+	doc["responses"] = responses
+	return doc
+}
+
+// arrayOf возвращает схему массива компонентов.
+func arrayOf(name string) object {
+	return object{"type": "array", "items": ref(name)}
+}
+
+// withError добавляет схему текстовой ошибки, которую возвращает http.Error.
+func withError(components object) object {
+	components["Error"] = object{"type": "string", "description": "Human-readable error message"}
+	return components
+}
+
+// parameters описывает параметры пути, страницы и идемпотентности.
+func parameters() object {
+	id := func(name, description string) object {
+		return object{"name": name, "in": "path", "required": true, "description": description,
+			"schema": object{"type": "integer", "minimum": 1}}
+	}
+	return object{
+		"UserID":    id("id", "User ID"),
+		"ProductID": id("id", "Product ID"),
+		"KeyID":     id("keyID", "API key ID"),
+		"Limit": object{"name": "limit", "in": "query", "description": "Page size; without limit and offset the whole list is returned",
+			"schema": object{"type": "integer", "minimum": 1, "maximum": 1000}},
+		"Offset": object{"name": "offset", "in": "query", "description": "Number of items to skip; requires limit",
+			"schema": object{"type": "integer", "minimum": 0, "default": 0}},
+		"IdempotencyKey": object{"name": "Idempotency-Key", "in": "header",
+			"description": "Repeating a request with the same key returns the stored response",
+			"schema":      object{"type": "string", "maxLength": 255}},
+	}
+}
+
+// responseName возвращает имя компонента ответа для кода ошибки.
+func responseName(code int) string {
+	return strings.ReplaceAll(http.StatusText(code), " ", "")
+}
+
+// errorResponses описывает ответы с ошибками.
+func errorResponses() object {
+	out := object{}
+	for _, code := range []int{400, 401, 403, 404, 409, 422, 429, 500, 503} {
+		response := object{
+			"description": http.StatusText(code),
+			"content":     object{"text/plain": object{"schema": ref("Error")}},
+		}
+		switch code {
+		case http.StatusUnauthorized:
+			response["headers"] = object{"WWW-Authenticate": object{"schema": object{"type": "string", "const": "ApiKey"}}}
+		case http.StatusTooManyRequests:
+			response["headers"] = object{
+				"Retry-After":         object{"$ref": "#/components/headers/RetryAfter"},
+				"RateLimit-Limit":     object{"$ref": "#/components/headers/RateLimitLimit"},
+				"RateLimit-Remaining": object{"$ref": "#/components/headers/RateLimitRemaining"},
+				"RateLimit-Reset":     object{"$ref": "#/components/headers/RateLimitReset"},
+			}
+		}
+		out[responseName(code)] = response
+	}
+	return out
+}
+
+// headers описывает заголовки ограничения частоты запросов.
+func headers() object {
+	integer := func(description string) object {
+		return object{"description": description, "schema": object{"type": "integer", "minimum": 0}}
+	}
+	return object{
+		"RetryAfter":         integer("Seconds to wait before retrying"),
+		"RateLimitLimit":     integer("Requests allowed in the window"),
+		"RateLimitRemaining": integer("Requests left in the window"),
+		"RateLimitReset":     integer("Seconds until the limit is fully restored"),
+	}
+}
+
+// apiKeyScheme описывает заголовок "Authorization: ApiKey <ключ>".
+func apiKeyScheme() object {
+	return object{
+		"type":        "apiKey",
+		"in":          "header",
+		"name":        "Authorization",
+		"description": "API key in the form `ApiKey <key>`. GET requires the read scope, other methods require write. API keys are managed with a key of the same user or one with the admin scope.",
+	}
+}
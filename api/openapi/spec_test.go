@@ -0,0 +1,201 @@
+package openapi
+
+import (
+	"Projectapirest/api/routes"
+	http2 "Projectapirest/internal/controller/http"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// documentedRouteFiles — файлы маршрутов публичного API. Служебные
+// маршруты администрирования и документации в описание не входят.
+var documentedRouteFiles = []string{
+	"../routes/user_routes.go",
+	"../routes/product_routes.go",
+	"../routes/gateway_routes.go",
+	"../routes/api_key_routes.go",
+	"../routes/health_routes.go",
+}
+
+// registeredPatterns собирает шаблоны из вызовов mux.Handle и mux.HandleFunc.
+func registeredPatterns(t *testing.T) []string {
+	t.Helper()
+	var patterns []string
+	fset := token.NewFileSet()
+	for _, file := range documentedRouteFiles {
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			t.Fatalf("parse %s: %v", file, err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || (sel.Sel.Name != "Handle" && sel.Sel.Name != "HandleFunc") {
+				return true
+			}
+			lit, ok := call.Args[0].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				t.Errorf("%s: route pattern is not a string literal", fset.Position(call.Pos()))
+				return true
+			}
+			pattern, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			patterns = append(patterns, pattern)
+			return true
+		})
+	}
+	sort.Strings(patterns)
+	return patterns
+}
+
+func documentedPatterns() []string {
+	patterns := make([]string, 0, len(operations))
+	for _, op := range operations {
+		patterns = append(patterns, op.method+" "+op.path)
+	}
+	sort.Strings(patterns)
+	return patterns
+}
+
+func TestSpecMatchesRoutes(t *testing.T) {
+	registered := registeredPatterns(t)
+	documented := documentedPatterns()
+
+	missing, stale := diff(registered, documented), diff(documented, registered)
+	for _, pattern := range missing {
+		t.Errorf("route %q is not described in the OpenAPI document", pattern)
+	}
+	for _, pattern := range stale {
+		t.Errorf("OpenAPI operation %q has no route", pattern)
+	}
+}
+
+func TestSpecOperationsResolveToRoutes(t *testing.T) {
+	routeOf := routes.PatternOf(
+		routes.SetupUserRoutes(http2.NewUserController(nil)),
+		routes.SetupProductRoutes(http2.NewProductController(nil)),
+		routes.SetupGatewayRoutes(http.NotFoundHandler()),
+		routes.SetupAPIKeyRoutes(http2.NewAPIKeyController(nil)),
+		routes.SetupHealthRoutes(http2.NewHealthController(nil)),
+	)
+	for _, op := range operations {
+		path := strings.NewReplacer("{id}", "1", "{keyID}", "2").Replace(op.path)
+		r := httptest.NewRequest(op.method, path, nil)
+		if got, want := routeOf(r), op.method+" "+op.path; got != want {
+			t.Errorf("%s %s resolves to %q, want %q", op.method, path, got, want)
+		}
+	}
+}
+
+func TestSpecReferencesResolve(t *testing.T) {
+	data, err := json.Marshal(Document())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["openapi"] != "3.1.0" {
+		t.Errorf("openapi = %v, want 3.1.0", doc["openapi"])
+	}
+
+	var walk func(node interface{})
+	walk = func(node interface{}) {
+		switch v := node.(type) {
+		case map[string]interface{}:
+			if target, ok := v["$ref"].(string); ok && resolve(doc, target) == nil {
+				t.Errorf("unresolved reference %q", target)
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+}
+
+func TestSpecSchemasFollowJSONEncoding(t *testing.T) {
+	components := schemas.components()
+	apiKey := components["APIKey"].(object)["properties"].(object)
+	if _, ok := apiKey["Hash"]; ok {
+		t.Error("APIKey schema exposes Hash, which is not serialized")
+	}
+	if got := apiKey["ExpiresAt"].(object)["type"]; !equalJSON(got, []interface{}{"string", "null"}) {
+		t.Errorf("ExpiresAt type = %v, want nullable string", got)
+	}
+	report := components["HealthReport"].(object)["properties"].(object)
+	if _, ok := report["checks"]; !ok {
+		t.Errorf("HealthReport properties = %v, want json tag names", report)
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	rec := httptest.NewRecorder()
+	SpecHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK || !json.Valid(rec.Body.Bytes()) {
+		t.Errorf("spec: status %d, valid JSON %v", rec.Code, json.Valid(rec.Body.Bytes()))
+	}
+
+	for path, want := range map[string]string{
+		DocsPath:                            "swagger-ui",
+		DocsPath + "swagger-initializer.js": "/openapi.json",
+	} {
+		rec := httptest.NewRecorder()
+		UIHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("%s: status %d, body does not contain %q", path, rec.Code, want)
+		}
+	}
+}
+
+// resolve находит узел документа по локальной ссылке "#/a/b".
+func resolve(doc map[string]interface{}, target string) interface{} {
+	var node interface{} = doc
+	for _, part := range strings.Split(strings.TrimPrefix(target, "#/"), "/") {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node = m[part]
+	}
+	return node
+}
+
+// diff возвращает элементы a, которых нет в b.
+func diff(a, b []string) []string {
+	set := make(map[string]bool, len(b))
+	for _, s := range b {
+		set[s] = true
+	}
+	var out []string
+	for _, s := range a {
+		if !set[s] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func equalJSON(a, b interface{}) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	http2 "Projectapirest/internal/controller/http"
+	"net/http"
+)
+
+// SetupAdminRoutes настраивает служебные маршруты: метрики, подробный отчет
+// о готовности и управление кешем
+func SetupAdminRoutes(metricsHandler http.Handler, healthController *http2.HealthController, cacheController *http2.CacheAdminController) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.Handle("GET /metrics", metricsHandler)                             // Метрики в формате Prometheus
+	mux.HandleFunc("GET /admin/health", healthController.ReadinessDetails) // GET для отчета о готовности с ошибками
+
+	// Маршруты для просмотра и сброса кеша
+	mux.HandleFunc("GET /admin/cache/keys", cacheController.ListKeys)              // GET для списка ключей по префиксу
+	mux.HandleFunc("GET /admin/cache/keys/{key...}", cacheController.GetKey)       // GET для состояния ключа на уровнях
+	mux.HandleFunc("DELETE /admin/cache/keys/{key...}", cacheController.DeleteKey) // DELETE для удаления ключа
+	mux.HandleFunc("POST /admin/cache/flush", cacheController.Flush)               // POST для сброса ключей по префиксу
+
+	return mux
+}
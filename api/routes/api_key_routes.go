@@ -0,0 +1,18 @@
+package routes
+
+import (
+	http2 "Projectapirest/internal/controller/http"
+	"net/http"
+)
+
+// SetupAPIKeyRoutes настраивает маршруты для управления API-ключами пользователя
+func SetupAPIKeyRoutes(apiKeyController *http2.APIKeyController) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	// Маршруты для работы с API-ключами
+	mux.HandleFunc("GET /api/v1/users/{id}/api-keys", apiKeyController.ListAPIKeys)             // GET для ключей пользователя
+	mux.HandleFunc("POST /api/v1/users/{id}/api-keys", apiKeyController.CreateAPIKey)           // POST для выпуска ключа
+	mux.HandleFunc("DELETE /api/v1/users/{id}/api-keys/{keyID}", apiKeyController.RevokeAPIKey) // DELETE для отзыва ключа
+
+	return mux
+}
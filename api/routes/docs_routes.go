@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"net/http"
+)
+
+// SetupDocsRoutes настраивает маршруты документации API
+func SetupDocsRoutes(specHandler, uiHandler http.Handler) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.Handle("GET /openapi.json", specHandler) // Описание API в формате OpenAPI 3.1
+	mux.Handle("GET /docs/", uiHandler)          // Страница Swagger UI
+
+	return mux
+}
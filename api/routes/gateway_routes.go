@@ -0,0 +1,27 @@
+package routes
+
+import "net/http"
+
+// SetupGatewayRoutes настраивает маршруты REST-шлюза. Пути совпадают
+// с SetupUserRoutes и SetupProductRoutes, поэтому шлюз заменяет контроллеры
+// без изменений для клиентов; список продуктов пользователя есть только в шлюзе.
+func SetupGatewayRoutes(gateway http.Handler) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	// Маршруты пользователей
+	mux.Handle("GET /api/v1/users", gateway)
+	mux.Handle("POST /api/v1/users", gateway)
+	mux.Handle("GET /api/v1/users/{id}", gateway)
+	mux.Handle("PUT /api/v1/users/{id}", gateway)
+	mux.Handle("DELETE /api/v1/users/{id}", gateway)
+	mux.Handle("GET /api/v1/users/{id}/products", gateway)
+
+	// Маршруты продуктов
+	mux.Handle("GET /api/v1/products", gateway)
+	mux.Handle("POST /api/v1/products", gateway)
+	mux.Handle("GET /api/v1/products/{id}", gateway)
+	mux.Handle("PUT /api/v1/products/{id}", gateway)
+	mux.Handle("DELETE /api/v1/products/{id}", gateway)
+
+	return mux
+}
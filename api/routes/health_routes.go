@@ -0,0 +1,16 @@
+package routes
+
+import (
+	http2 "Projectapirest/internal/controller/http"
+	"net/http"
+)
+
+// SetupHealthRoutes настраивает маршруты проверок живости и готовности
+func SetupHealthRoutes(healthController *http2.HealthController) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /healthz", healthController.Liveness) // GET для проверки живости
+	mux.HandleFunc("GET /readyz", healthController.Readiness) // GET для проверки готовности
+
+	return mux
+}
@@ -0,0 +1,17 @@
+package routes
+
+import "net/http"
+
+// PatternOf возвращает функцию, определяющую шаблон маршрута запроса
+// (например, "GET /api/v1/users/{id}") по переданным мультиплексорам.
+// Для запросов без маршрута возвращается пустая строка.
+func PatternOf(muxes ...*http.ServeMux) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		for _, mux := range muxes {
+			if _, pattern := mux.Handler(r); pattern != "" {
+				return pattern
+			}
+		}
+		return ""
+	}
+}
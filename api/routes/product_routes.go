@@ -10,11 +10,11 @@ func SetupProductRoutes(productController *http2.ProductController) *http.ServeM
 	mux := http.NewServeMux()
 
 	// Маршруты для работы с продуктами
-	mux.HandleFunc("/api/v1/products", productController.GetAllProducts) // GET для всех продуктов
-	mux.HandleFunc("/api/v1/products", productController.CreateProduct)  // POST для создания продукта
-	mux.HandleFunc("/api/v1/products/", productController.GetProduct)    // GET для продукта по ID
-	mux.HandleFunc("/api/v1/products/", productController.UpdateProduct) // PUT для обновления продукта
-	mux.HandleFunc("/api/v1/products/", productController.DeleteProduct) // DELETE для удаления продукта
+	mux.HandleFunc("GET /api/v1/products", productController.GetAllProducts)        // GET для всех продуктов
+	mux.HandleFunc("POST /api/v1/products", productController.CreateProduct)        // POST для создания продукта
+	mux.HandleFunc("GET /api/v1/products/{id}", productController.GetProduct)       // GET для продукта по ID
+	mux.HandleFunc("PUT /api/v1/products/{id}", productController.UpdateProduct)    // PUT для обновления продукта
+	mux.HandleFunc("DELETE /api/v1/products/{id}", productController.DeleteProduct) // DELETE для удаления продукта
 
 	return mux
 }
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"Projectapirest/internal/auth"
+	"Projectapirest/internal/clock"
+	"Projectapirest/internal/config"
+	"Projectapirest/internal/entity"
+	service "Projectapirest/internal/services"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const apiKeyUsage = `usage: Projectapirest apikey create -user ID -name NAME [-scopes SCOPES] [-expires DURATION]
+
+Выпускает API-ключ в обход API, например первый ключ с областью admin.`
+
+// runAPIKey выполняет подкоманду apikey. Через API ключами управляют только
+// аутентифицированные клиенты, поэтому первый ключ выдается отсюда от имени
+// администратора.
+func runAPIKey(cfg config.Config, args []string) error {
+	if len(args) == 0 || args[0] != "create" {
+		return errors.New(apiKeyUsage)
+	}
+	flags := flag.NewFlagSet("create", flag.ContinueOnError)
+	userID := flags.Int("user", 0, "ID владельца ключа")
+	name := flags.String("name", "", "имя ключа")
+	scopes := flags.String("scopes", auth.ScopeRead+","+auth.ScopeWrite, "области доступа через запятую")
+	expires := flags.Duration("expires", 0, "срок действия; 0 — бессрочный ключ")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	// Хранилище в памяти не переживает команду, и ключ был бы бесполезен
+	if cfg.Storage != "postgres" {
+		return fmt.Errorf("apikey requires STORAGE=postgres, got %q", cfg.Storage)
+	}
+
+	clk := clock.Real{}
+	store, err := openStorage(cfg, prometheus.NewRegistry(), clk)
+	if err != nil {
+		return err
+	}
+	defer store.close()
+
+	key := entity.APIKey{UserID: *userID, Name: *name, Scopes: strings.Split(*scopes, ",")}
+	if *expires > 0 {
+		expiresAt := clk.Now().Add(*expires)
+		key.ExpiresAt = &expiresAt
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx = auth.NewContext(ctx, auth.Principal{Scopes: []string{auth.ScopeAdmin}})
+
+	created, token, err := service.NewAPIKeyService(store.apiKeys, service.WithClock(clk)).Create(ctx, key)
+	if err != nil {
+		return fmt.Errorf("create API key: %w", err)
+	}
+	fmt.Printf("API key %d (%s) issued to user %d\n%s\n", created.ID, created.Prefix, created.UserID, token)
+	return nil
+}
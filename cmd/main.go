@@ -1 +1,397 @@
 package main
+
+import (
+	"Projectapirest/api/openapi"
+	"Projectapirest/api/routes"
+	"Projectapirest/internal/auth"
+	"Projectapirest/internal/cache"
+	"Projectapirest/internal/clock"
+	"Projectapirest/internal/config"
+	grpc2 "Projectapirest/internal/controller/grpc"
+	http2 "Projectapirest/internal/controller/http"
+	"Projectapirest/internal/entity"
+	"Projectapirest/internal/gateway"
+	"Projectapirest/internal/grpcserver"
+	"Projectapirest/internal/health"
+	"Projectapirest/internal/idempotency"
+	"Projectapirest/internal/logger"
+	"Projectapirest/internal/metrics"
+	"Projectapirest/internal/outbox"
+	users "Projectapirest/internal/proto"
+	"Projectapirest/internal/ratelimit"
+	service "Projectapirest/internal/services"
+	"Projectapirest/internal/tracing"
+	"Projectapirest/internal/watch"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// localCacheMaxTTL ограничивает время жизни записей в локальном уровне кеша.
+const localCacheMaxTTL = 60 * time.Second
+
+func main() {
+	cfg := config.Load()
+
+	// Подкоманды migrate и apikey выполняются без запуска серверов
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "apikey" {
+		if err := runAPIKey(cfg, os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	appLogger, err := logger.New(os.Stdout, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		slog.Error("failed to configure logger", "error", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(appLogger)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), "Projectapirest", cfg.TracingExporter)
+	if err != nil {
+		fatal("failed to set up tracing", err)
+	}
+
+	// Метрики и инструментированные уровни кеша
+	registry := prometheus.NewRegistry()
+	metrics.RegisterRuntime(registry)
+	httpMetrics := metrics.NewHTTPMetrics(registry)
+	grpcMetrics := metrics.NewGRPCMetrics(registry)
+	cacheMetrics := metrics.NewCacheMetrics(registry)
+
+	// Отметки времени сущностей ставят репозитории
+	clk := clock.Real{}
+
+	store, err := openStorage(cfg, registry, clk)
+	if err != nil {
+		fatal("failed to open storage", err)
+	}
+	defer store.close()
+
+	inMemoryCache := cache.NewInstrumentedCache("inmem", cache.NewInMemoryCache(), cacheMetrics)
+	cacheLayers := []*cache.InstrumentedCache{inMemoryCache}
+	checks := append(store.checks, health.Check{Name: "cache:inmem", Critical: true, Ping: inMemoryCache.Ping})
+
+	// В демо-режиме STORAGE=memory внешние сервисы не нужны, поэтому Redis
+	// не подключается и кеш остается только в памяти
+	var redisLayer cache.Cache
+	// Ключи идемпотентности храним в Redis, чтобы повтор, попавший на другую
+	// реплику, тоже был распознан
+	idempotencyBackend := inMemoryCache
+	var limiter ratelimit.Limiter = ratelimit.NewMemoryLimiter(clk)
+	var redisClient *cache.RedisCache
+	if cfg.Storage != "memory" {
+		redisOpts, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			fatal("invalid REDIS_URL", err)
+		}
+		redisClient = cache.NewRedisCache(redisOpts.Addr, redisOpts.Password, redisOpts.DB, cfg.CacheKeyPrefix)
+		metrics.RegisterRedisPoolStats(registry, "cache", redisClient.PoolStats)
+		redisCache := cache.NewInstrumentedCache("redis", redisClient, cacheMetrics)
+		redisLayer = redisCache
+		idempotencyBackend = redisCache
+		if cfg.RateLimitBackend == "redis" {
+			limiter = ratelimit.NewRedisLimiter(redisClient.Client())
+		}
+		cacheLayers = append(cacheLayers, redisCache)
+		// Без Redis MultiLevelCache продолжает обслуживать запросы из памяти
+		checks = append(checks, health.Check{Name: "cache:redis", Critical: false, Ping: redisCache.Ping})
+	}
+	// Общий многослойный кеш сервисов. Локальный уровень держит записи недолго,
+	// чтобы реплики не расходились с Redis после изменений на других узлах.
+	layers := []cache.Layer{{Cache: inMemoryCache, MaxTTL: localCacheMaxTTL}}
+	if redisLayer != nil {
+		layers = append(layers, cache.Layer{Cache: redisLayer})
+	}
+	appCache := cache.NewMultiLevelCacheWithLayers(layers...)
+
+	// Репозитории и сервисы. Изменения продуктов рассылаются подписчикам
+	// WatchProducts. С OUTBOX_PUBLISHER=redis события читаются из потока outbox
+	// и видны на всех репликах; иначе сервис передает их напрямую, и подписчики
+	// видят только изменения, сделанные этим экземпляром.
+	productEvents := watch.NewHub[entity.ProductEvent](cfg.WatchBuffer)
+	watchFromStream := cfg.OutboxPublisher == "redis"
+	var notifyProducts func(entity.ProductEvent)
+	if !watchFromStream {
+		notifyProducts = productEvents.Publish
+	}
+	// Доменные события пишутся в outbox в одной транзакции с изменениями
+	productService := service.NewProductService(store.products, service.WithCache(appCache), service.WithLogger(appLogger),
+		service.WithProductEvents(notifyProducts), service.WithOutbox(store.transactor))
+	userService := service.NewUserService(store.users, service.WithCache(appCache), service.WithLogger(appLogger),
+		service.WithOutbox(store.transactor))
+	outboxPublisher, err := newOutboxPublisher(cfg, redisClient)
+	if err != nil {
+		fatal("failed to configure outbox publisher", err)
+	}
+	// Проверенные API-ключи кешируются в том же кеше, чтобы удаление
+	// пользователя сбрасывало их по тегу
+	apiKeyService := service.NewAPIKeyService(store.apiKeys, service.WithCache(appCache), service.WithLogger(appLogger), service.WithClock(clk))
+
+	idempotencyStore := idempotency.NewStore(idempotencyBackend, cfg.IdempotencyTTL)
+
+	// REST-шлюз помечает свои вызовы этим секретом, чтобы gRPC-сервер не
+	// проверял и не ограничивал повторно запросы, уже учтенные HTTP-слоем
+	gatewayToken, err := auth.NewGatewayToken()
+	if err != nil {
+		fatal("failed to generate gateway token", err)
+	}
+	// Проверки живости и готовности и документация доступны без ключа.
+	// Отражение gRPC при включенной аутентификации требует ключа с областью read
+	authPolicy := auth.Policy{
+		Required:     cfg.AuthRequired,
+		GatewayToken: gatewayToken,
+		Public: map[string]bool{
+			"/healthz":                     true,
+			"/readyz":                      true,
+			"/openapi.json":                true,
+			openapi.DocsPath:               true,
+			"/grpc.health.v1.Health/Check": true,
+			"/grpc.health.v1.Health/Watch": true,
+		},
+	}
+
+	rateLimits, err := ratelimit.ParsePolicy(cfg.RateLimitDefault, cfg.RateLimitRoutes)
+	if err != nil {
+		fatal("invalid rate limit configuration", err)
+	}
+
+	readiness := health.NewChecker(2*time.Second, checks...)
+
+	// Маршруты. Пользователей и продукты обслуживают либо HTTP-контроллеры,
+	// либо REST-шлюз поверх gRPC-сервисов с теми же путями.
+	var userRoutes, productRoutes *http.ServeMux
+	switch cfg.HTTPAPI {
+	case "controllers":
+		userRoutes = routes.SetupUserRoutes(http2.NewUserController(userService))
+		productRoutes = routes.SetupProductRoutes(http2.NewProductController(productService))
+	case "gateway":
+		// Шлюз принимает ответы того же размера, что сервер может отправить
+		conn, err := gateway.Dial(cfg.GRPCAddr, grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(cfg.GRPCMaxSendMsgSize),
+			grpc.MaxCallSendMsgSize(cfg.GRPCMaxRecvMsgSize),
+		))
+		if err != nil {
+			fatal("failed to connect gateway to gRPC server", err)
+		}
+		defer conn.Close()
+		gatewayHandler, err := gateway.New(context.Background(), conn, gatewayToken)
+		if err != nil {
+			fatal("failed to set up REST gateway", err)
+		}
+		userRoutes = routes.SetupGatewayRoutes(gatewayHandler)
+		productRoutes = userRoutes
+	default:
+		fatal("invalid HTTP_API", fmt.Errorf("unknown value %q: want controllers or gateway", cfg.HTTPAPI))
+	}
+	apiKeyRoutes := routes.SetupAPIKeyRoutes(http2.NewAPIKeyController(apiKeyService))
+	healthController := http2.NewHealthController(readiness)
+	healthRoutes := routes.SetupHealthRoutes(healthController)
+	docsRoutes := routes.SetupDocsRoutes(openapi.SpecHandler(), openapi.UIHandler())
+	adminRoutes := routes.SetupAdminRoutes(metrics.Handler(registry), healthController, http2.NewCacheAdminController(cacheLayers...))
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/users", userRoutes)
+	mux.Handle("/api/v1/users/", userRoutes)
+	mux.Handle("/api/v1/users/{id}/api-keys", apiKeyRoutes)
+	mux.Handle("/api/v1/users/{id}/api-keys/", apiKeyRoutes)
+	mux.Handle("/api/v1/products", productRoutes)
+	mux.Handle("/api/v1/products/", productRoutes)
+	mux.Handle("/healthz", healthRoutes)
+	mux.Handle("/readyz", healthRoutes)
+	mux.Handle("/openapi.json", docsRoutes)
+	mux.Handle(openapi.DocsPath, docsRoutes)
+
+	routeOf := routes.PatternOf(apiKeyRoutes, userRoutes, productRoutes, healthRoutes, docsRoutes)
+	// Аутентификация идет до ограничения частоты, чтобы лимиты считались
+	// по ключу или пользователю, а не только по IP
+	apiHandler := auth.HTTPMiddleware(apiKeyService, authPolicy,
+		ratelimit.HTTPMiddleware(limiter, rateLimits, idempotency.HTTPMiddleware(idempotencyStore, mux), routeOf))
+	server := &http.Server{
+		Addr: cfg.HTTPAddr,
+		// otelhttp извлекает W3C traceparent и открывает серверный span с именем маршрута
+		Handler: otelhttp.NewHandler(logger.HTTPMiddleware(appLogger, httpMetrics.Middleware(apiHandler, routeOf), routeOf), "http.server",
+			otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+				if route := routeOf(r); route != "" {
+					return route
+				}
+				return r.Method + " unmatched"
+			}),
+		),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	// Служебный сервер на отдельном порту, чтобы метрики и администрирование
+	// не были доступны через публичный API
+	// Управление кешем требует ключа с областью admin даже без AUTH_REQUIRED
+	adminMux := http.NewServeMux()
+	adminMux.Handle("/metrics", adminRoutes)
+	adminMux.Handle("/admin/", auth.RequireScope(apiKeyService, auth.ScopeAdmin, adminRoutes))
+	adminServer := &http.Server{
+		Addr:              cfg.AdminAddr,
+		Handler:           adminMux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	// Идентификатор запроса, журнал и метрики идут первыми, чтобы видеть все
+	// вызовы, включая отклоненные и завершившиеся паникой. Восстановление
+	// после паники стоит сразу за ними: журнал и метрики получат код Internal.
+	grpcOptions := grpcserver.ServerOptions(grpcserver.Options{
+		MaxRecvMsgSize:   cfg.GRPCMaxRecvMsgSize,
+		MaxSendMsgSize:   cfg.GRPCMaxSendMsgSize,
+		KeepaliveTime:    cfg.GRPCKeepaliveTime,
+		KeepaliveTimeout: cfg.GRPCKeepaliveTimeout,
+		KeepaliveMinTime: cfg.GRPCKeepaliveMinTime,
+		MaxConnectionAge: cfg.GRPCMaxConnectionAge,
+	})
+	grpcServer := grpc.NewServer(append(grpcOptions,
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			logger.RequestIDUnaryServerInterceptor(),
+			logger.UnaryServerInterceptor(appLogger),
+			grpcMetrics.UnaryServerInterceptor(),
+			grpcserver.RecoveryUnaryInterceptor(),
+			grpcserver.DeadlineUnaryInterceptor(cfg.GRPCDefaultTimeout, cfg.GRPCMaxTimeout),
+			auth.UnaryServerInterceptor(apiKeyService, authPolicy),
+			ratelimit.UnaryServerInterceptor(limiter, rateLimits),
+			idempotency.UnaryServerInterceptor(idempotencyStore),
+		),
+		grpc.ChainStreamInterceptor(
+			logger.RequestIDStreamServerInterceptor(),
+			logger.StreamServerInterceptor(appLogger),
+			grpcMetrics.StreamServerInterceptor(),
+			grpcserver.RecoveryStreamInterceptor(),
+			auth.StreamServerInterceptor(apiKeyService, authPolicy),
+			ratelimit.StreamServerInterceptor(limiter, rateLimits),
+		),
+	)...)
+	users.RegisterUserServiceServer(grpcServer, grpc2.NewUserServer(userService))
+	users.RegisterProductServiceServer(grpcServer, grpc2.NewProductServer(productService, productEvents))
+	healthServer := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	if cfg.GRPCReflection {
+		reflection.Register(grpcServer)
+	}
+
+	grpcListener, err := net.Listen("tcp", cfg.GRPCAddr)
+	if err != nil {
+		fatal("failed to listen on "+cfg.GRPCAddr, err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go readiness.Watch(ctx, 10*time.Second, func(report health.Report) {
+		health.UpdateGRPC(healthServer, report)
+	})
+
+	// Ретранслятор outbox останавливается вместе с ctx; relayDone закрывается,
+	// когда он закончит текущую порцию
+	relayDone := make(chan struct{})
+	if outboxPublisher != nil {
+		relay := outbox.NewRelay(store.outbox, outboxPublisher, outbox.Options{
+			BatchSize:    cfg.OutboxBatchSize,
+			PollInterval: cfg.OutboxPollInterval,
+			Retention:    cfg.OutboxRetention,
+		}, clk, appLogger)
+		go func() {
+			defer close(relayDone)
+			slog.Info("outbox relay started", "publisher", cfg.OutboxPublisher)
+			relay.Run(ctx)
+		}()
+	} else {
+		close(relayDone)
+	}
+	if watchFromStream {
+		reader := outbox.NewRedisStreamReader(redisClient.Client(), cfg.OutboxRedisStream, appLogger)
+		go reader.Run(ctx, func(event entity.DomainEvent) {
+			productEvent, ok, err := entity.ProductEventFromDomain(event)
+			if err != nil {
+				slog.Warn("skipping product event", "event_id", event.ID, "error", err)
+			}
+			if ok {
+				productEvents.Publish(productEvent)
+			}
+		})
+	} else {
+		slog.Info("WatchProducts sees only changes made by this instance; set OUTBOX_PUBLISHER=redis to share them across replicas")
+	}
+
+	for name, srv := range map[string]*http.Server{"HTTP": server, "admin": adminServer} {
+		go func(name string, srv *http.Server) {
+			slog.Info("server listening", "server", name, "addr", srv.Addr)
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fatal(name+" server failed", err)
+			}
+		}(name, srv)
+	}
+
+	go func() {
+		slog.Info("server listening", "server", "gRPC", "addr", cfg.GRPCAddr)
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			fatal("gRPC server failed", err)
+		}
+	}()
+
+	<-ctx.Done()
+	// Повторный сигнал завершает процесс сразу
+	stop()
+	slog.Info("shutting down", "timeout", cfg.ShutdownTimeout)
+	healthServer.Shutdown()
+
+	// HTTP останавливается первым: REST-шлюз завершает свои запросы через
+	// gRPC-сервер, поэтому тот работает, пока HTTP не опустеет. Оба укладываются
+	// в общий срок ShutdownTimeout.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	for _, srv := range []*http.Server{server, adminServer} {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			slog.Error("server shutdown", "addr", srv.Addr, "error", err)
+		}
+	}
+	// Подписки WatchProducts не завершаются сами, поэтому закрываются до
+	// остановки gRPC, чтобы не ждать их до истечения срока
+	productEvents.Close()
+	if err := grpcserver.GracefulStop(shutdownCtx, grpcServer); err != nil {
+		slog.Error("gRPC server forced to stop", "error", err)
+	}
+	select {
+	case <-relayDone:
+	case <-shutdownCtx.Done():
+		slog.Error("outbox relay did not stop in time")
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("tracing shutdown", "error", err)
+	}
+}
+
+// fatal пишет ошибку в лог и завершает процесс.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"Projectapirest/internal/config"
+	"Projectapirest/internal/migrate"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pressly/goose/v3"
+)
+
+const migrateUsage = `usage: Projectapirest migrate <command> [arguments]
+
+commands:
+  up                       применить все новые миграции
+  down                     откатить последнюю миграцию
+  redo                     откатить и заново применить последнюю миграцию
+  status                   показать состояние миграций
+  create [-dir DIR] NAME   создать файл новой миграции`
+
+// runMigrate выполняет подкоманду migrate.
+func runMigrate(cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	command, args := args[0], args[1:]
+
+	if command == "create" {
+		flags := flag.NewFlagSet("create", flag.ContinueOnError)
+		dir := flags.String("dir", "migrations", "каталог миграций")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		return migrate.Create(*dir, flags.Arg(0))
+	}
+
+	db, err := sql.Open("postgres", cfg.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer db.Close()
+
+	migrator, err := migrate.New(db)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		results, err := migrator.Up(ctx)
+		printResults(results...)
+		return err
+	case "down":
+		result, err := migrator.Down(ctx)
+		if result != nil {
+			printResults(result)
+		}
+		return err
+	case "redo":
+		results, err := migrator.Redo(ctx)
+		printResults(results...)
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "STATE\tAPPLIED AT\tMIGRATION")
+		for _, s := range statuses {
+			appliedAt := "-"
+			if !s.AppliedAt.IsZero() {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.State, appliedAt, s.Source.Path)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", command, migrateUsage)
+	}
+}
+
+func printResults(results ...*goose.MigrationResult) {
+	if len(results) == 0 {
+		fmt.Println("no migrations to apply")
+	}
+	for _, result := range results {
+		fmt.Println(result)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"Projectapirest/internal/cache"
+	"Projectapirest/internal/config"
+	"Projectapirest/internal/outbox"
+	"errors"
+	"fmt"
+)
+
+// memoryPublisherCapacity ограничивает число событий, которые хранит
+// OUTBOX_PUBLISHER=memory.
+const memoryPublisherCapacity = 1000
+
+// newOutboxPublisher создает публикатор доменных событий согласно
+// cfg.OutboxPublisher. Для none возвращает nil: ретранслятор не запускается.
+// redisClient равен nil, если Redis не подключен.
+func newOutboxPublisher(cfg config.Config, redisClient *cache.RedisCache) (outbox.Publisher, error) {
+	switch cfg.OutboxPublisher {
+	case "none":
+		return nil, nil
+	case "memory":
+		return outbox.NewMemoryPublisher(memoryPublisherCapacity), nil
+	case "redis":
+		if redisClient == nil {
+			return nil, errors.New("OUTBOX_PUBLISHER=redis requires Redis, which is not used with STORAGE=memory")
+		}
+		return outbox.NewRedisStreamPublisher(redisClient.Client(), cfg.OutboxRedisStream, int64(cfg.OutboxRedisMaxLen)), nil
+	case "webhook":
+		if cfg.OutboxWebhookURL == "" {
+			return nil, errors.New("OUTBOX_PUBLISHER=webhook requires OUTBOX_WEBHOOK_URL")
+		}
+		return outbox.NewWebhookPublisher(cfg.OutboxWebhookURL, cfg.OutboxWebhookSecret, cfg.OutboxWebhookTimeout), nil
+	default:
+		return nil, fmt.Errorf("unknown OUTBOX_PUBLISHER %q: want none, memory, redis or webhook", cfg.OutboxPublisher)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"Projectapirest/internal/clock"
+	"Projectapirest/internal/config"
+	"Projectapirest/internal/health"
+	"Projectapirest/internal/metrics"
+	"Projectapirest/internal/migrate"
+	"Projectapirest/internal/repository"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// storage объединяет репозитории выбранного хранилища, его проверки
+// готовности и функцию освобождения ресурсов.
+type storage struct {
+	users    repository.UserRepositoryInterface
+	products repository.ProductRepositoryInterface
+	apiKeys  repository.APIKeyRepositoryInterface
+	// transactor и outbox сохраняют доменные события вместе с изменениями
+	transactor repository.Transactor
+	outbox     repository.OutboxRepositoryInterface
+	checks     []health.Check
+	close      func() error
+}
+
+// openStorage создает репозитории согласно cfg.Storage.
+func openStorage(cfg config.Config, registry *prometheus.Registry, clk clock.Clock) (storage, error) {
+	switch cfg.Storage {
+	case "memory":
+		store := repository.NewMemoryStore(clk)
+		return storage{
+			users:      repository.NewMemoryUserRepository(store),
+			products:   repository.NewMemoryProductRepository(store),
+			apiKeys:    repository.NewMemoryAPIKeyRepository(store),
+			transactor: repository.NewMemoryTransactor(store),
+			outbox:     repository.NewMemoryOutboxRepository(store),
+			close:      func() error { return nil },
+		}, nil
+	case "postgres":
+		return openPostgres(cfg, registry, clk)
+	default:
+		return storage{}, fmt.Errorf("unknown STORAGE %q: want postgres or memory", cfg.Storage)
+	}
+}
+
+// openPostgres подключается к PostgreSQL, при необходимости применяет миграции
+// и сверяет схему с запросами репозиториев.
+func openPostgres(cfg config.Config, registry *prometheus.Registry, clk clock.Clock) (storage, error) {
+	db, err := sql.Open("postgres", cfg.DatabaseURL)
+	if err != nil {
+		return storage{}, fmt.Errorf("open database: %w", err)
+	}
+
+	if cfg.AutoMigrate {
+		migrator, err := migrate.New(db)
+		if err != nil {
+			db.Close()
+			return storage{}, fmt.Errorf("prepare migrations: %w", err)
+		}
+		results, err := migrator.Up(context.Background())
+		if err != nil {
+			db.Close()
+			return storage{}, fmt.Errorf("apply migrations: %w", err)
+		}
+		slog.Info("migrations applied", "count", len(results))
+	}
+
+	// Проверяем, что схема базы соответствует запросам репозиториев,
+	// чтобы упасть при старте, а не на первом запросе
+	if cfg.SchemaCheck {
+		checkCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := repository.CheckSchema(checkCtx, db)
+		cancel()
+		if err != nil {
+			db.Close()
+			return storage{}, fmt.Errorf("schema check: %w", err)
+		}
+	}
+
+	metrics.RegisterDBStats(registry, db, "postgres")
+	return storage{
+		users:      repository.NewUserRepository(db, clk),
+		products:   repository.NewProductRepository(db, clk),
+		apiKeys:    repository.NewAPIKeyRepository(db, clk),
+		transactor: repository.NewTransactor(db, clk),
+		outbox:     repository.NewOutboxRepository(db, clk),
+		// Без базы приложение не работает
+		checks: []health.Check{{Name: "postgres", Critical: true, Ping: db.PingContext}},
+		close:  db.Close,
+	}, nil
+}
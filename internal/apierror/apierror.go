@@ -0,0 +1,89 @@
+// Package apierror сопоставляет ошибки сервисов и репозиториев с кодами
+// транспортов. Его используют HTTP-контроллеры, gRPC-сервисы и REST-шлюз,
+// чтобы одна и та же ошибка давала одинаковый ответ в любом транспорте.
+package apierror
+
+import (
+	"Projectapirest/internal/auth"
+	"Projectapirest/internal/repository"
+	service "Projectapirest/internal/services"
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Code возвращает gRPC-код для ошибки. Ошибки, уже несущие gRPC-статус,
+// сохраняют свой код; неизвестные ошибки считаются внутренними.
+func Code(err error) codes.Code {
+	switch {
+	case err == nil:
+		return codes.OK
+	case errors.Is(err, repository.ErrNotFound):
+		return codes.NotFound
+	case errors.Is(err, repository.ErrDuplicateEmail), errors.Is(err, repository.ErrDuplicateAPIKey):
+		return codes.AlreadyExists
+	case errors.Is(err, repository.ErrUserReference):
+		return codes.FailedPrecondition
+	case errors.Is(err, repository.ErrInvalidPage), errors.Is(err, service.ErrInvalidAPIKeyRequest):
+		return codes.InvalidArgument
+	case errors.Is(err, service.ErrForbidden):
+		return codes.PermissionDenied
+	case errors.Is(err, auth.ErrUnauthenticated), errors.Is(err, service.ErrAuthenticationRequired):
+		return codes.Unauthenticated
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	}
+	if s, ok := status.FromError(err); ok {
+		return s.Code()
+	}
+	return codes.Internal
+}
+
+// HTTPStatus возвращает HTTP-статус для ошибки.
+func HTTPStatus(err error) int {
+	return HTTPStatusFromCode(Code(err))
+}
+
+// HTTPStatusFromCode переводит gRPC-код в HTTP-статус. В отличие от таблицы
+// grpc-gateway, FailedPrecondition дает 422, как у HTTP-контроллеров.
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.FailedPrecondition:
+		return http.StatusUnprocessableEntity
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Canceled:
+		// Нестандартный статус nginx: клиент закрыл соединение
+		return 499
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// Status оборачивает ошибку в gRPC-статус с кодом из Code. Сообщение
+// предваряется action, как в текстовых ответах HTTP-контроллеров.
+func Status(action string, err error) error {
+	return status.Error(Code(err), action+": "+err.Error())
+}
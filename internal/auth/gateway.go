@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Метаданные, которыми REST-шлюз этого процесса помечает свои вызовы gRPC.
+const (
+	gatewayTokenMetadata     = "x-gateway-token"
+	gatewayPrincipalMetadata = "x-gateway-principal"
+)
+
+type gatewayKey struct{}
+
+// NewGatewayToken возвращает случайный секрет для Policy.GatewayToken.
+func NewGatewayToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// GatewayMetadata возвращает метаданные вызова шлюза: секрет и клиента,
+// которого уже проверил HTTP-слой. Клиент передается всегда, пустое значение
+// означает анонимный запрос: второе значение, подставленное через заголовок
+// Grpc-Metadata-*, отклонит gatewayContext.
+func GatewayMetadata(ctx context.Context, token string) metadata.MD {
+	var value string
+	if principal, ok := FromContext(ctx); ok {
+		data, _ := json.Marshal(principal)
+		value = string(data)
+	}
+	return metadata.Pairs(gatewayTokenMetadata, token, gatewayPrincipalMetadata, value)
+}
+
+// FromGateway сообщает, что вызов пришел от REST-шлюза и уже прошел
+// аутентификацию и ограничение частоты на HTTP-слое.
+func FromGateway(ctx context.Context) bool {
+	gateway, _ := ctx.Value(gatewayKey{}).(bool)
+	return gateway
+}
+
+// gatewayContext принимает вызов шлюза с клиентом из метаданных вместо
+// повторной проверки ключа.
+func gatewayContext(ctx context.Context, md metadata.MD, token string) (context.Context, error) {
+	tokens, principals := md.Get(gatewayTokenMetadata), md.Get(gatewayPrincipalMetadata)
+	if len(tokens) != 1 || len(principals) != 1 || subtle.ConstantTimeCompare([]byte(tokens[0]), []byte(token)) != 1 {
+		return nil, status.Error(codes.Unauthenticated, "invalid gateway metadata")
+	}
+	ctx = context.WithValue(ctx, gatewayKey{}, true)
+	if principals[0] == "" {
+		return ctx, nil
+	}
+	var principal Principal
+	if err := json.Unmarshal([]byte(principals[0]), &principal); err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid gateway metadata")
+	}
+	return withPrincipal(ctx, principal), nil
+}
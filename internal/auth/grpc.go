@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"Projectapirest/internal/logger"
+	"context"
+	"errors"
+	"path"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// readMethodPrefixes — префиксы имен методов gRPC, которым достаточно
+// области read; ServerReflectionInfo — отражение gRPC.
+var readMethodPrefixes = []string{"Get", "List", "Find", "Stream", "Watch", "Check", "ServerReflection"}
+
+// UnaryServerInterceptor делает для gRPC то же, что HTTPMiddleware для HTTP:
+// ключ передается в метаданных authorization, методы с именами на Get, List,
+// Find, Stream, Watch и Check и отражение gRPC требуют read, остальные — write.
+func UnaryServerInterceptor(authn Authenticator, policy Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticateGRPC(ctx, authn, policy, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor — потоковый вариант UnaryServerInterceptor.
+func StreamServerInterceptor(authn Authenticator, policy Policy) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticateGRPC(ss.Context(), authn, policy, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticateGRPC проверяет ключ из метаданных и возвращает контекст с клиентом.
+// Вызовы REST-шлюза с секретом Policy.GatewayToken несут клиента, уже
+// проверенного HTTP-слоем.
+func authenticateGRPC(ctx context.Context, authn Authenticator, policy Policy, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if policy.GatewayToken != "" && len(md.Get(gatewayTokenMetadata)) > 0 {
+		return gatewayContext(ctx, md, policy.GatewayToken)
+	}
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		if policy.Required && !policy.public(method) {
+			return nil, status.Error(codes.Unauthenticated, "authentication required")
+		}
+		return ctx, nil
+	}
+
+	token, ok := parseAuthorization(values[0])
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "unsupported authorization scheme")
+	}
+	principal, err := authn.Authenticate(ctx, token)
+	if errors.Is(err, ErrUnauthenticated) {
+		return nil, status.Error(codes.Unauthenticated, "invalid API key")
+	}
+	if err != nil {
+		logger.FromContext(ctx).ErrorContext(ctx, "failed to authenticate API key", "error", err)
+		return nil, status.Error(codes.Unavailable, "authentication unavailable")
+	}
+	if scope := grpcScope(method); !principal.HasScope(scope) {
+		return nil, status.Error(codes.PermissionDenied, "API key lacks scope "+scope)
+	}
+	return withPrincipal(ctx, principal), nil
+}
+
+// withPrincipal добавляет клиента в журнал вызова и в контекст.
+func withPrincipal(ctx context.Context, principal Principal) context.Context {
+	logger.AddFields(ctx, "user_id", principal.UserID)
+	return NewContext(ctx, principal)
+}
+
+// grpcScope возвращает область доступа, нужную для метода gRPC.
+func grpcScope(fullMethod string) string {
+	name := path.Base(fullMethod)
+	for _, prefix := range readMethodPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return ScopeRead
+		}
+	}
+	return ScopeWrite
+}
+
+// serverStream подменяет контекст потока.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"Projectapirest/internal/logger"
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptorLogsUserID(t *testing.T) {
+	authn := fakeAuthenticator{"pk_reader": {UserID: 7, Scopes: []string{ScopeRead}}}
+	info := &grpc.UnaryServerInfo{FullMethod: "/users.UserService/GetUser"}
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantUserID    interface{}
+	}{
+		{name: "authenticated", authorization: "ApiKey pk_reader", wantUserID: float64(7)},
+		{name: "anonymous", wantUserID: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			access := logger.UnaryServerInterceptor(slog.New(slog.NewJSONHandler(&buf, nil)))
+			authenticate := UnaryServerInterceptor(authn, Policy{})
+
+			ctx := context.Background()
+			if tt.authorization != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.authorization))
+			}
+			_, err := access(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return authenticate(ctx, req, info, func(context.Context, interface{}) (interface{}, error) {
+					return nil, nil
+				})
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			line := accessLogLine(t, &buf)
+			if line["msg"] != "grpc request" || line["user_id"] != tt.wantUserID {
+				t.Errorf("access log = %v, want user_id %v", line, tt.wantUserID)
+			}
+		})
+	}
+}
+
+func TestUnaryServerInterceptorGatewayCalls(t *testing.T) {
+	// Ключ pk_reader не должен проверяться у вызовов шлюза
+	authn := fakeAuthenticator{"pk_reader": {UserID: 1, Scopes: []string{ScopeRead}}}
+	info := &grpc.UnaryServerInfo{FullMethod: "/users.UserService/CreateUser"}
+	writer := GatewayMetadata(NewContext(context.Background(), Principal{UserID: 7, APIKeyID: 3, Scopes: []string{ScopeWrite}}), "secret")
+	anonymous := GatewayMetadata(context.Background(), "secret")
+
+	tests := []struct {
+		name          string
+		policy        Policy
+		md            metadata.MD
+		wantCode      codes.Code
+		wantGateway   bool
+		wantPrincipal int
+	}{
+		{name: "authenticated client", policy: Policy{Required: true, GatewayToken: "secret"},
+			md: metadata.Join(writer, metadata.Pairs("authorization", "ApiKey pk_reader")), wantGateway: true, wantPrincipal: 7},
+		{name: "anonymous client", policy: Policy{GatewayToken: "secret"}, md: anonymous, wantGateway: true},
+		{name: "wrong token", policy: Policy{GatewayToken: "other"}, md: writer, wantCode: codes.Unauthenticated},
+		{name: "injected principal", policy: Policy{GatewayToken: "secret"},
+			md: metadata.Join(anonymous, metadata.Pairs(gatewayPrincipalMetadata, `{"UserID":1}`)), wantCode: codes.Unauthenticated},
+		{name: "token not configured", policy: Policy{}, md: writer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gateway bool
+			var principal Principal
+			interceptor := UnaryServerInterceptor(authn, tt.policy)
+			_, err := interceptor(metadata.NewIncomingContext(context.Background(), tt.md), nil, info,
+				func(ctx context.Context, _ interface{}) (interface{}, error) {
+					gateway = FromGateway(ctx)
+					principal, _ = FromContext(ctx)
+					return nil, nil
+				})
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("code = %v, want %v", status.Code(err), tt.wantCode)
+			}
+			if gateway != tt.wantGateway || principal.UserID != tt.wantPrincipal {
+				t.Errorf("gateway = %v, principal = %+v, want %v and user %d", gateway, principal, tt.wantGateway, tt.wantPrincipal)
+			}
+		})
+	}
+}
+
+func TestGRPCScope(t *testing.T) {
+	tests := []struct {
+		method string
+		want   string
+	}{
+		{method: "/users.UserService/GetUser", want: ScopeRead},
+		{method: "/users.ProductService/WatchProducts", want: ScopeRead},
+		{method: "/grpc.health.v1.Health/Check", want: ScopeRead},
+		{method: "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo", want: ScopeRead},
+		{method: "/users.UserService/CreateUser", want: ScopeWrite},
+		{method: "/users.ProductService/DeleteProduct", want: ScopeWrite},
+	}
+	for _, tt := range tests {
+		if got := grpcScope(tt.method); got != tt.want {
+			t.Errorf("grpcScope(%s) = %s, want %s", tt.method, got, tt.want)
+		}
+	}
+}
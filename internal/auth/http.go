@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"Projectapirest/internal/logger"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Scheme — схема заголовка Authorization для API-ключей.
+const Scheme = "ApiKey"
+
+// Policy задает, обязательна ли аутентификация. Маршруты из Public
+// (путь HTTP или полное имя метода gRPC) доступны без ключа, например
+// проверки живости и готовности; путь, оканчивающийся на "/", открывает
+// все вложенные пути. GatewayToken — секрет, которым REST-шлюз этого
+// процесса помечает свои вызовы gRPC, чтобы они не проверялись повторно.
+type Policy struct {
+	Required     bool
+	Public       map[string]bool
+	GatewayToken string
+}
+
+// public сообщает, доступен ли маршрут без ключа.
+func (p Policy) public(route string) bool {
+	if p.Public[route] {
+		return true
+	}
+	for prefix := range p.Public {
+		if strings.HasSuffix(prefix, "/") && strings.HasPrefix(route, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// HTTPMiddleware проверяет заголовок "Authorization: ApiKey <ключ>" и кладет
+// клиента в контекст запроса. Недействительный ключ получает 401, ключ без
+// нужной области доступа — 403: GET и HEAD требуют read, остальные методы — write.
+// Запрос без ключа отклоняется, только если аутентификация обязательна.
+func HTTPMiddleware(authn Authenticator, policy Policy, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			if policy.Required && !policy.public(r.URL.Path) {
+				unauthorized(w, "Authentication required")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		serveWithScope(authn, httpScope(r.Method), next, w, r)
+	})
+}
+
+// RequireScope пропускает только запросы с ключом, у которого есть область
+// scope, независимо от Policy.Required. Им закрыты служебные маршруты.
+func RequireScope(authn Authenticator, scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			unauthorized(w, "Authentication required")
+			return
+		}
+		serveWithScope(authn, scope, next, w, r)
+	})
+}
+
+// serveWithScope проверяет ключ из заголовка и его область доступа и передает
+// запрос с клиентом в контексте дальше.
+func serveWithScope(authn Authenticator, scope string, next http.Handler, w http.ResponseWriter, r *http.Request) {
+	token, ok := parseAuthorization(r.Header.Get("Authorization"))
+	if !ok {
+		unauthorized(w, "Unsupported authorization scheme")
+		return
+	}
+	ctx := r.Context()
+	principal, err := authn.Authenticate(ctx, token)
+	if errors.Is(err, ErrUnauthenticated) {
+		unauthorized(w, "Invalid API key")
+		return
+	}
+	if err != nil {
+		logger.FromContext(ctx).ErrorContext(ctx, "failed to authenticate API key", "error", err)
+		http.Error(w, "Authentication unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if !principal.HasScope(scope) {
+		http.Error(w, "API key lacks scope "+scope, http.StatusForbidden)
+		return
+	}
+	// Поле увидят все записи журнала запроса, включая access-лог
+	logger.AddFields(ctx, "user_id", principal.UserID)
+	next.ServeHTTP(w, r.WithContext(NewContext(ctx, principal)))
+}
+
+// parseAuthorization извлекает ключ из значения "ApiKey <ключ>".
+func parseAuthorization(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, Scheme) {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// httpScope возвращает область доступа, нужную для метода HTTP.
+func httpScope(method string) string {
+	if method == http.MethodGet || method == http.MethodHead {
+		return ScopeRead
+	}
+	return ScopeWrite
+}
+
+// unauthorized отвечает 401 с вызовом схемы ApiKey.
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", Scheme)
+	http.Error(w, msg, http.StatusUnauthorized)
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"Projectapirest/internal/logger"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeAuthenticator сопоставляет ключи клиентам.
+type fakeAuthenticator map[string]Principal
+
+func (a fakeAuthenticator) Authenticate(_ context.Context, token string) (Principal, error) {
+	principal, ok := a[token]
+	if !ok {
+		return Principal{}, ErrUnauthenticated
+	}
+	return principal, nil
+}
+
+// accessLogLine разбирает единственную запись журнала в формате JSON.
+func accessLogLine(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("access log %q: %v", buf.String(), err)
+	}
+	return line
+}
+
+func TestHTTPMiddlewareLogsUserID(t *testing.T) {
+	authn := fakeAuthenticator{"pk_reader": {UserID: 7, Scopes: []string{ScopeRead}}}
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantUserID    interface{}
+	}{
+		{name: "authenticated", authorization: "ApiKey pk_reader", wantUserID: float64(7)},
+		{name: "anonymous", wantUserID: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			base := slog.New(slog.NewJSONHandler(&buf, nil))
+			next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {})
+			handler := logger.HTTPMiddleware(base, HTTPMiddleware(authn, Policy{}, next),
+				func(*http.Request) string { return "GET /api/v1/users" })
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			line := accessLogLine(t, &buf)
+			if line["msg"] != "http request" || line["user_id"] != tt.wantUserID {
+				t.Errorf("access log = %v, want user_id %v", line, tt.wantUserID)
+			}
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	authn := fakeAuthenticator{
+		"pk_reader": {UserID: 1, Scopes: []string{ScopeRead, ScopeWrite}},
+		"pk_admin":  {UserID: 2, Scopes: []string{ScopeAdmin}},
+	}
+	handler := RequireScope(authn, ScopeAdmin, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if principal, ok := FromContext(r.Context()); !ok || principal.UserID != 2 {
+			t.Errorf("principal = %+v, %v", principal, ok)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{name: "no key", want: http.StatusUnauthorized},
+		{name: "unknown key", authorization: "ApiKey pk_unknown", want: http.StatusUnauthorized},
+		{name: "other scheme", authorization: "Bearer pk_admin", want: http.StatusUnauthorized},
+		{name: "key without scope", authorization: "ApiKey pk_reader", want: http.StatusForbidden},
+		{name: "admin key", authorization: "ApiKey pk_admin", want: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/admin/cache/flush", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
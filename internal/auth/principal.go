@@ -0,0 +1,60 @@
+// Package auth аутентифицирует запросы по API-ключам и передает клиента
+// запроса через контекст.
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// Области доступа API-ключей.
+const (
+	ScopeRead  = "read"  // чтение данных
+	ScopeWrite = "write" // создание, изменение и удаление
+	ScopeAdmin = "admin" // ключи любых пользователей и служебные маршруты
+)
+
+// ValidScope сообщает, известна ли область доступа.
+func ValidScope(scope string) bool {
+	return scope == ScopeRead || scope == ScopeWrite || scope == ScopeAdmin
+}
+
+// ErrUnauthenticated возвращается Authenticator для неизвестного,
+// отозванного или просроченного ключа.
+var ErrUnauthenticated = errors.New("недействительный API-ключ")
+
+// Authenticator проверяет API-ключ и возвращает его владельца.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (Principal, error)
+}
+
+// Principal — аутентифицированный клиент: пользователь и, если запрос
+// подписан API-ключом, идентификатор ключа и его области доступа.
+type Principal struct {
+	UserID   int
+	APIKeyID int
+	Scopes   []string
+}
+
+// HasScope сообщает, разрешена ли клиенту область доступа.
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// NewContext возвращает контекст с клиентом запроса.
+func NewContext(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext возвращает клиента запроса, если запрос аутентифицирован.
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
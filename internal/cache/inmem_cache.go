@@ -1,52 +1,172 @@
 package cache
 
 import (
-	"fmt"
+	"context"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
+// sweepEvery задает, через сколько записей удалять истекшие ключи.
+const sweepEvery = 1024
+
+// InMemoryCache хранит значения в памяти процесса. Истекшие ключи не
+// возвращаются сразу и удаляются вместе с привязками к тегам каждые
+// sweepEvery записей, поэтому отрицательные записи от перебора ID не копятся.
 type InMemoryCache struct {
-	mu    sync.RWMutex
-	store map[string]cacheItem
+	mu     sync.RWMutex
+	store  map[string]cacheItem
+	tags   map[string]map[string]struct{} // тег -> множество ключей
+	writes int
 }
 
 type cacheItem struct {
-	value      string
+	value      []byte
 	expiration time.Time
+	tags       []string
 }
 
 func NewInMemoryCache() *InMemoryCache {
 	return &InMemoryCache{
 		store: make(map[string]cacheItem),
+		tags:  make(map[string]map[string]struct{}),
 	}
 }
 
-func (c *InMemoryCache) Get(key string) (string, error) {
+func (c *InMemoryCache) Get(ctx context.Context, key string) ([]byte, error) {
+	entry, err := c.GetEntry(ctx, key)
+	return entry.Value, err
+}
+
+// GetEntry возвращает значение вместе с оставшимся временем жизни и тегами.
+func (c *InMemoryCache) GetEntry(_ context.Context, key string) (Entry, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	item, found := c.store[key]
-	if !found || time.Now().After(item.expiration) {
-		return "", fmt.Errorf("key not found or expired")
+	if !found {
+		return Entry{}, ErrMiss
 	}
-	return item.value, nil
+	ttl := time.Until(item.expiration)
+	if ttl <= 0 {
+		return Entry{}, ErrMiss
+	}
+	return Entry{Value: item.value, TTL: ttl, Tags: item.tags}, nil
+}
+
+func (c *InMemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.SetWithTags(ctx, key, value, ttl)
 }
 
-func (c *InMemoryCache) Set(key, value string, ttl int) error {
+func (c *InMemoryCache) SetWithTags(_ context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	now := time.Now()
+	c.maybeSweep(now)
+	c.untag(key)
 	c.store[key] = cacheItem{
 		value:      value,
-		expiration: time.Now().Add(time.Duration(ttl) * time.Second),
+		expiration: now.Add(ttl),
+		tags:       tags,
+	}
+	for _, tag := range tags {
+		keys, ok := c.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			c.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
 	}
 	return nil
 }
 
-func (c *InMemoryCache) Delete(key string) error {
+// Add сохраняет значение, только если ключа нет или его срок жизни истек.
+func (c *InMemoryCache) Add(_ context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if item, found := c.store[key]; found && now.Before(item.expiration) {
+		return false, nil
+	}
+	c.maybeSweep(now)
+	c.untag(key)
+	c.store[key] = cacheItem{value: value, expiration: now.Add(ttl)}
+	return true, nil
+}
+
+func (c *InMemoryCache) Delete(_ context.Context, key string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.untag(key)
 	delete(c.store, key)
 	return nil
 }
+
+// InvalidateTag удаляет все ключи, привязанные к тегу.
+func (c *InMemoryCache) InvalidateTag(_ context.Context, tag string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.tags[tag] {
+		c.untag(key)
+		delete(c.store, key)
+	}
+	delete(c.tags, tag)
+	return nil
+}
+
+// untag отвязывает ключ от всех его тегов. Вызывается под блокировкой.
+func (c *InMemoryCache) untag(key string) {
+	item, found := c.store[key]
+	if !found {
+		return
+	}
+	for _, tag := range item.tags {
+		delete(c.tags[tag], key)
+		if len(c.tags[tag]) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+}
+
+// maybeSweep удаляет истекшие ключи каждые sweepEvery записей.
+// Вызывается под блокировкой.
+func (c *InMemoryCache) maybeSweep(now time.Time) {
+	if c.writes++; c.writes%sweepEvery == 0 {
+		c.sweep(now)
+	}
+}
+
+// sweep удаляет истекшие к now ключи и их привязки к тегам.
+// Вызывается под блокировкой.
+func (c *InMemoryCache) sweep(now time.Time) {
+	for key, item := range c.store {
+		if !now.Before(item.expiration) {
+			c.untag(key)
+			delete(c.store, key)
+		}
+	}
+}
+
+// Keys возвращает неистекшие ключи, начинающиеся с prefix.
+func (c *InMemoryCache) Keys(_ context.Context, prefix string) ([]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := time.Now()
+	keys := []string{}
+	for key, item := range c.store {
+		if strings.HasPrefix(key, prefix) && now.Before(item.expiration) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Ping всегда успешен: локальный кеш доступен, пока жив процесс.
+func (c *InMemoryCache) Ping(_ context.Context) error {
+	return nil
+}
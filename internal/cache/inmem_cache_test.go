@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestInMemoryCacheSweepFreesExpiredKeys(t *testing.T) {
+	ctx := context.Background()
+	c := NewInMemoryCache()
+	if err := c.SetWithTags(ctx, "product:1", []byte("1"), time.Hour, "products"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetWithTags(ctx, "product:2:missing", []byte{1}, time.Minute, "products", "user:1:products"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Add(ctx, "idempotency:k", []byte("{}"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	c.mu.Lock()
+	c.sweep(time.Now().Add(30 * time.Minute))
+	c.mu.Unlock()
+
+	if _, ok := c.store["product:1"]; !ok || len(c.store) != 1 {
+		t.Errorf("keys after sweep = %v, want only product:1", c.store)
+	}
+	if _, ok := c.tags["user:1:products"]; ok || len(c.tags["products"]) != 1 {
+		t.Errorf("tags after sweep = %v, want products -> product:1", c.tags)
+	}
+}
+
+func TestInMemoryCacheSweepsOnWrites(t *testing.T) {
+	ctx := context.Background()
+	c := NewInMemoryCache()
+	for i := 0; i < sweepEvery-1; i++ {
+		if err := c.SetWithTags(ctx, fmt.Sprintf("user:%d:missing", i), []byte{1}, time.Nanosecond, "users"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(time.Millisecond)
+
+	// Запись с номером sweepEvery удаляет истекшие ключи
+	if err := c.Set(ctx, "user:live", []byte("1"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.store) != 1 || len(c.tags) != 0 {
+		t.Errorf("after sweep: %d keys, %d tags, want 1 key and no tags", len(c.store), len(c.tags))
+	}
+}
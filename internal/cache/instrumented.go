@@ -0,0 +1,228 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("Projectapirest/internal/cache")
+
+// Результаты операций с кешем для наблюдателя.
+const (
+	ResultHit   = "hit"
+	ResultMiss  = "miss"
+	ResultOK    = "ok"
+	ResultError = "error"
+)
+
+// Op описывает одну выполненную операцию с уровнем кеша.
+type Op struct {
+	Layer   string // имя уровня: inmem, redis
+	Name    string // get, set, delete, invalidate_tag
+	Prefix  string // пространство ключей, см. KeyPrefix
+	Result  string // hit, miss, ok, error
+	Latency time.Duration
+	Size    int // размер значения в байтах для get/set
+}
+
+// Observer получает сведения о каждой операции с кешем.
+type Observer interface {
+	ObserveCacheOp(op Op)
+}
+
+// Inspector реализуется уровнями, которые умеют перечислять свои ключи.
+type Inspector interface {
+	Keys(ctx context.Context, prefix string) ([]string, error)
+}
+
+// Pinger реализуется уровнями, доступность которых можно проверить.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// InstrumentedCache оборачивает уровень кеша, открывает span OpenTelemetry
+// и сообщает наблюдателю о каждой операции. Сохраняет поддержку EntryGetter, Adder и Inspector.
+type InstrumentedCache struct {
+	cache    Cache
+	layer    string
+	observer Observer
+}
+
+// NewInstrumentedCache создает инструментированный уровень кеша с именем layer.
+func NewInstrumentedCache(layer string, cache Cache, observer Observer) *InstrumentedCache {
+	return &InstrumentedCache{
+		cache:    cache,
+		layer:    layer,
+		observer: observer,
+	}
+}
+
+// Layer возвращает имя уровня.
+func (c *InstrumentedCache) Layer() string {
+	return c.layer
+}
+
+// Unwrap возвращает исходный уровень кеша.
+func (c *InstrumentedCache) Unwrap() Cache {
+	return c.cache
+}
+
+func (c *InstrumentedCache) Get(ctx context.Context, key string) ([]byte, error) {
+	entry, err := c.GetEntry(ctx, key)
+	return entry.Value, err
+}
+
+// GetEntry читает запись с метаданными, если исходный уровень это поддерживает.
+func (c *InstrumentedCache) GetEntry(ctx context.Context, key string) (Entry, error) {
+	ctx, span, start := c.start(ctx, "get", key)
+	entry, err := GetEntry(ctx, c.cache, key)
+	c.finish(span, "get", key, readResult(err), start, len(entry.Value), err)
+	return entry, err
+}
+
+func (c *InstrumentedCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	ctx, span, start := c.start(ctx, "set", key)
+	err := c.cache.Set(ctx, key, value, ttl)
+	c.finish(span, "set", key, writeResult(err), start, len(value), err)
+	return err
+}
+
+func (c *InstrumentedCache) Delete(ctx context.Context, key string) error {
+	ctx, span, start := c.start(ctx, "delete", key)
+	err := c.cache.Delete(ctx, key)
+	c.finish(span, "delete", key, writeResult(err), start, 0, err)
+	return err
+}
+
+func (c *InstrumentedCache) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	ctx, span, start := c.start(ctx, "set", key)
+	err := c.cache.SetWithTags(ctx, key, value, ttl, tags...)
+	c.finish(span, "set", key, writeResult(err), start, len(value), err)
+	return err
+}
+
+func (c *InstrumentedCache) InvalidateTag(ctx context.Context, tag string) error {
+	ctx, span, start := c.start(ctx, "invalidate_tag", tagKeyPrefix+tag)
+	err := c.cache.InvalidateTag(ctx, tag)
+	c.finish(span, "invalidate_tag", tagKeyPrefix+tag, writeResult(err), start, 0, err)
+	return err
+}
+
+// Add атомарно сохраняет значение на исходном уровне, если он поддерживает Adder.
+func (c *InstrumentedCache) Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	adder, ok := c.cache.(Adder)
+	if !ok {
+		return false, errors.New("cache: layer does not support add")
+	}
+	ctx, span, start := c.start(ctx, "add", key)
+	added, err := adder.Add(ctx, key, value, ttl)
+	c.finish(span, "add", key, writeResult(err), start, len(value), err)
+	return added, err
+}
+
+// Keys перечисляет ключи исходного уровня, если он это поддерживает.
+func (c *InstrumentedCache) Keys(ctx context.Context, prefix string) ([]string, error) {
+	inspector, ok := c.cache.(Inspector)
+	if !ok {
+		return nil, errors.New("cache: layer does not support key listing")
+	}
+	return inspector.Keys(ctx, prefix)
+}
+
+// Ping проверяет доступность исходного уровня. Уровни без Pinger считаются доступными.
+func (c *InstrumentedCache) Ping(ctx context.Context) error {
+	if pinger, ok := c.cache.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// start открывает span операции уровня кеша.
+func (c *InstrumentedCache) start(ctx context.Context, name, key string) (context.Context, trace.Span, time.Time) {
+	ctx, span := tracer.Start(ctx, "cache."+c.layer+"."+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("cache.layer", c.layer),
+			attribute.String("cache.key_prefix", KeyPrefix(key)),
+		),
+	)
+	return ctx, span, time.Now()
+}
+
+// finish закрывает span и сообщает об операции наблюдателю.
+func (c *InstrumentedCache) finish(span trace.Span, name, key, result string, start time.Time, size int, err error) {
+	latency := time.Since(start)
+	span.SetAttributes(attribute.String("cache.result", result))
+	if result == ResultError {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+
+	c.observer.ObserveCacheOp(Op{
+		Layer:   c.layer,
+		Name:    name,
+		Prefix:  KeyPrefix(key),
+		Result:  result,
+		Latency: latency,
+		Size:    size,
+	})
+}
+
+func readResult(err error) string {
+	switch {
+	case err == nil:
+		return ResultHit
+	case errors.Is(err, ErrMiss):
+		return ResultMiss
+	default:
+		return ResultError
+	}
+}
+
+func writeResult(err error) string {
+	if err != nil {
+		return ResultError
+	}
+	return ResultOK
+}
+
+// maxNamespaceLength ограничивает длину пространства ключей в метке.
+const maxNamespaceLength = 32
+
+// KeyPrefix сводит ключ к пространству ключей — его первому сегменту:
+// "user:42" -> "user", "products:page:10:0" -> "products". Отрицательные
+// записи учитываются отдельно: "product:7:missing" -> "product:missing".
+// Остальные сегменты могут содержать данные клиента (ключ идемпотентности,
+// хеш API-ключа) и в метку не попадают; первый сегмент, не похожий на имя,
+// заменяется на "other". Так число меток метрик остается ограниченным.
+func KeyPrefix(key string) string {
+	namespace, rest, _ := strings.Cut(key, ":")
+	if !isName(namespace) {
+		return "other"
+	}
+	if id, suffix, ok := strings.Cut(rest, ":"); ok && suffix == "missing" && id != "" {
+		return namespace + ":missing"
+	}
+	return namespace
+}
+
+// isName сообщает, состоит ли s из строчных латинских букв и "_".
+func isName(s string) bool {
+	if s == "" || len(s) > maxNamespaceLength {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyPrefix(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "user:42", want: "user"},
+		{key: "user:42:missing", want: "user:missing"},
+		{key: "user:42:products", want: "user"},
+		{key: "users:all", want: "users"},
+		{key: "products:page:10:0", want: "products"},
+		{key: "product:7:missing", want: "product:missing"},
+		{key: "apikey:" + strings.Repeat("ab", 32), want: "apikey"},
+		{key: "apikey:" + strings.Repeat("cd", 32) + ":missing", want: "apikey:missing"},
+		{key: "idempotency:ip:10.0.0.1:3f2b8c1e-6c1a-4c55-9b1e-0a8f0e2d7c11", want: "idempotency"},
+		{key: "idempotency:user:7:client-key", want: "idempotency"},
+		// Ключ клиента, оканчивающийся на ":missing", не меняет метку
+		{key: "idempotency:user:7:x:missing", want: "idempotency"},
+		{key: "", want: "other"},
+		{key: "42", want: "other"},
+		{key: "User:1", want: "other"},
+		{key: strings.Repeat("a", 100) + ":1", want: "other"},
+	}
+	for _, tt := range tests {
+		if got := KeyPrefix(tt.key); got != tt.want {
+			t.Errorf("KeyPrefix(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
@@ -2,35 +2,220 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"github.com/go-redis/redis/v8"
+	"sort"
+	"strings"
 	"time"
 )
 
+const (
+	// tagKeyPrefix задает префикс ключей Redis, в которых хранятся множества ключей тега.
+	tagKeyPrefix = "tag:"
+	// keyTagsPrefix задает префикс обратного индекса: множество тегов ключа.
+	keyTagsPrefix = "keytags:"
+)
+
+// maxTxRetries ограничивает повторы транзакции, отслеживаемые ключи которой
+// изменил другой клиент.
+const maxTxRetries = 10
+
+// RedisCache хранит записи в Redis под общим префиксом, чтобы кеш не смешивался
+// с другими данными в той же базе Redis. Множества тегов хранят ключи без префикса.
 type RedisCache struct {
 	client *redis.Client
+	prefix string
 }
 
-func NewRedisCache(addr, password string, db int) *RedisCache {
+// NewRedisCache создает кеш, ключи которого начинаются с prefix.
+func NewRedisCache(addr, password string, db int, prefix string) *RedisCache {
 	return &RedisCache{
 		client: redis.NewClient(&redis.Options{
 			Addr:     addr,
 			Password: password,
 			DB:       db,
 		}),
+		prefix: prefix,
 	}
 }
 
-func (r *RedisCache) Get(key string) (string, error) {
-	ctx := context.Background()
-	return r.client.Get(ctx, key).Result()
+// key возвращает ключ Redis для ключа кеша.
+func (r *RedisCache) key(key string) string {
+	return r.prefix + key
+}
+
+func (r *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := r.client.Get(ctx, r.key(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMiss
+	}
+	return value, err
+}
+
+// GetEntry возвращает значение вместе с оставшимся временем жизни (PTTL) и тегами.
+func (r *RedisCache) GetEntry(ctx context.Context, key string) (Entry, error) {
+	var (
+		get  *redis.StringCmd
+		pttl *redis.DurationCmd
+		tags *redis.StringSliceCmd
+	)
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, r.key(key))
+		pttl = pipe.PTTL(ctx, r.key(key))
+		tags = pipe.SMembers(ctx, r.key(keyTagsPrefix+key))
+		return nil
+	})
+	if errors.Is(err, redis.Nil) {
+		return Entry{}, ErrMiss
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+	value, _ := get.Bytes()
+	entry := Entry{Value: value, Tags: tags.Val()}
+	// PTTL возвращает отрицательное значение для ключей без срока жизни
+	if ttl := pttl.Val(); ttl > 0 {
+		entry.TTL = ttl
+	}
+	return entry, nil
+}
+
+func (r *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.SetWithTags(ctx, key, value, ttl)
+}
+
+// Add сохраняет значение командой SET NX.
+func (r *RedisCache) Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, r.key(key), value, ttl).Result()
+}
+
+// Delete удаляет ключ и убирает его из множеств тегов.
+func (r *RedisCache) Delete(ctx context.Context, key string) error {
+	return r.retag(ctx, key, func(pipe redis.Pipeliner) {
+		pipe.Del(ctx, r.key(key))
+	})
 }
 
-func (r *RedisCache) Set(key, value string, ttl int) error {
-	ctx := context.Background()
-	return r.client.Set(ctx, key, value, time.Duration(ttl)*time.Second).Err()
+// SetWithTags сохраняет значение и переносит ключ из множеств прежних тегов
+// в множества новых. Множество тега живет не меньше самого долгоживущего
+// из его ключей; флаги EXPIRE NX и GT требуют Redis 7.
+func (r *RedisCache) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	return r.retag(ctx, key, func(pipe redis.Pipeliner) {
+		pipe.Set(ctx, r.key(key), value, ttl)
+		if len(tags) == 0 {
+			return
+		}
+		members := make([]interface{}, len(tags))
+		for i, tag := range tags {
+			members[i] = tag
+			tagKey := r.key(tagKeyPrefix + tag)
+			pipe.SAdd(ctx, tagKey, key)
+			if ttl > 0 {
+				pipe.ExpireNX(ctx, tagKey, ttl)
+				pipe.ExpireGT(ctx, tagKey, ttl)
+			}
+		}
+		pipe.SAdd(ctx, r.key(keyTagsPrefix+key), members...)
+		if ttl > 0 {
+			pipe.Expire(ctx, r.key(keyTagsPrefix+key), ttl)
+		}
+	})
+}
+
+// retag убирает ключ из множеств его тегов и выполняет change в той же
+// транзакции. Теги читаются из обратного индекса под WATCH, поэтому
+// транзакция затрагивает только известные клиенту ключи и повторяется,
+// если индекс изменился до ее выполнения.
+func (r *RedisCache) retag(ctx context.Context, key string, change func(pipe redis.Pipeliner)) error {
+	tagsKey := r.key(keyTagsPrefix + key)
+	return r.watch(ctx, func(tx *redis.Tx) error {
+		tags, err := tx.SMembers(ctx, tagsKey).Result()
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, tag := range tags {
+				pipe.SRem(ctx, r.key(tagKeyPrefix+tag), key)
+			}
+			pipe.Del(ctx, tagsKey)
+			change(pipe)
+			return nil
+		})
+		return err
+	}, tagsKey)
+}
+
+// watch выполняет fn под WATCH ключей keys и повторяет ее, пока другой
+// клиент меняет эти ключи, но не больше maxTxRetries раз.
+func (r *RedisCache) watch(ctx context.Context, fn func(tx *redis.Tx) error, keys ...string) error {
+	var err error
+	for i := 0; i < maxTxRetries; i++ {
+		err = r.client.Watch(ctx, fn, keys...)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return err
+}
+
+// Client возвращает клиент Redis для компонентов, которым нужны собственные
+// команды, например лимитеру запросов.
+func (r *RedisCache) Client() *redis.Client {
+	return r.client
+}
+
+// InvalidateTag удаляет все ключи, привязанные к тегу, их обратные индексы
+// и само множество тега. Ключи тега читаются под WATCH, так что ключ,
+// добавленный к тегу параллельно, тоже будет удален при повторе.
+func (r *RedisCache) InvalidateTag(ctx context.Context, tag string) error {
+	tagKey := r.key(tagKeyPrefix + tag)
+	return r.watch(ctx, func(tx *redis.Tx) error {
+		keys, err := tx.SMembers(ctx, tagKey).Result()
+		if err != nil {
+			return err
+		}
+		remove := make([]string, 0, 2*len(keys)+1)
+		for _, key := range keys {
+			remove = append(remove, r.key(key), r.key(keyTagsPrefix+key))
+		}
+		remove = append(remove, tagKey)
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, remove...)
+			return nil
+		})
+		return err
+	}, tagKey)
+}
+
+// Keys возвращает ключи кеша, начинающиеся с prefix. Использует SCAN, чтобы
+// не блокировать Redis, и не выходит за пределы префикса кеша; служебные
+// множества тегов в результат не попадают.
+func (r *RedisCache) Keys(ctx context.Context, prefix string) ([]string, error) {
+	keys := []string{}
+	iter := r.client.Scan(ctx, 0, globEscaper.Replace(r.key(prefix))+"*", 500).Iterator()
+	for iter.Next(ctx) {
+		key := strings.TrimPrefix(iter.Val(), r.prefix)
+		if strings.HasPrefix(key, tagKeyPrefix) || strings.HasPrefix(key, keyTagsPrefix) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// globEscaper экранирует спецсимволы шаблона MATCH.
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// PoolStats возвращает статистику пула соединений Redis.
+func (r *RedisCache) PoolStats() *redis.PoolStats {
+	return r.client.PoolStats()
 }
 
-func (r *RedisCache) Delete(key string) error {
-	ctx := context.Background()
-	return r.client.Del(ctx, key).Err()
+// Ping проверяет доступность Redis.
+func (r *RedisCache) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
 }
@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// testPrefix — префикс ключей кеша в тестах.
+const testPrefix = "app:"
+
+// newTestRedisCache поднимает Redis в памяти процесса.
+func newTestRedisCache(t *testing.T) (*RedisCache, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	c := NewRedisCache(server.Addr(), "", 0, testPrefix)
+	t.Cleanup(func() { c.client.Close() })
+	return c, server
+}
+
+func TestRedisCacheTagSets(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// change меняет ключ "a", изначально помеченный тегами x и y
+		change func(c *RedisCache) error
+		// wantX и wantY — ожидаемые члены множеств тегов после change
+		wantX, wantY []string
+	}{
+		{
+			name:   "delete removes key from tag sets",
+			change: func(c *RedisCache) error { return c.Delete(ctx, "a") },
+			wantX:  []string{"b"},
+			wantY:  nil,
+		},
+		{
+			name: "retagging moves key between tag sets",
+			change: func(c *RedisCache) error {
+				return c.SetWithTags(ctx, "a", []byte("2"), time.Minute, "y")
+			},
+			wantX: []string{"b"},
+			wantY: []string{"a"},
+		},
+		{
+			name:   "set without tags clears tag sets",
+			change: func(c *RedisCache) error { return c.Set(ctx, "a", []byte("2"), time.Minute) },
+			wantX:  []string{"b"},
+			wantY:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, server := newTestRedisCache(t)
+			if err := c.SetWithTags(ctx, "a", []byte("1"), time.Minute, "x", "y"); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.SetWithTags(ctx, "b", []byte("1"), time.Minute, "x"); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.change(c); err != nil {
+				t.Fatal(err)
+			}
+			for tag, want := range map[string][]string{"x": tt.wantX, "y": tt.wantY} {
+				got, _ := server.Members(testPrefix + tagKeyPrefix + tag)
+				if !equalKeys(got, want) {
+					t.Errorf("tag %s members = %v, want %v", tag, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRedisCacheInvalidateTag(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestRedisCache(t)
+	for _, key := range []string{"a", "b"} {
+		if err := c.SetWithTags(ctx, key, []byte("1"), time.Minute, "x"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Set(ctx, "c", []byte("1"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.InvalidateTag(ctx, "x"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if _, err := c.Get(ctx, key); !errors.Is(err, ErrMiss) {
+			t.Errorf("Get(%s) error = %v, want ErrMiss", key, err)
+		}
+	}
+	if _, err := c.Get(ctx, "c"); err != nil {
+		t.Errorf("untagged key was removed: %v", err)
+	}
+	if got := server.Keys(); !equalKeys(got, []string{testPrefix + "c"}) {
+		t.Errorf("keys after invalidation = %v, want [%sc]", got, testPrefix)
+	}
+}
+
+func TestRedisCacheTagSetOutlivesKeys(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestRedisCache(t)
+	if err := c.SetWithTags(ctx, "long", []byte("1"), time.Hour, "x"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetWithTags(ctx, "short", []byte("1"), time.Minute, "x"); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.TTL(testPrefix + tagKeyPrefix + "x"); got != time.Hour {
+		t.Errorf("tag set TTL = %v, want %v", got, time.Hour)
+	}
+}
+
+func TestRedisCacheConcurrentRetagging(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestRedisCache(t)
+
+	// Каждый клиент помечает ключ своим тегом; после всех записей ключ должен
+	// остаться только в множестве тега из обратного индекса
+	tags := make([]string, maxTxRetries)
+	errs := make([]error, len(tags))
+	var wg sync.WaitGroup
+	for i := range tags {
+		tags[i] = fmt.Sprintf("t%d", i)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = c.SetWithTags(ctx, "a", []byte("1"), time.Minute, tags[i])
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	current, _ := server.Members(testPrefix + keyTagsPrefix + "a")
+	if len(current) != 1 {
+		t.Fatalf("key tags = %v, want one tag", current)
+	}
+	for _, tag := range tags {
+		var want []string
+		if tag == current[0] {
+			want = []string{"a"}
+		}
+		if got, _ := server.Members(testPrefix + tagKeyPrefix + tag); !equalKeys(got, want) {
+			t.Errorf("tag %s members = %v, want %v", tag, got, want)
+		}
+	}
+}
+
+func TestRedisCacheKeysStayWithinPrefix(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestRedisCache(t)
+	if err := c.SetWithTags(ctx, "user:1", []byte("1"), time.Minute, "users"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(ctx, "product:1", []byte("1"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	// Чужие ключи в той же базе Redis
+	server.Set("user:2", "foreign")
+	server.Set("session:1", "foreign")
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{prefix: "", want: []string{"product:1", "user:1"}},
+		{prefix: "user:", want: []string{"user:1"}},
+		{prefix: "session:", want: []string{}},
+	}
+	for _, tt := range tests {
+		got, err := c.Keys(ctx, tt.prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equalKeys(got, tt.want) {
+			t.Errorf("Keys(%q) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func equalKeys(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,55 +1,166 @@
 package cache
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"time"
 )
 
+// ErrMiss возвращается, когда ключа нет в кеше или срок его жизни истек.
+// Позволяет отличить промах от ошибки хранилища.
+var ErrMiss = errors.New("cache: miss")
+
+// defaultPromoteTTL используется при переносе значения на верхние уровни,
+// если нижний уровень не сообщает оставшееся время жизни.
+const defaultPromoteTTL = 300 * time.Second
+
 // Cache описывает интерфейс для кеширования.
 type Cache interface {
-	Get(key string) (string, error)
-	Set(key, value string, ttl int) error
-	Delete(key string) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	// SetWithTags сохраняет значение и привязывает ключ к тегам.
+	SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error
+	// InvalidateTag удаляет все ключи, привязанные к тегу.
+	InvalidateTag(ctx context.Context, tag string) error
+}
+
+// Entry описывает запись кеша вместе с метаданными.
+type Entry struct {
+	Value []byte
+	// TTL — оставшееся время жизни; 0, если срок не ограничен или неизвестен.
+	TTL  time.Duration
+	Tags []string
+}
+
+// EntryGetter реализуется уровнями, которые умеют вернуть запись
+// с оставшимся временем жизни и тегами.
+type EntryGetter interface {
+	GetEntry(ctx context.Context, key string) (Entry, error)
+}
+
+// Adder реализуется уровнями, которые умеют атомарно сохранить значение,
+// только если ключа еще нет. Используется для блокировок и резервирования ключей.
+type Adder interface {
+	// Add сохраняет значение и возвращает true, если ключ отсутствовал.
+	Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+}
+
+// Layer описывает уровень многослойного кеша и его политику.
+type Layer struct {
+	Cache Cache
+	// MaxTTL ограничивает время жизни записей на уровне; 0 — без ограничения.
+	MaxTTL time.Duration
 }
 
 // MultiLevelCache представляет многослойный кеш.
 type MultiLevelCache struct {
-	caches []Cache
+	layers []Layer
 }
 
-// NewMultiLevelCache создает новый многослойный кеш.
+// NewMultiLevelCache создает новый многослойный кеш без ограничений TTL на уровнях.
 func NewMultiLevelCache(caches ...Cache) *MultiLevelCache {
+	layers := make([]Layer, len(caches))
+	for i, cache := range caches {
+		layers[i] = Layer{Cache: cache}
+	}
+	return NewMultiLevelCacheWithLayers(layers...)
+}
+
+// NewMultiLevelCacheWithLayers создает многослойный кеш с политиками уровней.
+// Уровни перечисляются сверху вниз: от самого быстрого к самому медленному.
+func NewMultiLevelCacheWithLayers(layers ...Layer) *MultiLevelCache {
 	return &MultiLevelCache{
-		caches: caches,
+		layers: layers,
 	}
 }
 
 // Get получает значение из кеша. Поиск идет сверху вниз.
-func (m *MultiLevelCache) Get(key string) (string, error) {
-	for _, cache := range m.caches {
-		if value, err := cache.Get(key); err == nil && value != "" {
-			// Если найдено, обновляем более высокие уровни кеша
-			_ = m.Set(key, value, 300)
-			return value, nil
+// Если ни один уровень не содержит ключ, возвращается ErrMiss либо
+// первая ошибка хранилища, если она была.
+func (m *MultiLevelCache) Get(ctx context.Context, key string) ([]byte, error) {
+	var firstErr error
+	for i, layer := range m.layers {
+		entry, err := GetEntry(ctx, layer.Cache, key)
+		if err == nil {
+			// Если найдено, обновляем только более высокие уровни кеша
+			m.promote(ctx, key, entry, i)
+			return entry.Value, nil
+		}
+		if !errors.Is(err, ErrMiss) && firstErr == nil {
+			firstErr = fmt.Errorf("cache layer %d: %w", i, err)
 		}
 	}
-	return "", errors.New("key not found")
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return nil, ErrMiss
+}
+
+// promote копирует запись, найденную на уровне found, на все уровни выше него.
+// Ошибки записи не прерывают чтение: значение уже получено.
+func (m *MultiLevelCache) promote(ctx context.Context, key string, entry Entry, found int) {
+	ttl := entry.TTL
+	if ttl <= 0 {
+		ttl = defaultPromoteTTL
+	}
+	for _, layer := range m.layers[:found] {
+		_ = layer.Cache.SetWithTags(ctx, key, entry.Value, layer.clamp(ttl), entry.Tags...)
+	}
 }
 
 // Set сохраняет значение во все уровни кеша.
-func (m *MultiLevelCache) Set(key string, value string, ttl int) error {
-	for _, cache := range m.caches {
-		if err := cache.Set(key, value, ttl); err != nil {
-			// Логируем ошибку, но продолжаем, чтобы обновить другие уровни
-			continue
+// Ошибки уровней не прерывают запись в остальные и возвращаются вместе.
+func (m *MultiLevelCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return m.SetWithTags(ctx, key, value, ttl)
+}
+
+// Delete удаляет ключ из всех уровней кеша.
+func (m *MultiLevelCache) Delete(ctx context.Context, key string) error {
+	return m.each(func(layer Layer) error {
+		return layer.Cache.Delete(ctx, key)
+	})
+}
+
+// SetWithTags сохраняет значение с тегами во все уровни кеша.
+func (m *MultiLevelCache) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	return m.each(func(layer Layer) error {
+		return layer.Cache.SetWithTags(ctx, key, value, layer.clamp(ttl), tags...)
+	})
+}
+
+// InvalidateTag удаляет ключи тега из всех уровней кеша.
+func (m *MultiLevelCache) InvalidateTag(ctx context.Context, tag string) error {
+	return m.each(func(layer Layer) error {
+		return layer.Cache.InvalidateTag(ctx, tag)
+	})
+}
+
+// each применяет операцию ко всем уровням и объединяет их ошибки.
+func (m *MultiLevelCache) each(op func(layer Layer) error) error {
+	var errs []error
+	for i, layer := range m.layers {
+		if err := op(layer); err != nil {
+			errs = append(errs, fmt.Errorf("cache layer %d: %w", i, err))
 		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
-// Delete удаляет ключ из всех уровней кеша.
-func (m *MultiLevelCache) Delete(key string) error {
-	for _, cache := range m.caches {
-		_ = cache.Delete(key) // Игнорируем ошибки
+// clamp ограничивает TTL максимальным значением уровня.
+func (l Layer) clamp(ttl time.Duration) time.Duration {
+	if l.MaxTTL > 0 && (ttl <= 0 || ttl > l.MaxTTL) {
+		return l.MaxTTL
+	}
+	return ttl
+}
+
+// GetEntry читает запись с метаданными, если уровень это поддерживает.
+func GetEntry(ctx context.Context, cache Cache, key string) (Entry, error) {
+	if getter, ok := cache.(EntryGetter); ok {
+		return getter.GetEntry(ctx, key)
 	}
-	return nil
+	value, err := cache.Get(ctx, key)
+	return Entry{Value: value}, err
 }
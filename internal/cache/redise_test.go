@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestMultiLevelCache собирает кеш из уровня в памяти с MaxTTL и Redis,
+// как в приложении.
+func newTestMultiLevelCache(t *testing.T, maxTTL time.Duration) (*MultiLevelCache, *InMemoryCache, *RedisCache) {
+	t.Helper()
+	memory := NewInMemoryCache()
+	redis, _ := newTestRedisCache(t)
+	return NewMultiLevelCacheWithLayers(Layer{Cache: memory, MaxTTL: maxTTL}, Layer{Cache: redis}), memory, redis
+}
+
+func TestMultiLevelCachePromotesFromRedis(t *testing.T) {
+	ctx := context.Background()
+	c, memory, redis := newTestMultiLevelCache(t, time.Minute)
+	if err := redis.SetWithTags(ctx, "product:1", []byte("1"), time.Hour, "products"); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := c.Get(ctx, "product:1")
+	if err != nil || string(value) != "1" {
+		t.Fatalf("Get = %q, %v, want 1", value, err)
+	}
+	entry, err := memory.GetEntry(ctx, "product:1")
+	if err != nil {
+		t.Fatalf("value was not promoted to memory: %v", err)
+	}
+	// Время жизни в памяти ограничено MaxTTL уровня, а не остатком в Redis
+	if entry.TTL <= 0 || entry.TTL > time.Minute {
+		t.Errorf("promoted TTL = %v, want at most %v", entry.TTL, time.Minute)
+	}
+	if len(entry.Tags) != 1 || entry.Tags[0] != "products" {
+		t.Errorf("promoted tags = %v, want [products]", entry.Tags)
+	}
+
+	// Инвалидация тега убирает продвинутую копию
+	if err := c.InvalidateTag(ctx, "products"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := memory.Get(ctx, "product:1"); !errors.Is(err, ErrMiss) {
+		t.Errorf("memory Get after InvalidateTag error = %v, want ErrMiss", err)
+	}
+}
+
+func TestMultiLevelCacheClampsTTL(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name             string
+		ttl              time.Duration
+		wantMemory       time.Duration
+		wantRedisUnbound bool
+		wantRedisAtLeast time.Duration
+	}{
+		{name: "longer than MaxTTL", ttl: time.Hour, wantMemory: time.Minute, wantRedisAtLeast: 59 * time.Minute},
+		{name: "shorter than MaxTTL", ttl: 30 * time.Second, wantMemory: 30 * time.Second, wantRedisAtLeast: 29 * time.Second},
+		{name: "unbounded", ttl: 0, wantMemory: time.Minute, wantRedisUnbound: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, memory, redis := newTestMultiLevelCache(t, time.Minute)
+			if err := c.Set(ctx, "k", []byte("1"), tt.ttl); err != nil {
+				t.Fatal(err)
+			}
+			entry, err := memory.GetEntry(ctx, "k")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entry.TTL <= tt.wantMemory-time.Second || entry.TTL > tt.wantMemory {
+				t.Errorf("memory TTL = %v, want %v", entry.TTL, tt.wantMemory)
+			}
+			entry, err = redis.GetEntry(ctx, "k")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantRedisUnbound && entry.TTL != 0 {
+				t.Errorf("redis TTL = %v, want unbounded", entry.TTL)
+			}
+			if !tt.wantRedisUnbound && entry.TTL < tt.wantRedisAtLeast {
+				t.Errorf("redis TTL = %v, want at least %v", entry.TTL, tt.wantRedisAtLeast)
+			}
+		})
+	}
+}
+
+func TestMultiLevelCacheFallsBackToMemory(t *testing.T) {
+	ctx := context.Background()
+	memory := NewInMemoryCache()
+	redis, server := newTestRedisCache(t)
+	c := NewMultiLevelCacheWithLayers(Layer{Cache: memory, MaxTTL: time.Minute}, Layer{Cache: redis})
+	if err := c.Set(ctx, "product:1", []byte("1"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	server.SetError("connection lost")
+	value, err := c.Get(ctx, "product:1")
+	if err != nil || string(value) != "1" {
+		t.Errorf("Get with Redis down = %q, %v, want value from memory", value, err)
+	}
+	// Запись при недоступном Redis доходит до памяти, а ошибка Redis возвращается
+	if err := c.Set(ctx, "product:2", []byte("2"), time.Hour); err == nil || !strings.Contains(err.Error(), "cache layer 1") {
+		t.Errorf("Set with Redis down error = %v, want error of layer 1", err)
+	}
+	if value, err := c.Get(ctx, "product:2"); err != nil || string(value) != "2" {
+		t.Errorf("Get of value written with Redis down = %q, %v, want 2", value, err)
+	}
+	// Промах в памяти при недоступном Redis — ошибка хранилища, а не ErrMiss
+	if _, err := c.Get(ctx, "product:3"); err == nil || errors.Is(err, ErrMiss) {
+		t.Errorf("Get of missing key with Redis down error = %v, want Redis error", err)
+	}
+}
+
+// failingCache отказывает во всех операциях.
+type failingCache struct {
+	err error
+}
+
+func (f failingCache) Get(context.Context, string) ([]byte, error)              { return nil, f.err }
+func (f failingCache) Set(context.Context, string, []byte, time.Duration) error { return f.err }
+func (f failingCache) Delete(context.Context, string) error                     { return f.err }
+func (f failingCache) SetWithTags(context.Context, string, []byte, time.Duration, ...string) error {
+	return f.err
+}
+func (f failingCache) InvalidateTag(context.Context, string) error { return f.err }
+
+func TestMultiLevelCacheJoinsLayerErrors(t *testing.T) {
+	ctx := context.Background()
+	errMemory, errRedis := errors.New("memory failed"), errors.New("redis failed")
+	healthy := NewInMemoryCache()
+	c := NewMultiLevelCache(failingCache{errMemory}, healthy, failingCache{errRedis})
+
+	ops := map[string]func() error{
+		"Set":           func() error { return c.Set(ctx, "k", []byte("1"), time.Minute) },
+		"Delete":        func() error { return c.Delete(ctx, "k") },
+		"InvalidateTag": func() error { return c.InvalidateTag(ctx, "t") },
+	}
+	for name, op := range ops {
+		t.Run(name, func(t *testing.T) {
+			err := op()
+			if !errors.Is(err, errMemory) || !errors.Is(err, errRedis) {
+				t.Fatalf("error = %v, want both layer errors", err)
+			}
+			for _, layer := range []string{"cache layer 0", "cache layer 2"} {
+				if !strings.Contains(err.Error(), layer) {
+					t.Errorf("error = %q, want mention of %s", err, layer)
+				}
+			}
+		})
+	}
+	// Исправный уровень получает запись, несмотря на отказы соседей
+	if err := c.Set(ctx, "k", []byte("1"), time.Minute); err == nil {
+		t.Fatal("Set error = nil, want joined errors")
+	}
+	if value, err := healthy.Get(ctx, "k"); err != nil || string(value) != "1" {
+		t.Errorf("healthy layer Get = %q, %v, want 1", value, err)
+	}
+}
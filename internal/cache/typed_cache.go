@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TypedCache оборачивает Cache и сериализует значения типа T заданным кодеком.
+type TypedCache[T any] struct {
+	cache Cache
+	codec Codec
+}
+
+// NewTypedCache создает типизированную обертку над кешем.
+// Если codec равен nil, используется JSONCodec.
+func NewTypedCache[T any](cache Cache, codec Codec) *TypedCache[T] {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+	return &TypedCache[T]{
+		cache: cache,
+		codec: codec,
+	}
+}
+
+// Get возвращает значение по ключу. При отсутствии ключа возвращается ErrMiss,
+// при поврежденных данных — ошибка декодирования.
+func (c *TypedCache[T]) Get(ctx context.Context, key string) (T, error) {
+	var value T
+	data, err := c.cache.Get(ctx, key)
+	if err != nil {
+		return value, err
+	}
+	if err := c.codec.Unmarshal(data, &value); err != nil {
+		return value, fmt.Errorf("cache: decode %q: %w", key, err)
+	}
+	return value, nil
+}
+
+// Set сериализует значение и сохраняет его с тегами.
+func (c *TypedCache[T]) Set(ctx context.Context, key string, value T, ttl time.Duration, tags ...string) error {
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("cache: encode %q: %w", key, err)
+	}
+	if len(tags) == 0 {
+		return c.cache.Set(ctx, key, data, ttl)
+	}
+	return c.cache.SetWithTags(ctx, key, data, ttl, tags...)
+}
+
+// Delete удаляет значение по ключу.
+func (c *TypedCache[T]) Delete(ctx context.Context, key string) error {
+	return c.cache.Delete(ctx, key)
+}
@@ -0,0 +1,32 @@
+// Package clock абстрагирует получение текущего времени, чтобы сервисы
+// и репозитории можно было проверять с детерминированными отметками времени.
+package clock
+
+import "time"
+
+// Clock возвращает текущее время.
+type Clock interface {
+	Now() time.Time
+}
+
+// Real — системные часы. Время приводится к UTC и усекается до микросекунд,
+// как его хранит PostgreSQL, чтобы значения не менялись после записи в базу.
+type Real struct{}
+
+// Now возвращает текущее системное время.
+func (Real) Now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// Func позволяет использовать функцию как Clock.
+type Func func() time.Time
+
+// Now вызывает функцию.
+func (f Func) Now() time.Time {
+	return f()
+}
+
+// Fixed возвращает часы, которые всегда показывают t.
+func Fixed(t time.Time) Clock {
+	return Func(func() time.Time { return t })
+}
@@ -0,0 +1,90 @@
+// Package grpc реализует gRPC-сервисы из api/proto/users.proto поверх сервисов
+// приложения. Через них же работает REST-шлюз.
+package grpc
+
+import (
+	"Projectapirest/internal/entity"
+	users "Projectapirest/internal/proto"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxPageLimit ограничивает размер страницы списка, как в HTTP-контроллерах.
+const maxPageLimit = 1000
+
+// page проверяет limit и offset запроса списка. Без обоих параметров paged
+// равен false и список возвращается целиком; offset без limit — ошибка.
+func page(limit, offset int32) (paged bool, err error) {
+	if limit == 0 && offset == 0 {
+		return false, nil
+	}
+	switch {
+	case limit == 0:
+		return false, status.Error(codes.InvalidArgument, "Invalid pagination: offset requires limit")
+	case limit < 1 || limit > maxPageLimit:
+		return false, status.Errorf(codes.InvalidArgument, "Invalid pagination: limit must be between 1 and %d", maxPageLimit)
+	case offset < 0:
+		return false, status.Error(codes.InvalidArgument, "Invalid pagination: offset must be a non-negative integer")
+	}
+	return true, nil
+}
+
+// formatTime форматирует время так же, как encoding/json форматирует time.Time.
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+// toUserMessage переводит пользователя в сообщение protobuf.
+func toUserMessage(user entity.User) *users.User {
+	return &users.User{
+		Id:        int32(user.ID),
+		Name:      user.Name,
+		Email:     user.Email,
+		CreatedAt: formatTime(user.CreatedAt),
+		UpdatedAt: formatTime(user.UpdatedAt),
+	}
+}
+
+// toProductMessage переводит продукт в сообщение protobuf.
+func toProductMessage(product entity.Product) *users.Product {
+	return &users.Product{
+		Id:          int32(product.ID),
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       product.Price,
+		UserId:      int32(product.UserID),
+		CreatedAt:   formatTime(product.CreatedAt),
+		UpdatedAt:   formatTime(product.UpdatedAt),
+	}
+}
+
+// toProductMessages переводит список продуктов в сообщения protobuf.
+func toProductMessages(products []entity.Product) []*users.Product {
+	messages := make([]*users.Product, len(products))
+	for i, product := range products {
+		messages[i] = toProductMessage(product)
+	}
+	return messages
+}
+
+// productEventTypes сопоставляет виды событий с перечислением protobuf.
+var productEventTypes = map[entity.ProductEventType]users.ProductEvent_Type{
+	entity.ProductCreated: users.ProductEvent_TYPE_CREATED,
+	entity.ProductUpdated: users.ProductEvent_TYPE_UPDATED,
+	entity.ProductDeleted: users.ProductEvent_TYPE_DELETED,
+}
+
+// toProductEventMessage переводит событие изменения продукта в сообщение protobuf.
+func toProductEventMessage(event entity.ProductEvent) *users.ProductEvent {
+	message := &users.ProductEvent{
+		Type:       productEventTypes[event.Type],
+		Product:    toProductMessage(event.Product),
+		OccurredAt: formatTime(event.OccurredAt),
+	}
+	if event.Type == entity.ProductDeleted {
+		message.Product = &users.Product{Id: int32(event.Product.ID)}
+	}
+	return message
+}
@@ -0,0 +1,160 @@
+package grpc
+
+import (
+	"Projectapirest/internal/apierror"
+	"Projectapirest/internal/entity"
+	users "Projectapirest/internal/proto"
+	"Projectapirest/internal/repository"
+	service "Projectapirest/internal/services"
+	"Projectapirest/internal/watch"
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ProductServer реализует users.ProductServiceServer.
+type ProductServer struct {
+	users.UnimplementedProductServiceServer
+	productService service.ProductService
+	events         *watch.Hub[entity.ProductEvent]
+}
+
+// NewProductServer создает gRPC-сервис продуктов. events — источник событий
+// для WatchProducts; без него метод возвращает Unimplemented.
+func NewProductServer(productService service.ProductService, events *watch.Hub[entity.ProductEvent]) *ProductServer {
+	return &ProductServer{productService: productService, events: events}
+}
+
+// CreateProduct создает новый продукт.
+func (s *ProductServer) CreateProduct(ctx context.Context, req *users.CreateProductRequest) (*users.ProductResponse, error) {
+	product, err := s.productService.Create(ctx, entity.Product{
+		Name:        req.GetName(),
+		Description: req.GetDescription(),
+		Price:       req.GetPrice(),
+		UserID:      int(req.GetUserId()),
+	})
+	if err != nil {
+		return nil, apierror.Status("Failed to create product", err)
+	}
+	return &users.ProductResponse{Product: toProductMessage(product)}, nil
+}
+
+// GetProduct возвращает продукт по ID.
+func (s *ProductServer) GetProduct(ctx context.Context, req *users.GetProductRequest) (*users.ProductResponse, error) {
+	product, err := s.productService.FindByID(ctx, int(req.GetId()))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apierror.Status("Product not found", err)
+	}
+	if err != nil {
+		return nil, apierror.Status("Failed to fetch product", err)
+	}
+	return &users.ProductResponse{Product: toProductMessage(product)}, nil
+}
+
+// UpdateProduct обновляет продукт по ID.
+func (s *ProductServer) UpdateProduct(ctx context.Context, req *users.UpdateProductRequest) (*users.ProductResponse, error) {
+	product, err := s.productService.Update(ctx, entity.Product{
+		ID:          int(req.GetId()),
+		Name:        req.GetName(),
+		Description: req.GetDescription(),
+		Price:       req.GetPrice(),
+		UserID:      int(req.GetUserId()),
+	})
+	if err != nil {
+		return nil, apierror.Status("Failed to update product", err)
+	}
+	return &users.ProductResponse{Product: toProductMessage(product)}, nil
+}
+
+// DeleteProduct удаляет продукт по ID.
+func (s *ProductServer) DeleteProduct(ctx context.Context, req *users.DeleteProductRequest) (*users.DeleteProductResponse, error) {
+	if err := s.productService.Delete(ctx, int(req.GetId())); err != nil {
+		return nil, apierror.Status("Failed to delete product", err)
+	}
+	return &users.DeleteProductResponse{}, nil
+}
+
+// ListProducts возвращает все продукты или страницу по limit и offset.
+func (s *ProductServer) ListProducts(ctx context.Context, req *users.ListProductsRequest) (*users.ListProductsResponse, error) {
+	paged, err := page(req.GetLimit(), req.GetOffset())
+	if err != nil {
+		return nil, err
+	}
+
+	var products []entity.Product
+	if paged {
+		products, err = s.productService.FindPage(ctx, int(req.GetLimit()), int(req.GetOffset()))
+	} else {
+		products, err = s.productService.FindAll(ctx)
+	}
+	if err != nil {
+		return nil, apierror.Status("Failed to fetch products", err)
+	}
+	return &users.ListProductsResponse{Products: toProductMessages(products)}, nil
+}
+
+// ListUserProducts возвращает продукты пользователя.
+func (s *ProductServer) ListUserProducts(ctx context.Context, req *users.ListUserProductsRequest) (*users.ListUserProductsResponse, error) {
+	products, err := s.productService.FindByUser(ctx, int(req.GetUserId()))
+	if err != nil {
+		return nil, apierror.Status("Failed to fetch products", err)
+	}
+	return &users.ListUserProductsResponse{Products: toProductMessages(products)}, nil
+}
+
+// StreamProducts отправляет продукты по одному, читая их порциями после курсора.
+func (s *ProductServer) StreamProducts(req *users.StreamProductsRequest, stream grpc.ServerStreamingServer[users.Product]) error {
+	size, err := batchSize(req.GetBatchSize())
+	if err != nil {
+		return err
+	}
+	fetch := func(ctx context.Context, afterID, limit int) ([]entity.Product, error) {
+		products, err := s.productService.FindAfter(ctx, afterID, limit)
+		if err != nil {
+			return nil, apierror.Status("Failed to fetch products", err)
+		}
+		return products, nil
+	}
+	return streamAfter(stream.Context(), int(req.GetAfterId()), size, fetch,
+		func(product entity.Product) int { return product.ID }, toProductMessage, stream.Send)
+}
+
+// WatchProducts отправляет события изменений продуктов, пока клиент не
+// отменит вызов. Клиента, переполнившего буфер событий, поток завершает
+// с ResourceExhausted, а при остановке сервера — с Unavailable; в обоих
+// случаях клиенту стоит перечитать данные и подписаться снова. Изменения,
+// сделанные на других репликах, поток получает, только если hub наполняется
+// из общего потока outbox (OUTBOX_PUBLISHER=redis).
+func (s *ProductServer) WatchProducts(_ *users.WatchProductsRequest, stream grpc.ServerStreamingServer[users.ProductEvent]) error {
+	if s.events == nil {
+		return status.Error(codes.Unimplemented, "product events are disabled")
+	}
+	sub := s.events.Subscribe()
+	defer sub.Close()
+
+	// Заголовки уходят сразу, чтобы клиент знал, что подписка активна
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case event, ok := <-sub.Events():
+			if !ok {
+				if errors.Is(sub.Err(), watch.ErrClosed) {
+					return status.Error(codes.Unavailable, "server is shutting down")
+				}
+				return status.Error(codes.ResourceExhausted, "client is too slow to receive product events")
+			}
+			if err := stream.Send(toProductEventMessage(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Размер порции, которую потоковые методы читают из хранилища за раз.
+const (
+	defaultBatchSize = 100
+	maxBatchSize     = maxPageLimit
+)
+
+// batchSize проверяет размер порции из запроса; 0 означает значение по умолчанию.
+func batchSize(size int32) (int, error) {
+	switch {
+	case size == 0:
+		return defaultBatchSize, nil
+	case size < 0 || size > maxBatchSize:
+		return 0, status.Errorf(codes.InvalidArgument, "batch_size must be between 1 and %d", maxBatchSize)
+	}
+	return int(size), nil
+}
+
+// streamAfter читает записи порциями по курсору ID и отправляет их по одной.
+// Send блокируется, пока клиент не освободит окно HTTP/2, поэтому следующая
+// порция читается только после отправки предыдущей и сервер держит в памяти
+// не больше одной порции.
+func streamAfter[T, M any](
+	ctx context.Context,
+	afterID, size int,
+	fetch func(ctx context.Context, afterID, limit int) ([]T, error),
+	id func(T) int,
+	convert func(T) M,
+	send func(M) error,
+) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+		batch, err := fetch(ctx, afterID, size)
+		if err != nil {
+			return err
+		}
+		for _, item := range batch {
+			if err := send(convert(item)); err != nil {
+				return err
+			}
+		}
+		if len(batch) < size {
+			return nil
+		}
+		afterID = id(batch[len(batch)-1])
+	}
+}
@@ -0,0 +1,122 @@
+package grpc
+
+import (
+	"Projectapirest/internal/clock"
+	"Projectapirest/internal/entity"
+	users "Projectapirest/internal/proto"
+	"Projectapirest/internal/repository"
+	service "Projectapirest/internal/services"
+	"Projectapirest/internal/watch"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newClient поднимает сервис продуктов на bufconn и возвращает клиента к нему.
+func newClient(t *testing.T, products service.ProductService, events *watch.Hub[entity.ProductEvent]) users.ProductServiceClient {
+	t.Helper()
+	server := grpc.NewServer()
+	users.RegisterProductServiceServer(server, NewProductServer(products, events))
+	lis := bufconn.Listen(1 << 20)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return users.NewProductServiceClient(conn)
+}
+
+// newProductService создает сервис продуктов с одним пользователем и count продуктами.
+func newProductService(t *testing.T, count int, opts ...service.Option) service.ProductService {
+	t.Helper()
+	ctx := context.Background()
+	store := repository.NewMemoryStore(clock.Real{})
+	user, err := repository.NewMemoryUserRepository(store).Create(ctx, entity.User{Name: "Ann", Email: "ann@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	products := service.NewProductService(repository.NewMemoryProductRepository(store), opts...)
+	for i := 0; i < count; i++ {
+		if _, err := products.Create(ctx, entity.Product{Name: "item", Price: 1, UserID: user.ID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return products
+}
+
+func TestStreamProducts(t *testing.T) {
+	client := newClient(t, newProductService(t, 7), nil)
+
+	// Курсор после второго продукта, порции по два: 3-4, 5-6, 7
+	stream, err := client.StreamProducts(context.Background(), &users.StreamProductsRequest{AfterId: 2, BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int32
+	for {
+		product, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, product.GetId())
+	}
+	if want := []int32{3, 4, 5, 6, 7}; fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+
+	stream, err = client.StreamProducts(context.Background(), &users.StreamProductsRequest{BatchSize: maxBatchSize + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("oversized batch: %v, want InvalidArgument", err)
+	}
+}
+
+func TestWatchProducts(t *testing.T) {
+	events := watch.NewHub[entity.ProductEvent](4)
+	products := newProductService(t, 1, service.WithProductEvents(events.Publish))
+	client := newClient(t, products, events)
+
+	stream, err := client.WatchProducts(context.Background(), &users.WatchProductsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Заголовки приходят после подписки, поэтому событие ниже не потеряется
+	if _, err := stream.Header(); err != nil {
+		t.Fatal(err)
+	}
+	if err := products.Delete(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.GetType() != users.ProductEvent_TYPE_DELETED || event.GetProduct().GetId() != 1 || event.GetOccurredAt() == "" {
+		t.Errorf("event = %v, want deletion of product 1", event)
+	}
+
+	events.Close()
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("after hub close: %v, want Unavailable", err)
+	}
+}
@@ -0,0 +1,103 @@
+package grpc
+
+import (
+	"Projectapirest/internal/apierror"
+	"Projectapirest/internal/entity"
+	users "Projectapirest/internal/proto"
+	"Projectapirest/internal/repository"
+	service "Projectapirest/internal/services"
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+)
+
+// UserServer реализует users.UserServiceServer.
+type UserServer struct {
+	users.UnimplementedUserServiceServer
+	userService service.UserService
+}
+
+// NewUserServer создает gRPC-сервис пользователей.
+func NewUserServer(userService service.UserService) *UserServer {
+	return &UserServer{userService: userService}
+}
+
+// CreateUser создает нового пользователя.
+func (s *UserServer) CreateUser(ctx context.Context, req *users.CreateUserRequest) (*users.UserResponse, error) {
+	user, err := s.userService.Create(ctx, entity.User{Name: req.GetName(), Email: req.GetEmail()})
+	if err != nil {
+		return nil, apierror.Status("Failed to create user", err)
+	}
+	return &users.UserResponse{User: toUserMessage(user)}, nil
+}
+
+// GetUser возвращает пользователя по ID.
+func (s *UserServer) GetUser(ctx context.Context, req *users.GetUserRequest) (*users.UserResponse, error) {
+	user, err := s.userService.FindByID(ctx, int(req.GetId()))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apierror.Status("User not found", err)
+	}
+	if err != nil {
+		return nil, apierror.Status("Failed to fetch user", err)
+	}
+	return &users.UserResponse{User: toUserMessage(user)}, nil
+}
+
+// UpdateUser обновляет пользователя по ID.
+func (s *UserServer) UpdateUser(ctx context.Context, req *users.UpdateUserRequest) (*users.UserResponse, error) {
+	user, err := s.userService.Update(ctx, entity.User{ID: int(req.GetId()), Name: req.GetName(), Email: req.GetEmail()})
+	if err != nil {
+		return nil, apierror.Status("Failed to update user", err)
+	}
+	return &users.UserResponse{User: toUserMessage(user)}, nil
+}
+
+// DeleteUser удаляет пользователя по ID.
+func (s *UserServer) DeleteUser(ctx context.Context, req *users.DeleteUserRequest) (*users.DeleteUserResponse, error) {
+	if err := s.userService.Delete(ctx, int(req.GetId())); err != nil {
+		return nil, apierror.Status("Failed to delete user", err)
+	}
+	return &users.DeleteUserResponse{}, nil
+}
+
+// ListUsers возвращает всех пользователей или страницу по limit и offset.
+func (s *UserServer) ListUsers(ctx context.Context, req *users.ListUsersRequest) (*users.ListUsersResponse, error) {
+	paged, err := page(req.GetLimit(), req.GetOffset())
+	if err != nil {
+		return nil, err
+	}
+
+	var list []entity.User
+	if paged {
+		list, err = s.userService.FindPage(ctx, int(req.GetLimit()), int(req.GetOffset()))
+	} else {
+		list, err = s.userService.FindAll(ctx)
+	}
+	if err != nil {
+		return nil, apierror.Status("Failed to fetch users", err)
+	}
+
+	messages := make([]*users.User, len(list))
+	for i, user := range list {
+		messages[i] = toUserMessage(user)
+	}
+	return &users.ListUsersResponse{Users: messages}, nil
+}
+
+// StreamUsers отправляет пользователей по одному, читая их порциями после курсора.
+func (s *UserServer) StreamUsers(req *users.StreamUsersRequest, stream grpc.ServerStreamingServer[users.User]) error {
+	size, err := batchSize(req.GetBatchSize())
+	if err != nil {
+		return err
+	}
+	fetch := func(ctx context.Context, afterID, limit int) ([]entity.User, error) {
+		list, err := s.userService.FindAfter(ctx, afterID, limit)
+		if err != nil {
+			return nil, apierror.Status("Failed to fetch users", err)
+		}
+		return list, nil
+	}
+	return streamAfter(stream.Context(), int(req.GetAfterId()), size, fetch,
+		func(user entity.User) int { return user.ID }, toUserMessage, stream.Send)
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"Projectapirest/internal/entity"
+	service "Projectapirest/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// APIKeyController обрабатывает запросы управления API-ключами пользователя.
+type APIKeyController struct {
+	apiKeyService service.APIKeyService
+}
+
+// NewAPIKeyController создает новый контроллер API-ключей.
+func NewAPIKeyController(apiKeyService service.APIKeyService) *APIKeyController {
+	return &APIKeyController{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// CreateAPIKeyRequest — тело запроса на выпуск ключа.
+type CreateAPIKeyRequest struct {
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+// CreateAPIKeyResponse — выпущенный ключ и его открытое значение,
+// которое показывается только один раз.
+type CreateAPIKeyResponse struct {
+	APIKey entity.APIKey
+	Token  string
+}
+
+// CreateAPIKey выпускает ключ пользователю.
+func (kc *APIKeyController) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "APIKeyController.CreateAPIKey")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := service.DecodeRequestBody(r, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	created, token, err := kc.apiKeyService.Create(r.Context(), entity.APIKey{
+		UserID:    userID,
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		http.Error(w, "Failed to create API key: "+err.Error(), writeErrorStatus(err))
+		return
+	}
+
+	// Ключ показывается один раз: ответ не сохраняется ни кешами, ни
+	// хранилищем идемпотентности
+	w.Header().Set("Cache-Control", "no-store")
+	service.EncodeResponse(w, CreateAPIKeyResponse{APIKey: created, Token: token}, http.StatusCreated)
+}
+
+// ListAPIKeys возвращает ключи пользователя, включая отозванные.
+func (kc *APIKeyController) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "APIKeyController.ListAPIKeys")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	keys, err := kc.apiKeyService.List(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch API keys: "+err.Error(), writeErrorStatus(err))
+		return
+	}
+
+	service.EncodeResponse(w, keys, http.StatusOK)
+}
+
+// RevokeAPIKey отзывает ключ пользователя.
+func (kc *APIKeyController) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "APIKeyController.RevokeAPIKey")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	keyID, err := strconv.Atoi(r.PathValue("keyID"))
+	if err != nil {
+		http.Error(w, "Invalid API key ID: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := kc.apiKeyService.Revoke(r.Context(), userID, keyID); err != nil {
+		http.Error(w, "Failed to revoke API key: "+err.Error(), writeErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"Projectapirest/internal/cache"
+	service "Projectapirest/internal/services"
+	"errors"
+	"net/http"
+)
+
+// CacheAdminController обрабатывает административные запросы к кешу:
+// просмотр и сброс ключей на каждом уровне.
+type CacheAdminController struct {
+	layers []*cache.InstrumentedCache
+}
+
+// NewCacheAdminController создает контроллер для указанных уровней кеша.
+func NewCacheAdminController(layers ...*cache.InstrumentedCache) *CacheAdminController {
+	return &CacheAdminController{
+		layers: layers,
+	}
+}
+
+// layerKeys описывает ключи одного уровня кеша.
+type layerKeys struct {
+	Layer string   `json:"layer"`
+	Keys  []string `json:"keys"`
+	Error string   `json:"error,omitempty"`
+}
+
+// layerEntry описывает состояние ключа на одном уровне кеша.
+type layerEntry struct {
+	Layer      string   `json:"layer"`
+	Found      bool     `json:"found"`
+	TTLSeconds float64  `json:"ttl_seconds,omitempty"`
+	Size       int      `json:"size,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Value      string   `json:"value,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// ListKeys возвращает ключи каждого уровня, начинающиеся с параметра prefix.
+func (cc *CacheAdminController) ListKeys(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	result := make([]layerKeys, 0, len(cc.layers))
+	for _, layer := range cc.layers {
+		keys, err := layer.Keys(r.Context(), prefix)
+		item := layerKeys{Layer: layer.Layer(), Keys: keys}
+		if err != nil {
+			item.Error = err.Error()
+		}
+		result = append(result, item)
+	}
+	service.EncodeResponse(w, result, http.StatusOK)
+}
+
+// GetKey показывает значение, TTL и теги ключа на каждом уровне.
+// Чтение идет мимо инструментирования, чтобы не искажать метрики попаданий.
+func (cc *CacheAdminController) GetKey(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	result := make([]layerEntry, 0, len(cc.layers))
+	for _, layer := range cc.layers {
+		item := layerEntry{Layer: layer.Layer()}
+		entry, err := cache.GetEntry(r.Context(), layer.Unwrap(), key)
+		switch {
+		case err == nil:
+			item.Found = true
+			item.TTLSeconds = entry.TTL.Seconds()
+			item.Size = len(entry.Value)
+			item.Tags = entry.Tags
+			item.Value = string(entry.Value)
+		case !errors.Is(err, cache.ErrMiss):
+			item.Error = err.Error()
+		}
+		result = append(result, item)
+	}
+	service.EncodeResponse(w, result, http.StatusOK)
+}
+
+// DeleteKey удаляет ключ со всех уровней.
+func (cc *CacheAdminController) DeleteKey(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	for _, layer := range cc.layers {
+		if err := layer.Delete(r.Context(), key); err != nil {
+			http.Error(w, "Failed to delete key from "+layer.Layer()+": "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Flush удаляет со всех уровней ключи, начинающиеся с параметра prefix.
+// Без prefix очищается весь кеш.
+func (cc *CacheAdminController) Flush(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	deleted := make(map[string]int, len(cc.layers))
+	for _, layer := range cc.layers {
+		keys, err := layer.Keys(r.Context(), prefix)
+		if err != nil {
+			http.Error(w, "Failed to list keys in "+layer.Layer()+": "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, key := range keys {
+			if err := layer.Delete(r.Context(), key); err != nil {
+				http.Error(w, "Failed to delete key from "+layer.Layer()+": "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		deleted[layer.Layer()] = len(keys)
+	}
+	service.EncodeResponse(w, map[string]interface{}{"deleted": deleted}, http.StatusOK)
+}
@@ -0,0 +1,10 @@
+package http
+
+import "Projectapirest/internal/apierror"
+
+// writeErrorStatus подбирает HTTP-статус для ошибки изменения данных:
+// отсутствие записи и нарушения ограничений хранилища — ошибки клиента,
+// остальное — сервера. Таблица общая с gRPC-сервисами и REST-шлюзом.
+func writeErrorStatus(err error) int {
+	return apierror.HTTPStatus(err)
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"Projectapirest/internal/health"
+	"Projectapirest/internal/logger"
+	service "Projectapirest/internal/services"
+	"net/http"
+)
+
+// HealthController обрабатывает проверки живости и готовности.
+type HealthController struct {
+	checker *health.Checker
+}
+
+// NewHealthController создает контроллер проверок состояния.
+func NewHealthController(checker *health.Checker) *HealthController {
+	return &HealthController{
+		checker: checker,
+	}
+}
+
+// Liveness сообщает, что процесс жив. Зависимости не проверяются,
+// чтобы оркестратор не перезапускал приложение из-за недоступной базы.
+func (hc *HealthController) Liveness(w http.ResponseWriter, r *http.Request) {
+	service.EncodeResponse(w, map[string]health.Status{"status": health.StatusUp}, http.StatusOK)
+}
+
+// Readiness опрашивает зависимости и возвращает их состояние без текстов
+// ошибок; ошибки пишутся в журнал. В состоянии degraded приложение
+// продолжает принимать трафик.
+func (hc *HealthController) Readiness(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	report := hc.checker.Check(ctx)
+	for name, result := range report.Checks {
+		if result.Error != "" {
+			logger.FromContext(ctx).WarnContext(ctx, "readiness check failed", "check", name, "error", result.Error)
+		}
+	}
+	service.EncodeResponse(w, report.Public(), readinessStatus(report))
+}
+
+// ReadinessDetails возвращает полный отчет с ошибками зависимостей.
+// Предназначен для служебного сервера.
+func (hc *HealthController) ReadinessDetails(w http.ResponseWriter, r *http.Request) {
+	report := hc.checker.Check(r.Context())
+	service.EncodeResponse(w, report, readinessStatus(report))
+}
+
+// readinessStatus возвращает код ответа для отчета о готовности.
+func readinessStatus(report health.Report) int {
+	if report.Status == health.StatusDown {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
@@ -0,0 +1,33 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// maxPageLimit ограничивает размер страницы списка.
+const maxPageLimit = 1000
+
+// parsePage читает параметры limit и offset. Без обоих параметров
+// paged равен false и список возвращается целиком; offset без limit
+// считается ошибкой.
+func parsePage(r *http.Request) (limit, offset int, paged bool, err error) {
+	query := r.URL.Query()
+	rawLimit, rawOffset := query.Get("limit"), query.Get("offset")
+	if rawLimit == "" && rawOffset == "" {
+		return 0, 0, false, nil
+	}
+	if rawLimit == "" {
+		return 0, 0, false, errors.New("offset requires limit")
+	}
+	if limit, err = strconv.Atoi(rawLimit); err != nil || limit < 1 || limit > maxPageLimit {
+		return 0, 0, false, errors.New("limit must be between 1 and " + strconv.Itoa(maxPageLimit))
+	}
+	if rawOffset != "" {
+		if offset, err = strconv.Atoi(rawOffset); err != nil || offset < 0 {
+			return 0, 0, false, errors.New("offset must be a non-negative integer")
+		}
+	}
+	return limit, offset, true, nil
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"Projectapirest/internal/entity"
+	"Projectapirest/internal/repository"
 	service "Projectapirest/internal/services"
 	"encoding/json"
 	"errors"
@@ -24,6 +25,10 @@ func NewProductController(productService service.ProductService) *ProductControl
 
 // CreateProduct создает новый продукт
 func (pc *ProductController) CreateProduct(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "ProductController.CreateProduct")
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	var product entity.Product
 	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
@@ -32,7 +37,7 @@ func (pc *ProductController) CreateProduct(w http.ResponseWriter, r *http.Reques
 
 	createdProduct, err := pc.productService.Create(r.Context(), product)
 	if err != nil {
-		http.Error(w, "Failed to create product: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to create product: "+err.Error(), writeErrorStatus(err))
 		return
 	}
 
@@ -40,9 +45,24 @@ func (pc *ProductController) CreateProduct(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(createdProduct)
 }
 
-// GetAllProducts возвращает все продукты
+// GetAllProducts возвращает все продукты или страницу по limit и offset
 func (pc *ProductController) GetAllProducts(w http.ResponseWriter, r *http.Request) {
-	products, err := pc.productService.FindAll(r.Context())
+	ctx, span := tracer.Start(r.Context(), "ProductController.GetAllProducts")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	limit, offset, paged, err := parsePage(r)
+	if err != nil {
+		http.Error(w, "Invalid pagination: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var products []entity.Product
+	if paged {
+		products, err = pc.productService.FindPage(r.Context(), limit, offset)
+	} else {
+		products, err = pc.productService.FindAll(r.Context())
+	}
 	if err != nil {
 		http.Error(w, "Failed to fetch products: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -53,6 +73,10 @@ func (pc *ProductController) GetAllProducts(w http.ResponseWriter, r *http.Reque
 
 // GetProduct возвращает продукт по ID
 func (pc *ProductController) GetProduct(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "ProductController.GetProduct")
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	id, err := extractProductIDFromURL(r.URL.Path)
 	if err != nil {
 		http.Error(w, "Invalid product ID: "+err.Error(), http.StatusBadRequest)
@@ -60,16 +84,24 @@ func (pc *ProductController) GetProduct(w http.ResponseWriter, r *http.Request)
 	}
 
 	product, err := pc.productService.FindByID(r.Context(), id)
-	if err != nil {
+	if errors.Is(err, repository.ErrNotFound) {
 		http.Error(w, "Product not found: "+err.Error(), http.StatusNotFound)
 		return
 	}
+	if err != nil {
+		http.Error(w, "Failed to fetch product: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	json.NewEncoder(w).Encode(product)
 }
 
 // UpdateProduct обновляет продукт по ID
 func (pc *ProductController) UpdateProduct(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "ProductController.UpdateProduct")
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	id, err := extractProductIDFromURL(r.URL.Path)
 	if err != nil {
 		http.Error(w, "Invalid product ID: "+err.Error(), http.StatusBadRequest)
@@ -85,7 +117,7 @@ func (pc *ProductController) UpdateProduct(w http.ResponseWriter, r *http.Reques
 
 	product, err := pc.productService.Update(r.Context(), updatedProduct)
 	if err != nil {
-		http.Error(w, "Failed to update product: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to update product: "+err.Error(), writeErrorStatus(err))
 		return
 	}
 
@@ -94,6 +126,10 @@ func (pc *ProductController) UpdateProduct(w http.ResponseWriter, r *http.Reques
 
 // DeleteProduct удаляет продукт по ID
 func (pc *ProductController) DeleteProduct(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "ProductController.DeleteProduct")
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	id, err := extractProductIDFromURL(r.URL.Path)
 	if err != nil {
 		http.Error(w, "Invalid product ID: "+err.Error(), http.StatusBadRequest)
@@ -102,7 +138,7 @@ func (pc *ProductController) DeleteProduct(w http.ResponseWriter, r *http.Reques
 
 	err = pc.productService.Delete(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Failed to delete product: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to delete product: "+err.Error(), writeErrorStatus(err))
 		return
 	}
 
@@ -0,0 +1,5 @@
+package http
+
+import "go.opentelemetry.io/otel"
+
+var tracer = otel.Tracer("Projectapirest/internal/controller/http")
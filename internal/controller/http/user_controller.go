@@ -2,6 +2,7 @@ package http
 
 import (
 	"Projectapirest/internal/entity"
+	"Projectapirest/internal/repository"
 	service "Projectapirest/internal/services"
 	"errors"
 	"net/http"
@@ -23,6 +24,10 @@ func NewUserController(userService service.UserService) *UserController {
 
 // CreateUser создает нового пользователя.
 func (uc *UserController) CreateUser(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "UserController.CreateUser")
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	var user entity.User
 	// Десериализация запроса через функцию из сервиса
 	if err := service.DecodeUserRequestBody(r, &user); err != nil {
@@ -32,7 +37,7 @@ func (uc *UserController) CreateUser(w http.ResponseWriter, r *http.Request) {
 
 	createdUser, err := uc.userService.Create(r.Context(), user)
 	if err != nil {
-		http.Error(w, "Failed to create user: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to create user: "+err.Error(), writeErrorStatus(err))
 		return
 	}
 
@@ -40,9 +45,24 @@ func (uc *UserController) CreateUser(w http.ResponseWriter, r *http.Request) {
 	service.EncodeUserResponse(w, createdUser, http.StatusCreated)
 }
 
-// GetAllUsers возвращает всех пользователей.
+// GetAllUsers возвращает всех пользователей или страницу по limit и offset.
 func (uc *UserController) GetAllUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := uc.userService.FindAll(r.Context())
+	ctx, span := tracer.Start(r.Context(), "UserController.GetAllUsers")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	limit, offset, paged, err := parsePage(r)
+	if err != nil {
+		http.Error(w, "Invalid pagination: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var users []entity.User
+	if paged {
+		users, err = uc.userService.FindPage(r.Context(), limit, offset)
+	} else {
+		users, err = uc.userService.FindAll(r.Context())
+	}
 	if err != nil {
 		http.Error(w, "Failed to fetch users: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -54,6 +74,10 @@ func (uc *UserController) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 
 // GetUser возвращает пользователя по ID.
 func (uc *UserController) GetUser(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "UserController.GetUser")
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	id, err := extractUserIDFromURL(r.URL.Path)
 	if err != nil {
 		http.Error(w, "Invalid user ID: "+err.Error(), http.StatusBadRequest)
@@ -61,10 +85,14 @@ func (uc *UserController) GetUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user, err := uc.userService.FindByID(r.Context(), id)
-	if err != nil {
+	if errors.Is(err, repository.ErrNotFound) {
 		http.Error(w, "User not found: "+err.Error(), http.StatusNotFound)
 		return
 	}
+	if err != nil {
+		http.Error(w, "Failed to fetch user: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	// Сериализация ответа через функцию из сервиса
 	service.EncodeUserResponse(w, user, http.StatusOK)
@@ -72,6 +100,10 @@ func (uc *UserController) GetUser(w http.ResponseWriter, r *http.Request) {
 
 // UpdateUser обновляет пользователя по ID.
 func (uc *UserController) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "UserController.UpdateUser")
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	id, err := extractUserIDFromURL(r.URL.Path)
 	if err != nil {
 		http.Error(w, "Invalid user ID: "+err.Error(), http.StatusBadRequest)
@@ -88,7 +120,7 @@ func (uc *UserController) UpdateUser(w http.ResponseWriter, r *http.Request) {
 
 	user, err := uc.userService.Update(r.Context(), updatedUser)
 	if err != nil {
-		http.Error(w, "Failed to update user: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to update user: "+err.Error(), writeErrorStatus(err))
 		return
 	}
 
@@ -98,6 +130,10 @@ func (uc *UserController) UpdateUser(w http.ResponseWriter, r *http.Request) {
 
 // DeleteUser удаляет пользователя по ID.
 func (uc *UserController) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "UserController.DeleteUser")
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	id, err := extractUserIDFromURL(r.URL.Path)
 	if err != nil {
 		http.Error(w, "Invalid user ID: "+err.Error(), http.StatusBadRequest)
@@ -106,7 +142,7 @@ func (uc *UserController) DeleteUser(w http.ResponseWriter, r *http.Request) {
 
 	err = uc.userService.Delete(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Failed to delete user: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to delete user: "+err.Error(), writeErrorStatus(err))
 		return
 	}
 
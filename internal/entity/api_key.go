@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+// APIKey — ключ доступа машинного клиента, принадлежащий пользователю.
+// Сам ключ не хранится: только его SHA-256 и короткий префикс для показа.
+type APIKey struct {
+	ID         int
+	UserID     int
+	Name       string
+	Prefix     string
+	Hash       []byte `json:"-"`
+	Scopes     []string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+// Active сообщает, можно ли использовать ключ в момент now.
+func (k APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
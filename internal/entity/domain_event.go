@@ -0,0 +1,66 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DomainEventType — вид события предметной области. Значения уходят во внешние
+// системы, поэтому существующие менять нельзя.
+type DomainEventType string
+
+const (
+	EventUserCreated         DomainEventType = "user.created"
+	EventUserUpdated         DomainEventType = "user.updated"
+	EventUserDeleted         DomainEventType = "user.deleted"
+	EventProductCreated      DomainEventType = "product.created"
+	EventProductUpdated      DomainEventType = "product.updated"
+	EventProductPriceChanged DomainEventType = "product.price_changed"
+	EventProductDeleted      DomainEventType = "product.deleted"
+)
+
+// Виды агрегатов, к которым относятся события.
+const (
+	AggregateUser    = "user"
+	AggregateProduct = "product"
+)
+
+// DomainEvent — событие для внешних систем. ID присваивает outbox; по нему
+// получатели отбрасывают повторы, так как доставка выполняется хотя бы один раз.
+type DomainEvent struct {
+	ID            int64
+	Type          DomainEventType
+	AggregateType string
+	AggregateID   int
+	Payload       json.RawMessage
+	OccurredAt    time.Time
+	// Attempts — сколько раз событие выдавалось на отправку, включая текущую.
+	Attempts int
+}
+
+// NewDomainEvent создает событие с полезной нагрузкой payload в JSON.
+func NewDomainEvent(eventType DomainEventType, aggregateType string, aggregateID int, payload interface{}, at time.Time) (DomainEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return DomainEvent{}, err
+	}
+	return DomainEvent{
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Payload:       data,
+		OccurredAt:    at,
+	}, nil
+}
+
+// ProductPriceChange — полезная нагрузка события EventProductPriceChanged.
+type ProductPriceChange struct {
+	ProductID int
+	OldPrice  float64
+	NewPrice  float64
+}
+
+// Deleted — полезная нагрузка событий удаления.
+type Deleted struct {
+	ID int
+}
@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ProductEventType — вид изменения продукта.
+type ProductEventType string
+
+const (
+	ProductCreated ProductEventType = "created"
+	ProductUpdated ProductEventType = "updated"
+	ProductDeleted ProductEventType = "deleted"
+)
+
+// ProductEvent описывает изменение продукта. У события удаления заполнен
+// только Product.ID.
+type ProductEvent struct {
+	Type       ProductEventType
+	Product    Product
+	OccurredAt time.Time
+}
+
+// ProductEventFromDomain восстанавливает событие продукта из доменного
+// события outbox. ok ложно для событий, которых нет в WatchProducts.
+func ProductEventFromDomain(event DomainEvent) (productEvent ProductEvent, ok bool, err error) {
+	productEvent.OccurredAt = event.OccurredAt
+	switch event.Type {
+	case EventProductCreated:
+		productEvent.Type = ProductCreated
+	case EventProductUpdated:
+		productEvent.Type = ProductUpdated
+	case EventProductDeleted:
+		productEvent.Type = ProductDeleted
+		productEvent.Product.ID = event.AggregateID
+		return productEvent, true, nil
+	default:
+		return ProductEvent{}, false, nil
+	}
+	if err := json.Unmarshal(event.Payload, &productEvent.Product); err != nil {
+		return ProductEvent{}, false, fmt.Errorf("decode %s payload: %w", event.Type, err)
+	}
+	return productEvent, true, nil
+}
@@ -0,0 +1,156 @@
+// Package gateway собирает REST-шлюз, сгенерированный grpc-gateway из
+// аннотаций google.api.http в api/proto/users.proto. Шлюз проксирует запросы
+// к gRPC-серверу и отвечает так же, как HTTP-контроллеры: те же пути, JSON,
+// статусы и текстовые ошибки.
+package gateway
+
+import (
+	"Projectapirest/internal/apierror"
+	"Projectapirest/internal/auth"
+	"Projectapirest/internal/logger"
+	users "Projectapirest/internal/proto"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Dial открывает соединение шлюза с gRPC-сервером этого же процесса.
+// Адрес без хоста (":50051") означает локальный интерфейс.
+func Dial(grpcAddr string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	host, port, err := net.SplitHostPort(grpcAddr)
+	if err != nil {
+		return nil, err
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	return grpc.NewClient(net.JoinHostPort(host, port), append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		// Контекст трассировки HTTP-запроса продолжается в gRPC-вызове
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	}, opts...)...)
+}
+
+// New возвращает обработчик маршрутов /api/v1/users и /api/v1/products,
+// проксирующий их через conn. Аутентификация, ограничение частоты и повторы
+// по Idempotency-Key обрабатываются HTTP-слоем один раз: вызовы помечаются
+// секретом gatewayToken (auth.Policy.GatewayToken) и несут уже проверенного
+// клиента, поэтому gRPC-сервер их не проверяет и не ограничивает повторно.
+func New(ctx context.Context, conn *grpc.ClientConn, gatewayToken string) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, compactJSON{&runtime.JSONPb{
+			// Пустые поля и списки выводятся, как в encoding/json
+			MarshalOptions:   protojson.MarshalOptions{EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}}),
+		runtime.WithMetadata(func(ctx context.Context, r *http.Request) metadata.MD {
+			return metadata.Join(requestIDMetadata(ctx, r), auth.GatewayMetadata(ctx, gatewayToken))
+		}),
+		// Заголовки RateLimit-* и X-Request-ID уже выставил HTTP-слой
+		runtime.WithOutgoingHeaderMatcher(func(string) (string, bool) { return "", false }),
+		runtime.WithForwardResponseOption(responseStatus),
+		runtime.WithErrorHandler(errorHandler),
+	)
+	if err := users.RegisterUserServiceHandler(ctx, mux, conn); err != nil {
+		return nil, err
+	}
+	if err := users.RegisterProductServiceHandler(ctx, mux, conn); err != nil {
+		return nil, err
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(&noContentWriter{ResponseWriter: w}, r)
+	}), nil
+}
+
+// compactJSON убирает пробелы, которые protojson намеренно вставляет
+// случайным образом, и завершает ответ переводом строки, как json.Encoder
+// в HTTP-контроллерах.
+type compactJSON struct {
+	*runtime.JSONPb
+}
+
+func (m compactJSON) Marshal(v interface{}) ([]byte, error) {
+	data, err := m.JSONPb.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// requestIDMetadata передает в gRPC идентификатор запроса HTTP-слоя, чтобы
+// записи журнала обоих транспортов связывались.
+func requestIDMetadata(ctx context.Context, _ *http.Request) metadata.MD {
+	if id := logger.RequestID(ctx); id != "" {
+		return metadata.Pairs(logger.RequestIDHeader, id)
+	}
+	return nil
+}
+
+// responseStatus выставляет статусы HTTP-контроллеров: 201 для создания
+// и 204 без тела для удаления.
+func responseStatus(ctx context.Context, w http.ResponseWriter, _ proto.Message) error {
+	method, _ := runtime.RPCMethod(ctx)
+	name := method[strings.LastIndex(method, "/")+1:]
+	switch {
+	case strings.HasPrefix(name, "Create"):
+		w.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(name, "Delete"):
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusNoContent)
+	}
+	return nil
+}
+
+// errorHandler отвечает текстом ошибки со статусом из общей таблицы apierror.
+// Ошибки маршрутизации шлюза (405 и т. п.) сохраняют свой HTTP-статус.
+func errorHandler(_ context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, _ *http.Request, err error) {
+	var httpErr *runtime.HTTPStatusError
+	if errors.As(err, &httpErr) {
+		http.Error(w, status.Convert(httpErr.Err).Message(), httpErr.HTTPStatus)
+		return
+	}
+	s := status.Convert(err)
+	http.Error(w, s.Message(), apierror.HTTPStatusFromCode(s.Code()))
+}
+
+// noContentWriter отбрасывает тело после статуса 204: шлюз всегда пишет
+// сообщение ответа, а net/http запрещает тело у 204.
+type noContentWriter struct {
+	http.ResponseWriter
+	noContent bool
+}
+
+func (w *noContentWriter) WriteHeader(status int) {
+	w.noContent = status == http.StatusNoContent
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *noContentWriter) Write(b []byte) (int, error) {
+	if w.noContent {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap открывает исходный ResponseWriter для http.ResponseController.
+func (w *noContentWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
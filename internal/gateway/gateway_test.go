@@ -0,0 +1,238 @@
+package gateway
+
+import (
+	"Projectapirest/api/routes"
+	"Projectapirest/internal/auth"
+	"Projectapirest/internal/clock"
+	grpc2 "Projectapirest/internal/controller/grpc"
+	http2 "Projectapirest/internal/controller/http"
+	users "Projectapirest/internal/proto"
+	"Projectapirest/internal/ratelimit"
+	"Projectapirest/internal/repository"
+	service "Projectapirest/internal/services"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newServices создает сервисы поверх отдельного хранилища в памяти
+// с фиксированными часами, чтобы ответы двух транспортов совпадали побайтно.
+func newServices() (service.UserService, service.ProductService) {
+	store := repository.NewMemoryStore(clock.Fixed(time.Date(2024, 12, 1, 10, 0, 0, 0, time.UTC)))
+	return service.NewUserService(repository.NewMemoryUserRepository(store)),
+		service.NewProductService(repository.NewMemoryProductRepository(store))
+}
+
+// testGatewayToken — секрет, которым шлюз в тестах помечает вызовы.
+const testGatewayToken = "test-gateway-token"
+
+// newGateway поднимает gRPC-сервер на bufconn и шлюз поверх него.
+func newGateway(t *testing.T, opts ...grpc.ServerOption) *http.ServeMux {
+	t.Helper()
+	userService, productService := newServices()
+	server := grpc.NewServer(opts...)
+	users.RegisterUserServiceServer(server, grpc2.NewUserServer(userService))
+	users.RegisterProductServiceServer(server, grpc2.NewProductServer(productService, nil))
+	lis := bufconn.Listen(1 << 20)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	handler, err := New(context.Background(), conn, testGatewayToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return routes.SetupGatewayRoutes(handler)
+}
+
+// newControllers собирает маршруты HTTP-контроллеров.
+func newControllers() http.Handler {
+	userService, productService := newServices()
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/users", routes.SetupUserRoutes(http2.NewUserController(userService)))
+	mux.Handle("/api/v1/users/", routes.SetupUserRoutes(http2.NewUserController(userService)))
+	mux.Handle("/api/v1/products", routes.SetupProductRoutes(http2.NewProductController(productService)))
+	mux.Handle("/api/v1/products/", routes.SetupProductRoutes(http2.NewProductController(productService)))
+	return mux
+}
+
+// TestGatewayMatchesControllers прогоняет один сценарий через оба транспорта
+// и сравнивает статусы и тела ответов.
+func TestGatewayMatchesControllers(t *testing.T) {
+	steps := []struct {
+		method, path, body string
+		// statusOnly — тексты ошибок разбора запроса у транспортов различаются
+		statusOnly bool
+	}{
+		{method: "POST", path: "/api/v1/users", body: `{"name":"Ann","email":"ann@example.com"}`},
+		{method: "POST", path: "/api/v1/users", body: `{"Name":"Ann","Email":"ann@example.com"}`},
+		{method: "POST", path: "/api/v1/users", body: `{"name":"Bob","email":"bob@example.com","unknown":1}`},
+		{method: "POST", path: "/api/v1/users", body: `{`, statusOnly: true},
+		{method: "GET", path: "/api/v1/users"},
+		{method: "GET", path: "/api/v1/users?limit=1&offset=1"},
+		{method: "GET", path: "/api/v1/users?offset=1"},
+		{method: "GET", path: "/api/v1/users?limit=5000"},
+		{method: "GET", path: "/api/v1/users/1"},
+		{method: "GET", path: "/api/v1/users/99"},
+		{method: "GET", path: "/api/v1/users/abc", statusOnly: true},
+		{method: "PUT", path: "/api/v1/users/2", body: `{"Name":"Robert","Email":"ann@example.com"}`},
+		{method: "PUT", path: "/api/v1/users/2", body: `{"ID":7,"Name":"Robert","Email":"robert@example.com"}`},
+		{method: "PUT", path: "/api/v1/users/99", body: `{"Name":"Nobody","Email":"nobody@example.com"}`},
+		{method: "POST", path: "/api/v1/products", body: `{"Name":"book","Description":"","Price":9.99,"UserID":1}`},
+		{method: "POST", path: "/api/v1/products", body: `{"Name":"pen","Price":1,"UserID":99}`},
+		{method: "GET", path: "/api/v1/products"},
+		{method: "GET", path: "/api/v1/products/1"},
+		{method: "PUT", path: "/api/v1/products/1", body: `{"Name":"ebook","Price":5,"UserID":2}`},
+		{method: "DELETE", path: "/api/v1/users/2"},
+		{method: "DELETE", path: "/api/v1/products/1"},
+		{method: "DELETE", path: "/api/v1/products/1"},
+		{method: "DELETE", path: "/api/v1/users/2"},
+		{method: "PATCH", path: "/api/v1/users/1", statusOnly: true},
+	}
+
+	controllers, gateway := newControllers(), newGateway(t)
+	for _, step := range steps {
+		want := serve(controllers, step.method, step.path, step.body)
+		got := serve(gateway, step.method, step.path, step.body)
+		name := step.method + " " + step.path
+		if got.Code != want.Code {
+			t.Errorf("%s: gateway status %d, controllers %d (%q)", name, got.Code, want.Code, want.Body)
+			continue
+		}
+		if step.statusOnly {
+			continue
+		}
+		if got.Body.String() != want.Body.String() {
+			t.Errorf("%s: gateway body\n%q\ncontrollers body\n%q", name, got.Body, want.Body)
+		}
+		if want.Code == http.StatusNoContent && got.Header().Get("Content-Type") != "" {
+			t.Errorf("%s: 204 response has Content-Type %q", name, got.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestGatewayListUserProducts(t *testing.T) {
+	gateway := newGateway(t)
+	for _, user := range []string{`{"Name":"Ann","Email":"ann@example.com"}`, `{"Name":"Bob","Email":"bob@example.com"}`} {
+		serve(gateway, "POST", "/api/v1/users", user)
+	}
+	for _, product := range []string{`{"Name":"book","UserID":1}`, `{"Name":"pen","UserID":2}`} {
+		serve(gateway, "POST", "/api/v1/products", product)
+	}
+
+	rec := serve(gateway, "GET", "/api/v1/users/2/products", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"Name":"pen"`) || strings.Contains(rec.Body.String(), `"book"`) {
+		t.Errorf("GET /api/v1/users/2/products = %d %s", rec.Code, rec.Body)
+	}
+	if rec := serve(gateway, "GET", "/api/v1/users/3/products", ""); rec.Body.String() != "[]\n" {
+		t.Errorf("products of a user without products = %q, want []", rec.Body)
+	}
+}
+
+// staticAuthenticator сопоставляет ключи клиентам.
+type staticAuthenticator map[string]auth.Principal
+
+func (a staticAuthenticator) Authenticate(_ context.Context, token string) (auth.Principal, error) {
+	principal, ok := a[token]
+	if !ok {
+		return auth.Principal{}, auth.ErrUnauthenticated
+	}
+	return principal, nil
+}
+
+// countingLimiter считает обращения к лимитеру.
+type countingLimiter struct {
+	ratelimit.Limiter
+	calls atomic.Int32
+}
+
+func (l *countingLimiter) Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	l.calls.Add(1)
+	return l.Limiter.Allow(ctx, key, limit)
+}
+
+// TestGatewayChargesOnce проверяет, что REST-запрос через шлюз проходит
+// аутентификацию и ограничение частоты один раз, на HTTP-слое, а gRPC-сервер
+// получает уже проверенного клиента.
+func TestGatewayChargesOnce(t *testing.T) {
+	authn := staticAuthenticator{"pk_writer": {UserID: 7, APIKeyID: 3, Scopes: []string{auth.ScopeRead, auth.ScopeWrite}}}
+	policy := auth.Policy{Required: true, GatewayToken: testGatewayToken}
+	limiter := &countingLimiter{Limiter: ratelimit.NewMemoryLimiter(clock.Real{})}
+	limits := ratelimit.Policy{Default: ratelimit.Limit{Algorithm: ratelimit.TokenBucket, Requests: 10, Window: time.Minute}}
+
+	var principals []auth.Principal
+	capture := func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		principal, _ := auth.FromContext(ctx)
+		principals = append(principals, principal)
+		return handler(ctx, req)
+	}
+	gateway := newGateway(t, grpc.ChainUnaryInterceptor(
+		auth.UnaryServerInterceptor(authn, policy),
+		ratelimit.UnaryServerInterceptor(limiter, limits),
+		capture,
+	))
+	handler := auth.HTTPMiddleware(authn, policy,
+		ratelimit.HTTPMiddleware(limiter, limits, gateway, routes.PatternOf(gateway)))
+
+	steps := []struct {
+		method, path, body string
+		wantStatus         int
+		// wantRemaining — остаток токенов маршрута после запроса
+		wantRemaining int
+	}{
+		{method: "POST", path: "/api/v1/users", body: `{"Name":"Ann","Email":"ann@example.com"}`, wantStatus: http.StatusCreated, wantRemaining: 9},
+		{method: "GET", path: "/api/v1/users/1", wantStatus: http.StatusOK, wantRemaining: 9},
+		{method: "GET", path: "/api/v1/users/1", wantStatus: http.StatusOK, wantRemaining: 8},
+		{method: "GET", path: "/api/v1/users/1", wantStatus: http.StatusOK, wantRemaining: 7},
+	}
+	for i, step := range steps {
+		r := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		r.Header.Set("Authorization", "ApiKey pk_writer")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+
+		name := step.method + " " + step.path
+		if rec.Code != step.wantStatus {
+			t.Fatalf("%s: status %d, want %d (%q)", name, rec.Code, step.wantStatus, rec.Body)
+		}
+		if got := limiter.calls.Load(); got != int32(i+1) {
+			t.Errorf("%s: limiter called %d times in total, want %d", name, got, i+1)
+		}
+		// Каждый запрос забирает из ведра ровно один токен
+		if got, want := rec.Header().Get("RateLimit-Remaining"), strconv.Itoa(step.wantRemaining); got != want {
+			t.Errorf("%s: RateLimit-Remaining = %s, want %s", name, got, want)
+		}
+		if got := principals[len(principals)-1]; got.UserID != 7 || got.APIKeyID != 3 {
+			t.Errorf("%s: gRPC principal = %+v, want user 7 with key 3", name, got)
+		}
+	}
+}
+
+func serve(h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	var r *http.Request
+	if body == "" {
+		r = httptest.NewRequest(method, path, nil)
+	} else {
+		r = httptest.NewRequest(method, path, strings.NewReader(body))
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
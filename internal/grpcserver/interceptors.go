@@ -0,0 +1,69 @@
+package grpcserver
+
+import (
+	"Projectapirest/internal/logger"
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RecoveryUnaryInterceptor превращает панику обработчика в ошибку Internal,
+// чтобы она не завершала процесс. Стек пишется в журнал запроса, клиенту
+// детали не передаются.
+func RecoveryUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recovered(ctx, info.FullMethod, p)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// RecoveryStreamInterceptor делает то же для потоковых вызовов.
+func RecoveryStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recovered(ss.Context(), info.FullMethod, p)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func recovered(ctx context.Context, method string, p interface{}) error {
+	logger.FromContext(ctx).ErrorContext(ctx, "panic in gRPC handler",
+		"method", method,
+		"panic", fmt.Sprint(p),
+		"stack", string(debug.Stack()),
+	)
+	return status.Error(codes.Internal, "internal server error")
+}
+
+// DeadlineUnaryInterceptor задает дедлайн defaultTimeout вызовам, пришедшим
+// без него, и сокращает до maxTimeout слишком длинные дедлайны клиента.
+// Нулевые значения отключают соответствующее правило. Потоковые вызовы
+// (подписки на изменения) живут долго, и их срок определяет клиент.
+func DeadlineUnaryInterceptor(defaultTimeout, maxTimeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		deadline, ok := ctx.Deadline()
+		var cancel context.CancelFunc
+		switch {
+		case !ok && defaultTimeout > 0:
+			ctx, cancel = context.WithTimeout(ctx, defaultTimeout)
+		case ok && maxTimeout > 0 && time.Until(deadline) > maxTimeout:
+			ctx, cancel = context.WithTimeout(ctx, maxTimeout)
+		}
+		if cancel != nil {
+			defer cancel()
+		}
+		return handler(ctx, req)
+	}
+}
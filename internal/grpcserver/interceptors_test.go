@@ -0,0 +1,70 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var unaryInfo = &grpc.UnaryServerInfo{FullMethod: "/users.UserService/GetUser"}
+
+func TestRecoveryUnaryInterceptor(t *testing.T) {
+	_, err := RecoveryUnaryInterceptor()(context.Background(), nil, unaryInfo, func(context.Context, interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("error = %v, want Internal", err)
+	}
+	if s, _ := status.FromError(err); s.Message() != "internal server error" {
+		t.Errorf("message = %q leaks panic details", s.Message())
+	}
+}
+
+func TestRecoveryStreamInterceptor(t *testing.T) {
+	err := RecoveryStreamInterceptor()(nil, fakeStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/users.ProductService/WatchProducts"},
+		func(interface{}, grpc.ServerStream) error { panic("boom") })
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("error = %v, want Internal", err)
+	}
+}
+
+func TestDeadlineUnaryInterceptor(t *testing.T) {
+	interceptor := DeadlineUnaryInterceptor(time.Second, time.Minute)
+	remaining := func(ctx context.Context) time.Duration {
+		var left time.Duration
+		_, _ = interceptor(ctx, nil, unaryInfo, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				t.Fatal("handler has no deadline")
+			}
+			left = time.Until(deadline)
+			return nil, nil
+		})
+		return left
+	}
+
+	if left := remaining(context.Background()); left > time.Second || left <= 0 {
+		t.Errorf("default deadline = %v, want up to 1s", left)
+	}
+	long, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	if left := remaining(long); left > time.Minute {
+		t.Errorf("clamped deadline = %v, want up to 1m", left)
+	}
+	short, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if left := remaining(short); left <= time.Second || left > 10*time.Second {
+		t.Errorf("client deadline = %v, want kept at 10s", left)
+	}
+}
+
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s fakeStream) Context() context.Context { return s.ctx }
@@ -0,0 +1,66 @@
+// Package grpcserver содержит общие части gRPC-сервера приложения: параметры
+// соединений, служебные интерсепторы и корректную остановку. Прикладные
+// интерсепторы (журнал, метрики, аутентификация и т. д.) живут в своих пакетах.
+package grpcserver
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+// Options описывает параметры соединений сервера.
+type Options struct {
+	MaxRecvMsgSize int
+	MaxSendMsgSize int
+	// KeepaliveTime и KeepaliveTimeout задают проверку простаивающих соединений пингом.
+	KeepaliveTime    time.Duration
+	KeepaliveTimeout time.Duration
+	// KeepaliveMinTime — минимальный допустимый интервал пингов клиента.
+	KeepaliveMinTime time.Duration
+	// MaxConnectionAge ограничивает время жизни соединения; 0 — без ограничения.
+	MaxConnectionAge time.Duration
+}
+
+// ServerOptions переводит Options в параметры grpc.NewServer.
+func ServerOptions(o Options) []grpc.ServerOption {
+	params := keepalive.ServerParameters{
+		Time:    o.KeepaliveTime,
+		Timeout: o.KeepaliveTimeout,
+	}
+	if o.MaxConnectionAge > 0 {
+		params.MaxConnectionAge = o.MaxConnectionAge
+		// Текущим вызовам дается время завершиться до закрытия соединения
+		params.MaxConnectionAgeGrace = o.KeepaliveTimeout
+	}
+	return []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(o.MaxRecvMsgSize),
+		grpc.MaxSendMsgSize(o.MaxSendMsgSize),
+		grpc.KeepaliveParams(params),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime: o.KeepaliveMinTime,
+			// Клиенты с долгими потоками могут держать соединение пингами
+			PermitWithoutStream: true,
+		}),
+	}
+}
+
+// GracefulStop останавливает сервер, дожидаясь завершения текущих вызовов.
+// Если ctx истекает раньше, оставшиеся вызовы и соединения обрываются.
+func GracefulStop(ctx context.Context, srv *grpc.Server) error {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		srv.Stop()
+		<-done
+		return ctx.Err()
+	}
+}
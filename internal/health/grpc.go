@@ -0,0 +1,16 @@
+package health
+
+import (
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// UpdateGRPC переводит отчет в статус стандартного сервиса grpc.health.v1
+// для всего сервера (пустое имя сервиса). Состояние degraded считается SERVING.
+func UpdateGRPC(server *health.Server, report Report) {
+	status := healthpb.HealthCheckResponse_SERVING
+	if report.Status == StatusDown {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	server.SetServingStatus("", status)
+}
@@ -0,0 +1,126 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Status описывает состояние зависимости или приложения в целом.
+type Status string
+
+const (
+	StatusUp       Status = "up"
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+// Check описывает проверку одной зависимости.
+type Check struct {
+	Name string
+	// Critical указывает, что без зависимости приложение не может обслуживать запросы.
+	// Сбой некритичной зависимости переводит приложение в состояние degraded.
+	Critical bool
+	Ping     func(ctx context.Context) error
+}
+
+// CheckResult содержит результат проверки одной зависимости.
+type CheckResult struct {
+	Status   Status `json:"status"`
+	Critical bool   `json:"critical,omitempty"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Report содержит общее состояние и результаты проверок по зависимостям.
+type Report struct {
+	Status Status                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Public возвращает отчет только с состоянием и длительностью проверок.
+// Тексты ошибок могут раскрывать адреса и учетные данные зависимостей,
+// поэтому полный отчет отдается лишь на служебном сервере.
+func (r Report) Public() Report {
+	checks := make(map[string]CheckResult, len(r.Checks))
+	for name, result := range r.Checks {
+		checks[name] = CheckResult{Status: result.Status, Duration: result.Duration}
+	}
+	return Report{Status: r.Status, Checks: checks}
+}
+
+// Checker выполняет проверки зависимостей параллельно, каждую со своим таймаутом.
+type Checker struct {
+	timeout time.Duration
+	checks  []Check
+}
+
+// NewChecker создает проверку готовности с таймаутом на каждую зависимость.
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{
+		timeout: timeout,
+		checks:  checks,
+	}
+}
+
+// Check опрашивает все зависимости и сводит результаты в отчет.
+func (c *Checker) Check(ctx context.Context) Report {
+	results := make([]CheckResult, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]CheckResult, len(c.checks))}
+	for i, check := range c.checks {
+		result := results[i]
+		report.Checks[check.Name] = result
+		if result.Status != StatusDown {
+			continue
+		}
+		if check.Critical {
+			report.Status = StatusDown
+		} else if report.Status == StatusUp {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Ping(ctx)
+	result := CheckResult{
+		Status:   StatusUp,
+		Critical: check.Critical,
+		Duration: time.Since(start).String(),
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Watch периодически выполняет проверки и передает отчет в onReport,
+// пока не будет отменен ctx. Первая проверка выполняется сразу.
+func (c *Checker) Watch(ctx context.Context, interval time.Duration, onReport func(Report)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		onReport(c.Check(ctx))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
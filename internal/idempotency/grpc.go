@@ -0,0 +1,112 @@
+package idempotency
+
+import (
+	"Projectapirest/internal/logger"
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// MetadataKey — ключ метаданных gRPC с ключом идемпотентности.
+const MetadataKey = "idempotency-key"
+
+// UnaryServerInterceptor делает для gRPC то же, что HTTPMiddleware для HTTP.
+// Ключи действуют в пространстве клиента, как в HTTP. Ответ сохраняется
+// как google.protobuf.Any и восстанавливается по зарегистрированному типу. Вызовы, завершившиеся ошибкой, не сохраняются.
+func UnaryServerInterceptor(store *Store) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		key := idempotencyKey(ctx)
+		msg, ok := req.(proto.Message)
+		if key == "" || !ok {
+			return handler(ctx, req)
+		}
+		if !validKey(key) {
+			return nil, status.Error(codes.InvalidArgument, "invalid idempotency-key")
+		}
+
+		var remoteAddr string
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			remoteAddr = p.Addr.String()
+		}
+		key = namespace(ctx, remoteAddr) + key
+
+		payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if err != nil {
+			return handler(ctx, req)
+		}
+		fingerprint := Fingerprint([]byte(info.FullMethod), payload)
+		record, err := store.Begin(ctx, key, fingerprint)
+		switch {
+		case errors.Is(err, ErrMismatch):
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		case errors.Is(err, ErrInFlight):
+			return nil, status.Error(codes.Aborted, err.Error())
+		case err != nil:
+			logger.FromContext(ctx).WarnContext(ctx, "idempotency store unavailable", "error", err)
+			return handler(ctx, req)
+		case record != nil:
+			return replayMessage(record)
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			if releaseErr := store.Release(ctx, key); releaseErr != nil {
+				logger.FromContext(ctx).WarnContext(ctx, "failed to release idempotency key", "error", releaseErr)
+			}
+			return resp, err
+		}
+		if err := complete(ctx, store, key, fingerprint, resp); err != nil {
+			logger.FromContext(ctx).WarnContext(ctx, "failed to store idempotency record", "error", err)
+		}
+		return resp, nil
+	}
+}
+
+// complete сохраняет ответ gRPC-вызова.
+func complete(ctx context.Context, store *Store, key, fingerprint string, resp interface{}) error {
+	msg, ok := resp.(proto.Message)
+	if !ok {
+		return store.Release(ctx, key)
+	}
+	packed, err := anypb.New(msg)
+	if err != nil {
+		return err
+	}
+	body, err := proto.Marshal(packed)
+	if err != nil {
+		return err
+	}
+	return store.Complete(ctx, key, Record{Fingerprint: fingerprint, Body: body})
+}
+
+// replayMessage восстанавливает сохраненный ответ.
+func replayMessage(record *Record) (interface{}, error) {
+	var packed anypb.Any
+	if err := proto.Unmarshal(record.Body, &packed); err != nil {
+		return nil, status.Error(codes.Internal, "corrupted idempotency record")
+	}
+	msg, err := packed.UnmarshalNew()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "corrupted idempotency record")
+	}
+	return msg, nil
+}
+
+// idempotencyKey возвращает ключ из входящих метаданных.
+func idempotencyKey(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(MetadataKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,140 @@
+package idempotency
+
+import (
+	"Projectapirest/internal/logger"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Header — заголовок, в котором клиент передает ключ идемпотентности.
+const Header = "Idempotency-Key"
+
+// ReplayedHeader отмечает ответ, повторенный из сохраненной записи.
+const ReplayedHeader = "Idempotent-Replayed"
+
+// maxBodySize ограничивает тело запроса, по которому считается отпечаток.
+const maxBodySize = 1 << 20
+
+// HTTPMiddleware обрабатывает POST-запросы с заголовком Idempotency-Key.
+// Ключи действуют в пространстве клиента (см. namespace). Повтор с тем же
+// телом получает сохраненный ответ, с другим телом или строкой запроса, как
+// и во время выполнения первого запроса, — 409. Ответы 5xx не сохраняются, чтобы клиент
+// мог повторить запрос, а ответы с Cache-Control: no-store — чтобы секреты
+// (например, выпущенный API-ключ) не попадали в хранилище. При недоступном
+// хранилище запрос выполняется без защиты от повторов.
+func HTTPMiddleware(store *Store, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !validKey(key) {
+			http.Error(w, "Invalid Idempotency-Key", http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxBodySize {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := r.Context()
+		key = namespace(ctx, r.RemoteAddr) + key
+		fingerprint := Fingerprint([]byte(r.Method), []byte(r.URL.Path), []byte(r.URL.RawQuery), body)
+		record, err := store.Begin(ctx, key, fingerprint)
+		switch {
+		case errors.Is(err, ErrMismatch), errors.Is(err, ErrInFlight):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			logger.FromContext(ctx).WarnContext(ctx, "idempotency store unavailable", "error", err)
+			next.ServeHTTP(w, r)
+			return
+		case record != nil:
+			replay(w, record)
+			return
+		}
+
+		recorder := &responseRecorder{header: make(http.Header), status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		if recorder.status >= http.StatusInternalServerError || noStore(recorder.header) {
+			err = store.Release(ctx, key)
+		} else {
+			err = store.Complete(ctx, key, Record{
+				Fingerprint: fingerprint,
+				Status:      recorder.status,
+				Header:      recorder.header,
+				Body:        recorder.body.Bytes(),
+			})
+		}
+		if err != nil {
+			logger.FromContext(ctx).WarnContext(ctx, "failed to store idempotency record", "error", err)
+		}
+		recorder.flush(w)
+	})
+}
+
+// replay отдает сохраненный ответ.
+func replay(w http.ResponseWriter, record *Record) {
+	for name, values := range record.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(record.Status)
+	w.Write(record.Body)
+}
+
+// noStore сообщает, запретил ли обработчик сохранять ответ.
+func noStore(header http.Header) bool {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+			return true
+		}
+	}
+	return false
+}
+
+// responseRecorder буферизует ответ обработчика, чтобы сохранить его
+// до отправки клиенту.
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.body.Write(b)
+}
+
+// flush отправляет буферизованный ответ клиенту.
+func (r *responseRecorder) flush(w http.ResponseWriter) {
+	for name, values := range r.header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(r.status)
+	w.Write(r.body.Bytes())
+}
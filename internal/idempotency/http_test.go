@@ -0,0 +1,158 @@
+package idempotency
+
+import (
+	"Projectapirest/internal/auth"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// request — запрос клиента к /api/v1/users.
+type request struct {
+	query      string
+	body       string
+	remoteAddr string
+	userID     int
+}
+
+func (q request) build() *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/users?"+q.query, strings.NewReader(q.body))
+	r.Header.Set(Header, "key-1")
+	if q.remoteAddr != "" {
+		r.RemoteAddr = q.remoteAddr
+	}
+	if q.userID != 0 {
+		r = r.WithContext(auth.NewContext(r.Context(), auth.Principal{UserID: q.userID}))
+	}
+	return r
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	first := request{body: `{"name":"a"}`, remoteAddr: "10.0.0.1:1000", userID: 1}
+
+	tests := []struct {
+		name string
+		// status и header задают ответ обработчика
+		status     int
+		header     http.Header
+		second     request
+		wantStatus int
+		wantCalls  int32
+		wantReplay bool
+	}{
+		{
+			name:       "replay",
+			status:     http.StatusCreated,
+			second:     first,
+			wantStatus: http.StatusCreated,
+			wantCalls:  1,
+			wantReplay: true,
+		},
+		{
+			name:       "fingerprint mismatch",
+			status:     http.StatusCreated,
+			second:     request{body: `{"name":"b"}`, remoteAddr: first.remoteAddr, userID: 1},
+			wantStatus: http.StatusConflict,
+			wantCalls:  1,
+		},
+		{
+			name:       "query mismatch",
+			status:     http.StatusCreated,
+			second:     request{query: "dry_run=true", body: first.body, remoteAddr: first.remoteAddr, userID: 1},
+			wantStatus: http.StatusConflict,
+			wantCalls:  1,
+		},
+		{
+			name:       "release after server error",
+			status:     http.StatusInternalServerError,
+			second:     first,
+			wantStatus: http.StatusInternalServerError,
+			wantCalls:  2,
+		},
+		{
+			name:       "no-store response is not kept",
+			status:     http.StatusCreated,
+			header:     http.Header{"Cache-Control": {"private, no-store"}},
+			second:     first,
+			wantStatus: http.StatusCreated,
+			wantCalls:  2,
+		},
+		{
+			name:       "other user with same key",
+			status:     http.StatusCreated,
+			second:     request{body: first.body, remoteAddr: first.remoteAddr, userID: 2},
+			wantStatus: http.StatusCreated,
+			wantCalls:  2,
+		},
+		{
+			name:       "anonymous client with same key",
+			status:     http.StatusCreated,
+			second:     request{body: first.body, remoteAddr: first.remoteAddr},
+			wantStatus: http.StatusCreated,
+			wantCalls:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			handler := HTTPMiddleware(newTestStore(), http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				calls.Add(1)
+				for name, values := range tt.header {
+					w.Header()[name] = values
+				}
+				w.Header().Set("Location", "/api/v1/users/1")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(`{"id":1}`))
+			}))
+
+			handler.ServeHTTP(httptest.NewRecorder(), first.build())
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, tt.second.build())
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("handler calls = %d, want %d", got, tt.wantCalls)
+			}
+			if replayed := w.Header().Get(ReplayedHeader) == "true"; replayed != tt.wantReplay {
+				t.Errorf("replayed = %v, want %v", replayed, tt.wantReplay)
+			}
+			if tt.wantReplay && (w.Header().Get("Location") != "/api/v1/users/1" || w.Body.String() != `{"id":1}`) {
+				t.Errorf("replayed response = %v %q", w.Header(), w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHTTPMiddlewareInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := HTTPMiddleware(newTestStore(), http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+	q := request{body: `{"name":"a"}`, userID: 1}
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, q.build())
+		done <- w.Code
+	}()
+	<-started
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, q.build())
+	if w.Code != http.StatusConflict {
+		t.Errorf("concurrent request status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	close(release)
+	if code := <-done; code != http.StatusCreated {
+		t.Errorf("first request status = %d, want %d", code, http.StatusCreated)
+	}
+}
@@ -0,0 +1,147 @@
+// Package idempotency реализует обработку заголовка Idempotency-Key:
+// повторный запрос с тем же ключом получает сохраненный ответ вместо
+// повторного выполнения.
+package idempotency
+
+import (
+	"Projectapirest/internal/auth"
+	"Projectapirest/internal/cache"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrMismatch возвращается, когда ключ уже использован для другого запроса.
+	ErrMismatch = errors.New("idempotency key is already used for a different request")
+	// ErrInFlight возвращается, когда запрос с этим ключом еще выполняется.
+	ErrInFlight = errors.New("request with this idempotency key is in progress")
+)
+
+// keyPrefix задает пространство ключей идемпотентности в кеше.
+const keyPrefix = "idempotency:"
+
+// lockTTL ограничивает время резервирования ключа: если обработчик завис или
+// процесс упал, ключ освободится и клиент сможет повторить запрос.
+const lockTTL = time.Minute
+
+// maxKeyLength ограничивает длину ключа, принятого от клиента.
+const maxKeyLength = 255
+
+// Backend — кеш, умеющий атомарно резервировать ключ. Для нескольких реплик
+// нужен общий backend (Redis), иначе повтор на другую реплику не будет распознан.
+type Backend interface {
+	cache.Cache
+	cache.Adder
+}
+
+// Record хранит отпечаток запроса и, после выполнения, ответ на него.
+type Record struct {
+	Fingerprint string      `json:"fingerprint"`
+	Done        bool        `json:"done"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// Store хранит записи идемпотентности в кеше.
+type Store struct {
+	backend Backend
+	ttl     time.Duration
+}
+
+// NewStore создает хранилище; ответы хранятся ttl.
+func NewStore(backend Backend, ttl time.Duration) *Store {
+	return &Store{backend: backend, ttl: ttl}
+}
+
+// Begin резервирует ключ за запросом с отпечатком fingerprint. Если ключ
+// свободен, возвращает nil-запись и вызывающий выполняет запрос. Если запрос
+// уже выполнен, возвращает сохраненную запись для повтора ответа.
+func (s *Store) Begin(ctx context.Context, key, fingerprint string) (*Record, error) {
+	pending, err := json.Marshal(Record{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, err
+	}
+	// Вторая попытка нужна, если запись истекла между Add и Get
+	for attempt := 0; attempt < 2; attempt++ {
+		added, err := s.backend.Add(ctx, keyPrefix+key, pending, lockTTL)
+		if err != nil {
+			return nil, err
+		}
+		if added {
+			return nil, nil
+		}
+
+		data, err := s.backend.Get(ctx, keyPrefix+key)
+		if errors.Is(err, cache.ErrMiss) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var record Record
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("idempotency: decode record: %w", err)
+		}
+		switch {
+		case record.Fingerprint != fingerprint:
+			return nil, ErrMismatch
+		case !record.Done:
+			return nil, ErrInFlight
+		}
+		return &record, nil
+	}
+	return nil, ErrInFlight
+}
+
+// Complete сохраняет результат выполненного запроса.
+func (s *Store) Complete(ctx context.Context, key string, record Record) error {
+	record.Done = true
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.backend.Set(ctx, keyPrefix+key, data, s.ttl)
+}
+
+// Release снимает резервирование, чтобы запрос можно было повторить,
+// например после внутренней ошибки сервера.
+func (s *Store) Release(ctx context.Context, key string) error {
+	return s.backend.Delete(ctx, keyPrefix+key)
+}
+
+// Fingerprint вычисляет отпечаток запроса из его частей.
+func Fingerprint(parts ...[]byte) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write(part)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// namespace возвращает пространство ключей клиента, чтобы одинаковые ключи
+// разных клиентов не пересекались: пользователь для аутентифицированных
+// запросов, иначе IP-адрес.
+func namespace(ctx context.Context, remoteAddr string) string {
+	if principal, ok := auth.FromContext(ctx); ok {
+		return fmt.Sprintf("user:%d:", principal.UserID)
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return "ip:" + host + ":"
+}
+
+// validKey проверяет ключ, принятый от клиента.
+func validKey(key string) bool {
+	return key != "" && len(key) <= maxKeyLength
+}
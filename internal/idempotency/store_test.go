@@ -0,0 +1,118 @@
+package idempotency
+
+import (
+	"Projectapirest/internal/cache"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestStore() *Store {
+	return NewStore(cache.NewInMemoryCache(), time.Hour)
+}
+
+func TestStoreBegin(t *testing.T) {
+	ctx := context.Background()
+	done := Record{Fingerprint: "a", Status: http.StatusCreated, Body: []byte("ok")}
+
+	tests := []struct {
+		name string
+		// prepare приводит ключ "k" в нужное состояние
+		prepare     func(s *Store) error
+		fingerprint string
+		wantRecord  bool
+		wantErr     error
+	}{
+		{
+			name:        "free key",
+			prepare:     func(*Store) error { return nil },
+			fingerprint: "a",
+		},
+		{
+			name:        "in flight",
+			prepare:     func(s *Store) error { _, err := s.Begin(ctx, "k", "a"); return err },
+			fingerprint: "a",
+			wantErr:     ErrInFlight,
+		},
+		{
+			name:        "in flight with other request",
+			prepare:     func(s *Store) error { _, err := s.Begin(ctx, "k", "a"); return err },
+			fingerprint: "b",
+			wantErr:     ErrMismatch,
+		},
+		{
+			name:        "completed",
+			prepare:     func(s *Store) error { return s.Complete(ctx, "k", done) },
+			fingerprint: "a",
+			wantRecord:  true,
+		},
+		{
+			name:        "completed with other request",
+			prepare:     func(s *Store) error { return s.Complete(ctx, "k", done) },
+			fingerprint: "b",
+			wantErr:     ErrMismatch,
+		},
+		{
+			name: "released",
+			prepare: func(s *Store) error {
+				if _, err := s.Begin(ctx, "k", "a"); err != nil {
+					return err
+				}
+				return s.Release(ctx, "k")
+			},
+			fingerprint: "b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStore()
+			if err := tt.prepare(s); err != nil {
+				t.Fatal(err)
+			}
+			record, err := s.Begin(ctx, "k", tt.fingerprint)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Begin error = %v, want %v", err, tt.wantErr)
+			}
+			if (record != nil) != tt.wantRecord {
+				t.Fatalf("Begin record = %+v, want record %v", record, tt.wantRecord)
+			}
+			if record != nil && (!record.Done || record.Status != done.Status || string(record.Body) != "ok") {
+				t.Errorf("record = %+v, want completed %+v", record, done)
+			}
+		})
+	}
+}
+
+func TestStoreBeginConcurrent(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore()
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = s.Begin(ctx, "k", "a")
+		}(i)
+	}
+	wg.Wait()
+
+	var owners int
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			owners++
+		case !errors.Is(err, ErrInFlight):
+			t.Errorf("Begin error = %v, want nil or ErrInFlight", err)
+		}
+	}
+	if owners != 1 {
+		t.Errorf("%d requests reserved the key, want 1", owners)
+	}
+}
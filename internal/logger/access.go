@@ -0,0 +1,153 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// maxRequestIDLength ограничивает длину идентификатора, принятого от клиента.
+const maxRequestIDLength = 64
+
+// HTTPMiddleware создает логгер запроса с request_id и route, кладет его
+// в контекст и по завершении пишет access-лог. Идентификатор запроса берется
+// из заголовка X-Request-ID или генерируется и возвращается клиенту.
+func HTTPMiddleware(base *slog.Logger, next http.Handler, routeOf func(r *http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		requestID := requestIDOrNew(r.Header.Get(RequestIDHeader))
+		w.Header().Set(RequestIDHeader, requestID)
+
+		route := routeOf(r)
+		ctx := WithRequestID(r.Context(), requestID)
+		ctx = NewContext(ctx, base.With(
+			slog.String("request_id", requestID),
+			slog.String("route", route),
+		))
+
+		recorder := &accessRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		level := slog.LevelInfo
+		if recorder.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		FromContext(ctx).LogAttrs(ctx, level, "http request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", recorder.status),
+			slog.Int("bytes", recorder.bytes),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("remote_addr", r.RemoteAddr),
+		)
+	})
+}
+
+// accessRecorder запоминает код ответа и размер тела.
+type accessRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (r *accessRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *accessRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap позволяет http.ResponseController добраться до исходного ResponseWriter.
+func (r *accessRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// UnaryServerInterceptor делает для gRPC то же, что HTTPMiddleware для HTTP.
+// Идентификатор запроса передается в метаданных x-request-id.
+func UnaryServerInterceptor(base *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		ctx = newGRPCContext(ctx, base, info.FullMethod)
+		resp, err := handler(ctx, req)
+		logGRPC(ctx, info.FullMethod, err, start)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor пишет access-лог для потоковых вызовов.
+func StreamServerInterceptor(base *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx := newGRPCContext(ss.Context(), base, info.FullMethod)
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		logGRPC(ctx, info.FullMethod, err, start)
+		return err
+	}
+}
+
+// newGRPCContext кладет в контекст логгер вызова. Идентификатор запроса
+// берется из RequestID-интерсептора, а без него — из метаданных.
+func newGRPCContext(ctx context.Context, base *slog.Logger, method string) context.Context {
+	if RequestID(ctx) == "" {
+		ctx = withGRPCRequestID(ctx)
+	}
+	return NewContext(ctx, base.With(
+		slog.String("request_id", RequestID(ctx)),
+		slog.String("route", method),
+	))
+}
+
+func logGRPC(ctx context.Context, method string, err error, start time.Time) {
+	code := status.Code(err)
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("duration", time.Since(start)),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		attrs = append(attrs, slog.String("remote_addr", p.Addr.String()))
+	}
+	level := slog.LevelInfo
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		level = slog.LevelWarn
+	}
+	FromContext(ctx).LogAttrs(ctx, level, "grpc request", attrs...)
+}
+
+// contextStream подменяет контекст потока на контекст с логгером запроса.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// requestIDOrNew возвращает идентификатор клиента, если он допустим, иначе новый.
+func requestIDOrNew(id string) string {
+	if id == "" || len(id) > maxRequestIDLength {
+		return NewRequestID()
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return NewRequestID()
+		}
+	}
+	return id
+}
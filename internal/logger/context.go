@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"sync"
+)
+
+type scopeKey struct{}
+
+// scope хранит логгер запроса. Логгер можно дополнять полями из вложенных
+// обработчиков (например, user_id после аутентификации), и эти поля увидит access-лог.
+type scope struct {
+	mu     sync.RWMutex
+	logger *slog.Logger
+}
+
+// NewContext возвращает контекст с логгером запроса.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, scopeKey{}, &scope{logger: logger})
+}
+
+// FromContext возвращает логгер запроса или slog.Default, если его нет.
+func FromContext(ctx context.Context) *slog.Logger {
+	return FromContextOr(ctx, slog.Default())
+}
+
+// FromContextOr возвращает логгер запроса или fallback, если его нет.
+func FromContextOr(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if s, ok := ctx.Value(scopeKey{}).(*scope); ok {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return s.logger
+	}
+	return fallback
+}
+
+// AddFields добавляет поля к логгеру запроса, сохраненному в контексте.
+// Без логгера в контексте вызов ничего не делает.
+func AddFields(ctx context.Context, args ...any) {
+	if s, ok := ctx.Value(scopeKey{}).(*scope); ok {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.logger = s.logger.With(args...)
+	}
+}
+
+// RequestIDHeader — заголовок и ключ метаданных gRPC с идентификатором запроса.
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID сохраняет идентификатор запроса в контексте.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID возвращает идентификатор запроса из контекста.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID генерирует случайный идентификатор запроса.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// New создает структурированный логгер. level — debug, info, warn или error;
+// format — json или text. Значения паролей и email-адреса в логах маскируются.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+	opts := &slog.HandlerOptions{
+		Level:       lvl,
+		ReplaceAttr: redact,
+	}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q", format)
+	}
+	return slog.New(traceHandler{handler}), nil
+}
+
+// secretKeys перечисляет атрибуты, значения которых не попадают в лог.
+var secretKeys = map[string]bool{
+	"password":      true,
+	"passwd":        true,
+	"secret":        true,
+	"token":         true,
+	"api_key":       true,
+	"authorization": true,
+}
+
+// emailPattern находит email-адреса внутри произвольных строк.
+var emailPattern = regexp.MustCompile(`([A-Za-z0-9._%+\-])[A-Za-z0-9._%+\-]*@([A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+
+// redact скрывает секреты по имени атрибута и маскирует email-адреса в строках:
+// "john@example.com" превращается в "j***@example.com".
+func redact(_ []string, attr slog.Attr) slog.Attr {
+	if secretKeys[strings.ToLower(attr.Key)] {
+		return slog.String(attr.Key, "[REDACTED]")
+	}
+	if attr.Value.Kind() == slog.KindString {
+		if value := attr.Value.String(); strings.Contains(value, "@") {
+			return slog.String(attr.Key, MaskEmails(value))
+		}
+	}
+	return attr
+}
+
+// MaskEmails маскирует все email-адреса в строке.
+func MaskEmails(s string) string {
+	return emailPattern.ReplaceAllString(s, "$1***@$2")
+}
+
+// traceHandler добавляет к записям trace_id и span_id активного span OpenTelemetry.
+type traceHandler struct {
+	slog.Handler
+}
+
+func (h traceHandler) Handle(ctx context.Context, record slog.Record) error {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h traceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return traceHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h traceHandler) WithGroup(name string) slog.Handler {
+	return traceHandler{h.Handler.WithGroup(name)}
+}
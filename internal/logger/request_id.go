@@ -0,0 +1,38 @@
+package logger
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDUnaryServerInterceptor берет идентификатор запроса из метаданных
+// x-request-id или создает новый, возвращает его клиенту в заголовках ответа
+// и добавляет в исходящие метаданные, чтобы вложенные gRPC-вызовы несли
+// тот же идентификатор.
+func RequestIDUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withGRPCRequestID(ctx), req)
+	}
+}
+
+// RequestIDStreamServerInterceptor делает то же для потоковых вызовов.
+func RequestIDStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &contextStream{ServerStream: ss, ctx: withGRPCRequestID(ss.Context())})
+	}
+}
+
+// withGRPCRequestID сохраняет идентификатор запроса в контексте вызова.
+func withGRPCRequestID(ctx context.Context) context.Context {
+	var requestID string
+	if values := metadata.ValueFromIncomingContext(ctx, RequestIDHeader); len(values) > 0 {
+		requestID = values[0]
+	}
+	requestID = requestIDOrNew(requestID)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, requestID))
+
+	ctx = metadata.AppendToOutgoingContext(ctx, RequestIDHeader, requestID)
+	return WithRequestID(ctx, requestID)
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"Projectapirest/internal/cache"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CacheMetrics собирает метрики операций с кешем по уровням и пространствам ключей.
+// Реализует cache.Observer.
+type CacheMetrics struct {
+	operations *prometheus.CounterVec
+	latency    *prometheus.HistogramVec
+	payload    *prometheus.HistogramVec
+}
+
+// NewCacheMetrics создает и регистрирует метрики кеша.
+func NewCacheMetrics(reg prometheus.Registerer) *CacheMetrics {
+	m := &CacheMetrics{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_operations_total",
+			Help: "Количество операций с кешем по уровню, операции, пространству ключей и результату.",
+		}, []string{"layer", "op", "prefix", "result"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "cache_operation_duration_seconds",
+			Help:    "Длительность операций с кешем.",
+			Buckets: []float64{.0001, .0005, .001, .0025, .005, .01, .025, .05, .1, .25},
+		}, []string{"layer", "op"}),
+		payload: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "cache_payload_bytes",
+			Help:    "Размер значений, прочитанных из кеша и записанных в него.",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+		}, []string{"layer", "op", "prefix"}),
+	}
+	reg.MustRegister(m.operations, m.latency, m.payload)
+	return m
+}
+
+// ObserveCacheOp учитывает одну операцию с кешем.
+func (m *CacheMetrics) ObserveCacheOp(op cache.Op) {
+	m.operations.WithLabelValues(op.Layer, op.Name, op.Prefix, op.Result).Inc()
+	m.latency.WithLabelValues(op.Layer, op.Name).Observe(op.Latency.Seconds())
+	if op.Size > 0 {
+		m.payload.WithLabelValues(op.Layer, op.Name, op.Prefix).Observe(float64(op.Size))
+	}
+}
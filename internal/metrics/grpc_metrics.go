@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCMetrics собирает количество и длительность gRPC-вызовов по методам.
+type GRPCMetrics struct {
+	handled  *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewGRPCMetrics создает и регистрирует метрики gRPC-сервера.
+func NewGRPCMetrics(reg prometheus.Registerer) *GRPCMetrics {
+	m := &GRPCMetrics{
+		handled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_server_handled_total",
+			Help: "Количество завершенных gRPC-вызовов по методу и коду ответа.",
+		}, []string{"method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "grpc_server_handling_seconds",
+			Help:    "Длительность обработки gRPC-вызовов.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+	}
+	reg.MustRegister(m.handled, m.duration)
+	return m
+}
+
+// UnaryServerInterceptor учитывает унарные вызовы.
+func (m *GRPCMetrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.observe(info.FullMethod, err, start)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor учитывает потоковые вызовы целиком.
+func (m *GRPCMetrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		m.observe(info.FullMethod, err, start)
+		return err
+	}
+}
+
+func (m *GRPCMetrics) observe(method string, err error, start time.Time) {
+	code := status.Code(err).String()
+	m.handled.WithLabelValues(method, code).Inc()
+	m.duration.WithLabelValues(method, code).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// unmatchedRoute используется для запросов, не попавших ни в один маршрут,
+// чтобы произвольные пути не раздували число меток.
+const unmatchedRoute = "unmatched"
+
+// HTTPMetrics собирает количество и длительность HTTP-запросов по шаблону маршрута.
+type HTTPMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewHTTPMetrics создает и регистрирует метрики HTTP.
+func NewHTTPMetrics(reg prometheus.Registerer) *HTTPMetrics {
+	m := &HTTPMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Количество HTTP-запросов по шаблону маршрута, методу и статусу.",
+		}, []string{"route", "method", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Длительность обработки HTTP-запросов.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+	}
+	reg.MustRegister(m.requests, m.duration)
+	return m
+}
+
+// Middleware учитывает каждый запрос. routeOf возвращает шаблон маршрута
+// (например, "GET /api/v1/products/{id}") или пустую строку.
+func (m *HTTPMetrics) Middleware(next http.Handler, routeOf func(r *http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		route := routeOf(r)
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(recorder.status)
+		m.requests.WithLabelValues(route, r.Method, status).Inc()
+		m.duration.WithLabelValues(route, r.Method, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// statusRecorder запоминает код ответа обработчика.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap позволяет http.ResponseController добраться до исходного ResponseWriter.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
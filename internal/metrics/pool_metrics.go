@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// RegisterRuntime регистрирует метрики рантайма Go и процесса.
+func RegisterRuntime(reg prometheus.Registerer) {
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// RegisterDBStats регистрирует метрики пула соединений sql.DB
+// (открытые, занятые, ожидания) с меткой db_name.
+func RegisterDBStats(reg prometheus.Registerer, db *sql.DB, name string) {
+	reg.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterRedisPoolStats регистрирует метрики пула соединений Redis.
+func RegisterRedisPoolStats(reg prometheus.Registerer, name string, stats func() *redis.PoolStats) {
+	reg.MustRegister(newRedisPoolCollector(name, stats))
+}
+
+// redisPoolCollector снимает статистику пула go-redis при каждом сборе метрик.
+type redisPoolCollector struct {
+	stats func() *redis.PoolStats
+
+	hits     *prometheus.Desc
+	misses   *prometheus.Desc
+	timeouts *prometheus.Desc
+	total    *prometheus.Desc
+	idle     *prometheus.Desc
+	stale    *prometheus.Desc
+}
+
+func newRedisPoolCollector(name string, stats func() *redis.PoolStats) *redisPoolCollector {
+	labels := prometheus.Labels{"pool": name}
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc("redis_pool_"+metric, help, nil, labels)
+	}
+	return &redisPoolCollector{
+		stats:    stats,
+		hits:     desc("hits_total", "Сколько раз свободное соединение нашлось в пуле."),
+		misses:   desc("misses_total", "Сколько раз свободного соединения в пуле не нашлось."),
+		timeouts: desc("timeouts_total", "Сколько раз истекло ожидание соединения."),
+		total:    desc("connections", "Общее число соединений в пуле."),
+		idle:     desc("idle_connections", "Число простаивающих соединений."),
+		stale:    desc("stale_connections_total", "Число соединений, удаленных из пула как устаревшие."),
+	}
+}
+
+func (c *redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.timeouts
+	ch <- c.total
+	ch <- c.idle
+	ch <- c.stale
+}
+
+func (c *redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(s.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(s.Misses))
+	ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(s.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(s.TotalConns))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.IdleConns))
+	ch <- prometheus.MustNewConstMetric(c.stale, prometheus.CounterValue, float64(s.StaleConns))
+}
@@ -0,0 +1,70 @@
+package migrate
+
+import (
+	"Projectapirest/migrations"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// Migrator применяет встроенные миграции goose к PostgreSQL.
+// Все изменения схемы выполняются под сессионной advisory-блокировкой,
+// поэтому реплики, стартующие одновременно, не мешают друг другу.
+type Migrator struct {
+	provider *goose.Provider
+}
+
+// New создает Migrator для встроенного каталога migrations.
+func New(db *sql.DB) (*Migrator, error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, fmt.Errorf("create migration lock: %w", err)
+	}
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, migrations.FS,
+		goose.WithSessionLocker(locker),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create migration provider: %w", err)
+	}
+	return &Migrator{provider: provider}, nil
+}
+
+// Up применяет все неприменённые миграции.
+func (m *Migrator) Up(ctx context.Context) ([]*goose.MigrationResult, error) {
+	return m.provider.Up(ctx)
+}
+
+// Down откатывает последнюю применённую миграцию.
+func (m *Migrator) Down(ctx context.Context) (*goose.MigrationResult, error) {
+	return m.provider.Down(ctx)
+}
+
+// Redo откатывает и заново применяет последнюю миграцию.
+func (m *Migrator) Redo(ctx context.Context) ([]*goose.MigrationResult, error) {
+	down, err := m.provider.Down(ctx)
+	if err != nil {
+		return nil, err
+	}
+	up, err := m.provider.UpByOne(ctx)
+	if err != nil {
+		return []*goose.MigrationResult{down}, err
+	}
+	return []*goose.MigrationResult{down, up}, nil
+}
+
+// Status возвращает состояние каждой миграции.
+func (m *Migrator) Status(ctx context.Context) ([]*goose.MigrationStatus, error) {
+	return m.provider.Status(ctx)
+}
+
+// Create создает в каталоге dir новый SQL-файл миграции с шаблоном Up/Down.
+func Create(dir, name string) error {
+	if name == "" {
+		return errors.New("migration name is required")
+	}
+	return goose.Create(nil, dir, name, "sql")
+}
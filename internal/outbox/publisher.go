@@ -0,0 +1,50 @@
+// Package outbox доставляет доменные события из outbox во внешние системы.
+// Relay забирает события из хранилища и передает их Publisher; событие
+// отмечается доставленным только после успешной отправки, поэтому доставка
+// выполняется хотя бы один раз и получатели должны отбрасывать повторы по ID.
+package outbox
+
+import (
+	"Projectapirest/internal/entity"
+	"context"
+	"sync"
+)
+
+// Publisher отправляет событие во внешнюю систему. Ошибка означает, что
+// событие будет отправлено повторно.
+type Publisher interface {
+	Publish(ctx context.Context, event entity.DomainEvent) error
+}
+
+// MemoryPublisher хранит последние события в памяти процесса; подходит
+// для разработки и тестов.
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	capacity int
+	events   []entity.DomainEvent
+}
+
+// NewMemoryPublisher создает публикатор, хранящий не больше capacity событий;
+// 0 — без ограничения.
+func NewMemoryPublisher(capacity int) *MemoryPublisher {
+	return &MemoryPublisher{capacity: capacity}
+}
+
+// Publish сохраняет событие, вытесняя самое старое при переполнении.
+func (p *MemoryPublisher) Publish(_ context.Context, event entity.DomainEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	event.Payload = append([]byte(nil), event.Payload...)
+	p.events = append(p.events, event)
+	if p.capacity > 0 && len(p.events) > p.capacity {
+		p.events = append(p.events[:0], p.events[len(p.events)-p.capacity:]...)
+	}
+	return nil
+}
+
+// Events возвращает копию сохраненных событий в порядке отправки.
+func (p *MemoryPublisher) Events() []entity.DomainEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]entity.DomainEvent(nil), p.events...)
+}
@@ -0,0 +1,134 @@
+package outbox
+
+import (
+	"Projectapirest/internal/entity"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisStreamPublisher добавляет события в поток Redis Streams. Потребители
+// читают его через группы потребителей (XREADGROUP), а RedisStreamReader —
+// целиком в каждом экземпляре сервиса.
+type RedisStreamPublisher struct {
+	client redis.Cmdable
+	stream string
+	maxLen int64
+}
+
+// NewRedisStreamPublisher создает публикатор в поток stream. maxLen
+// приблизительно ограничивает длину потока; 0 — без ограничения.
+func NewRedisStreamPublisher(client redis.Cmdable, stream string, maxLen int64) *RedisStreamPublisher {
+	return &RedisStreamPublisher{client: client, stream: stream, maxLen: maxLen}
+}
+
+// Publish добавляет событие в поток.
+func (p *RedisStreamPublisher) Publish(ctx context.Context, event entity.DomainEvent) error {
+	err := p.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: p.stream,
+		MaxLen: p.maxLen,
+		Approx: p.maxLen > 0,
+		Values: map[string]interface{}{
+			"id":             event.ID,
+			"type":           string(event.Type),
+			"aggregate_type": event.AggregateType,
+			"aggregate_id":   event.AggregateID,
+			"payload":        string(event.Payload),
+			"occurred_at":    event.OccurredAt.UTC().Format(time.RFC3339Nano),
+		},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("outbox: redis stream %s: %w", p.stream, err)
+	}
+	return nil
+}
+
+// RedisStreamReader читает поток RedisStreamPublisher без группы
+// потребителей, поэтому каждый экземпляр сервиса получает все события,
+// включая записанные другими репликами.
+type RedisStreamReader struct {
+	client redis.Cmdable
+	stream string
+	block  time.Duration
+	logger *slog.Logger
+}
+
+// NewRedisStreamReader создает читателя потока stream. logger может быть nil.
+func NewRedisStreamReader(client redis.Cmdable, stream string, logger *slog.Logger) *RedisStreamReader {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &RedisStreamReader{client: client, stream: stream, block: time.Second, logger: logger}
+}
+
+// Run передает в handle события, добавленные в поток после запуска, пока не
+// отменен ctx. События, пропущенные во время недоступности Redis, не
+// восполняются: читатель продолжает с последнего полученного.
+func (r *RedisStreamReader) Run(ctx context.Context, handle func(entity.DomainEvent)) {
+	lastID := "$"
+	for ctx.Err() == nil {
+		streams, err := r.client.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{r.stream, lastID},
+			Count:   100,
+			Block:   r.block,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				r.logger.ErrorContext(ctx, "failed to read outbox stream", "stream", r.stream, "error", err)
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(r.block):
+			}
+			continue
+		}
+		for _, stream := range streams {
+			for _, message := range stream.Messages {
+				lastID = message.ID
+				event, err := decodeStreamEvent(message.Values)
+				if err != nil {
+					r.logger.WarnContext(ctx, "skipping malformed outbox stream entry", "stream", r.stream, "entry_id", message.ID, "error", err)
+					continue
+				}
+				handle(event)
+			}
+		}
+	}
+}
+
+// decodeStreamEvent разбирает запись, добавленную RedisStreamPublisher.
+func decodeStreamEvent(values map[string]interface{}) (entity.DomainEvent, error) {
+	field := func(name string) string {
+		value, _ := values[name].(string)
+		return value
+	}
+	id, err := strconv.ParseInt(field("id"), 10, 64)
+	if err != nil {
+		return entity.DomainEvent{}, fmt.Errorf("id: %w", err)
+	}
+	aggregateID, err := strconv.Atoi(field("aggregate_id"))
+	if err != nil {
+		return entity.DomainEvent{}, fmt.Errorf("aggregate_id: %w", err)
+	}
+	occurredAt, err := time.Parse(time.RFC3339Nano, field("occurred_at"))
+	if err != nil {
+		return entity.DomainEvent{}, fmt.Errorf("occurred_at: %w", err)
+	}
+	return entity.DomainEvent{
+		ID:            id,
+		Type:          entity.DomainEventType(field("type")),
+		AggregateType: field("aggregate_type"),
+		AggregateID:   aggregateID,
+		Payload:       json.RawMessage(field("payload")),
+		OccurredAt:    occurredAt,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package outbox
+
+import (
+	"Projectapirest/internal/entity"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestRedisStreamReaderDeliversToEveryReplica(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Две реплики читают один поток; обе должны увидеть изменение продукта
+	const replicas = 2
+	received := make(chan entity.ProductEvent, 2*replicas)
+	for i := 0; i < replicas; i++ {
+		reader := NewRedisStreamReader(client, "events", nil)
+		reader.block = 10 * time.Millisecond
+		go reader.Run(ctx, func(event entity.DomainEvent) {
+			productEvent, ok, err := entity.ProductEventFromDomain(event)
+			if err != nil {
+				t.Error(err)
+			}
+			if ok {
+				received <- productEvent
+			}
+		})
+	}
+	// Читатели начинают с конца потока, поэтому публикация ждет их запуска
+	time.Sleep(50 * time.Millisecond)
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	publisher := NewRedisStreamPublisher(client, "events", 0)
+	price, err := entity.NewDomainEvent(entity.EventProductPriceChanged, entity.AggregateProduct, 7,
+		entity.ProductPriceChange{ProductID: 7, OldPrice: 1, NewPrice: 2}, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := entity.NewDomainEvent(entity.EventProductUpdated, entity.AggregateProduct, 7,
+		entity.Product{ID: 7, Name: "book", Price: 2}, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, event := range []entity.DomainEvent{price, updated} {
+		event.ID = int64(i + 1)
+		if err := publisher.Publish(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < replicas; i++ {
+		select {
+		case event := <-received:
+			if event.Type != entity.ProductUpdated || event.Product.ID != 7 || event.Product.Name != "book" || !event.OccurredAt.Equal(at) {
+				t.Errorf("replica got %+v, want update of product 7", event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%d of %d replicas got the event", i, replicas)
+		}
+	}
+	select {
+	case event := <-received:
+		t.Errorf("unexpected event %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,172 @@
+package outbox
+
+import (
+	"Projectapirest/internal/clock"
+	"Projectapirest/internal/entity"
+	"Projectapirest/internal/repository"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Options описывает параметры Relay. Нулевые поля заменяются значениями
+// по умолчанию.
+type Options struct {
+	// BatchSize — сколько событий забирается за один проход.
+	BatchSize int
+	// PollInterval — пауза между проходами, когда outbox пуст.
+	PollInterval time.Duration
+	// Lease — время на отправку порции; неподтвержденные за него события
+	// выдаются снова, например если реплика упала посреди отправки. Отправка
+	// порции прекращается, когда срок истекает, чтобы другая реплика не
+	// доставила те же события повторно.
+	Lease time.Duration
+	// MinBackoff и MaxBackoff ограничивают экспоненциальную паузу перед
+	// повторной отправкой события после ошибки.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Retention — сколько хранить доставленные события; 0 — не удалять.
+	Retention time.Duration
+}
+
+// Значения Options по умолчанию.
+const (
+	defaultBatchSize    = 100
+	defaultPollInterval = time.Second
+	defaultLease        = 30 * time.Second
+	defaultMinBackoff   = time.Second
+	defaultMaxBackoff   = 10 * time.Minute
+	// purgeInterval — как часто удаляются доставленные события.
+	purgeInterval = time.Hour
+)
+
+// Relay пересылает события из outbox через Publisher. Несколько реплик
+// могут работать одновременно: хранилище выдает каждое событие одной из них.
+// Порядок доставки между повторами не гарантируется.
+type Relay struct {
+	repo      repository.OutboxRepositoryInterface
+	publisher Publisher
+	opts      Options
+	clock     clock.Clock
+	logger    *slog.Logger
+}
+
+// NewRelay создает Relay. logger может быть nil.
+func NewRelay(repo repository.OutboxRepositoryInterface, publisher Publisher, opts Options, clk clock.Clock, logger *slog.Logger) *Relay {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultPollInterval
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = defaultLease
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = defaultMinBackoff
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = max(defaultMaxBackoff, opts.MinBackoff)
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Relay{repo: repo, publisher: publisher, opts: opts, clock: clk, logger: logger}
+}
+
+// Run пересылает события, пока не отменен ctx. Пока outbox отдает полные
+// порции, следующая читается сразу, иначе — после PollInterval.
+func (r *Relay) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	nextPurge := r.clock.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		n, err := r.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.logger.ErrorContext(ctx, "outbox relay failed", "error", err)
+		}
+		if now := r.clock.Now(); r.opts.Retention > 0 && !now.Before(nextPurge) {
+			r.purge(ctx, now)
+			nextPurge = now.Add(purgeInterval)
+		}
+
+		wait := r.opts.PollInterval
+		if err == nil && n == r.opts.BatchSize {
+			wait = 0
+		}
+		timer.Reset(wait)
+	}
+}
+
+// RunOnce забирает одну порцию событий и отправляет их. Возвращает число
+// полученных из outbox событий. События, до которых не дошла очередь за
+// время аренды, остаются в outbox и будут выданы снова после ее окончания.
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	// Срок отсчитывается до Claim, поэтому он не позже срока аренды в хранилище
+	leaseEnd := r.clock.Now().Add(r.opts.Lease)
+	events, err := r.repo.Claim(ctx, r.opts.BatchSize, r.opts.Lease)
+	if err != nil {
+		return 0, err
+	}
+	// Результат отправки записывается и после отмены ctx, чтобы при остановке
+	// не отправлять уже доставленные события повторно
+	ackCtx := context.WithoutCancel(ctx)
+
+	published := make([]int64, 0, len(events))
+	for i, event := range events {
+		remaining := leaseEnd.Sub(r.clock.Now())
+		if remaining <= 0 {
+			r.logger.WarnContext(ctx, "outbox lease expired before the batch was published",
+				"published", len(published), "left", len(events)-i)
+			break
+		}
+		if err := r.publish(ctx, event, remaining); err != nil {
+			retryAt := r.clock.Now().Add(r.backoff(event.Attempts))
+			r.logger.WarnContext(ctx, "failed to publish outbox event",
+				"event_id", event.ID, "type", event.Type, "attempts", event.Attempts, "retry_at", retryAt, "error", err)
+			if err := r.repo.Retry(ackCtx, event.ID, retryAt, err.Error()); err != nil {
+				r.logger.ErrorContext(ctx, "failed to schedule outbox retry", "event_id", event.ID, "error", err)
+			}
+			continue
+		}
+		published = append(published, event.ID)
+	}
+	return len(events), r.repo.MarkPublished(ackCtx, published...)
+}
+
+// publish отправляет событие, ограничивая отправку оставшимся временем аренды.
+func (r *Relay) publish(ctx context.Context, event entity.DomainEvent, remaining time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, remaining)
+	defer cancel()
+	return r.publisher.Publish(ctx, event)
+}
+
+// backoff возвращает паузу перед следующей попыткой: MinBackoff, удваиваемый
+// с каждой попыткой, но не больше MaxBackoff.
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.opts.MinBackoff
+	for i := 1; i < attempts && delay < r.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, r.opts.MaxBackoff)
+}
+
+// purge удаляет доставленные события старше Retention.
+func (r *Relay) purge(ctx context.Context, now time.Time) {
+	deleted, err := r.repo.DeletePublished(ctx, now.Add(-r.opts.Retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			r.logger.ErrorContext(ctx, "failed to purge outbox", "error", err)
+		}
+		return
+	}
+	if deleted > 0 {
+		r.logger.InfoContext(ctx, "outbox purged", "deleted", deleted)
+	}
+}
@@ -0,0 +1,184 @@
+package outbox
+
+import (
+	"Projectapirest/internal/clock"
+	"Projectapirest/internal/entity"
+	"Projectapirest/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// manualClock — часы, которые переставляет тест.
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// flakyPublisher отказывает в отправке событий из failures, пока
+// счетчик отказов для события не исчерпан.
+type flakyPublisher struct {
+	MemoryPublisher
+	failures map[int64]int
+	attempts []int64
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, event entity.DomainEvent) error {
+	p.attempts = append(p.attempts, event.ID)
+	if p.failures[event.ID] > 0 {
+		p.failures[event.ID]--
+		return errors.New("connection refused")
+	}
+	return p.MemoryPublisher.Publish(ctx, event)
+}
+
+func newOutbox(t *testing.T, clk clock.Clock, n int) repository.OutboxRepositoryInterface {
+	t.Helper()
+	outbox := repository.NewMemoryOutboxRepository(repository.NewMemoryStore(clk))
+	for id := 1; id <= n; id++ {
+		event, err := entity.NewDomainEvent(entity.EventUserDeleted, entity.AggregateUser, id, entity.Deleted{ID: id}, clk.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := outbox.Add(context.Background(), event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return outbox
+}
+
+func TestRelayRetriesFailedEvents(t *testing.T) {
+	ctx := context.Background()
+	clk := &manualClock{now: time.Date(2024, 12, 22, 9, 0, 0, 0, time.UTC)}
+	publisher := &flakyPublisher{failures: map[int64]int{2: 2}}
+	relay := NewRelay(newOutbox(t, clk, 3), publisher, Options{MinBackoff: time.Second, MaxBackoff: time.Minute}, clk, nil)
+
+	run := func(wantClaimed int) {
+		t.Helper()
+		n, err := relay.RunOnce(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != wantClaimed {
+			t.Errorf("RunOnce claimed %d events, want %d", n, wantClaimed)
+		}
+	}
+
+	run(3)
+	// Событие 2 ждет паузы после ошибки, остальные доставлены
+	run(0)
+	clk.advance(time.Second)
+	run(1)
+	// Вторая ошибка удваивает паузу
+	clk.advance(time.Second)
+	run(0)
+	clk.advance(time.Second)
+	run(1)
+	run(0)
+
+	if got := fmt.Sprint(publisher.attempts); got != "[1 2 3 2 2]" {
+		t.Errorf("publish attempts = %s, want [1 2 3 2 2]", got)
+	}
+	delivered := publisher.Events()
+	if len(delivered) != 3 || delivered[2].ID != 2 || delivered[2].Attempts != 3 {
+		t.Errorf("delivered = %+v, want events 1, 3 and then 2 on the third attempt", delivered)
+	}
+}
+
+// slowPublisher доставляет события, переводя часы на delay за каждое,
+// и запоминает, был ли у отправки срок.
+type slowPublisher struct {
+	MemoryPublisher
+	clock     *manualClock
+	delay     time.Duration
+	deadlines []bool
+}
+
+func (p *slowPublisher) Publish(ctx context.Context, event entity.DomainEvent) error {
+	_, ok := ctx.Deadline()
+	p.deadlines = append(p.deadlines, ok)
+	p.clock.advance(p.delay)
+	return p.MemoryPublisher.Publish(ctx, event)
+}
+
+func TestRelayStopsPublishingAfterLease(t *testing.T) {
+	ctx := context.Background()
+	clk := &manualClock{now: time.Date(2024, 12, 22, 9, 0, 0, 0, time.UTC)}
+	repo := newOutbox(t, clk, 3)
+	publisher := &slowPublisher{clock: clk, delay: 20 * time.Second}
+	relay := NewRelay(repo, publisher, Options{Lease: 30 * time.Second}, clk, nil)
+
+	// Аренда истекает после второго события: третье не отправляется
+	if n, err := relay.RunOnce(ctx); err != nil || n != 3 {
+		t.Fatalf("RunOnce = %d, %v, want 3 claimed", n, err)
+	}
+	if got := eventIDs(publisher.Events()); got != "[1 2]" {
+		t.Fatalf("delivered %s, want [1 2]", got)
+	}
+	if got := fmt.Sprint(publisher.deadlines); got != "[true true]" {
+		t.Errorf("publish deadlines = %s, want every publish bounded by the lease", got)
+	}
+
+	// Другая реплика после окончания аренды получает только неотправленное событие
+	events, err := repo.Claim(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := eventIDs(events); got != "[3]" {
+		t.Errorf("reclaimed %s, want [3]", got)
+	}
+}
+
+func eventIDs(events []entity.DomainEvent) string {
+	ids := make([]int64, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+	return fmt.Sprint(ids)
+}
+
+func TestRelayBackoff(t *testing.T) {
+	relay := NewRelay(nil, nil, Options{MinBackoff: time.Second, MaxBackoff: 5 * time.Second}, clock.Real{}, nil)
+	for attempts, want := range []time.Duration{time.Second, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if got := relay.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestRelayRunStopsWithContext(t *testing.T) {
+	publisher := NewMemoryPublisher(0)
+	relay := NewRelay(newOutbox(t, clock.Real{}, 2), publisher, Options{PollInterval: time.Millisecond}, clock.Real{}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+	deadline := time.After(5 * time.Second)
+	for len(publisher.Events()) < 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("delivered %d events, want 2", len(publisher.Events()))
+		case <-time.After(time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,78 @@
+package outbox
+
+import (
+	"Projectapirest/internal/entity"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// SignatureHeader содержит HMAC-SHA256 тела запроса вебхука в виде
+// "sha256=<hex>", если задан секрет.
+const SignatureHeader = "X-Signature"
+
+// WebhookPublisher отправляет события POST-запросом с телом в JSON.
+// Успешной считается доставка с ответом 2xx.
+type WebhookPublisher struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// NewWebhookPublisher создает публикатор на адрес url. Непустой secret
+// включает подпись запросов; timeout ограничивает один запрос.
+func NewWebhookPublisher(url, secret string, timeout time.Duration) *WebhookPublisher {
+	return &WebhookPublisher{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: timeout, Transport: otelhttp.NewTransport(http.DefaultTransport)},
+	}
+}
+
+// Publish отправляет событие получателю.
+func (p *WebhookPublisher) Publish(ctx context.Context, event entity.DomainEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("outbox: encode event %d: %w", event.ID, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("outbox: webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", strconv.FormatInt(event.ID, 10))
+	req.Header.Set("X-Event-Type", string(event.Type))
+	if len(p.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(p.secret, body))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("outbox: webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	// Тело дочитывается, чтобы соединение вернулось в пул
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("outbox: webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign возвращает значение SignatureHeader для тела body. Получатель
+// проверяет подпись, вычисляя ее тем же способом.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
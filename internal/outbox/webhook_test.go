@@ -0,0 +1,51 @@
+package outbox
+
+import (
+	"Projectapirest/internal/entity"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhookPublisher(t *testing.T) {
+	status := http.StatusNoContent
+	var received entity.DomainEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if got, want := r.Header.Get(SignatureHeader), Sign([]byte("secret"), body); got != want {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+		if r.Header.Get("X-Event-ID") != "7" || r.Header.Get("X-Event-Type") != "product.deleted" {
+			t.Errorf("event headers = %v", r.Header)
+		}
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	event, err := entity.NewDomainEvent(entity.EventProductDeleted, entity.AggregateProduct, 3, entity.Deleted{ID: 3}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	event.ID = 7
+	publisher := NewWebhookPublisher(server.URL, "secret", time.Second)
+
+	if err := publisher.Publish(context.Background(), event); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if received.ID != 7 || received.AggregateID != 3 || string(received.Payload) != `{"ID":3}` {
+		t.Errorf("received = %+v", received)
+	}
+
+	// Ответ не из 2xx требует повторной отправки
+	status = http.StatusServiceUnavailable
+	if err := publisher.Publish(context.Background(), event); err == nil {
+		t.Error("Publish succeeded on 503")
+	}
+}
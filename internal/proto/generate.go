@@ -0,0 +1,8 @@
+// Package users содержит код, сгенерированный из api/proto/users.proto:
+// сообщения, gRPC-сервисы и обработчики REST-шлюза grpc-gateway.
+// Для генерации нужны protoc, плагины protoc-gen-go, protoc-gen-go-grpc,
+// protoc-gen-grpc-gateway и файлы google/api из github.com/googleapis/googleapis
+// в каталоге GOOGLEAPIS_DIR.
+package users
+
+//go:generate sh -c "protoc -I ../../api/proto -I \"$GOOGLEAPIS_DIR\" --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative --grpc-gateway_out=. --grpc-gateway_opt=paths=source_relative users.proto"
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"Projectapirest/internal/auth"
+	"Projectapirest/internal/logger"
+	"context"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor делает для gRPC то же, что HTTPMiddleware для HTTP:
+// маршрутом служит полное имя метода, а превышение лимита возвращает
+// ResourceExhausted с метаданными retry-after. Вызовы REST-шлюза не
+// ограничиваются: запрос уже учтен HTTP-слоем.
+func UnaryServerInterceptor(limiter Limiter, policy Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, err := allowGRPC(ctx, limiter, policy, info.FullMethod)
+		if md != nil {
+			_ = grpc.SetHeader(ctx, md)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor — потоковый вариант UnaryServerInterceptor:
+// открытие потока расходует один запрос лимита, сообщения потока не учитываются.
+func StreamServerInterceptor(limiter Limiter, policy Policy) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		md, err := allowGRPC(ss.Context(), limiter, policy, info.FullMethod)
+		if md != nil {
+			_ = ss.SetHeader(md)
+		}
+		if err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// allowGRPC учитывает вызов метода и возвращает метаданные ratelimit-* для
+// заголовка ответа; при превышении лимита — ошибку ResourceExhausted.
+func allowGRPC(ctx context.Context, limiter Limiter, policy Policy, method string) (metadata.MD, error) {
+	limit := policy.For(method)
+	if limit.IsZero() || auth.FromGateway(ctx) {
+		return nil, nil
+	}
+
+	var remoteAddr string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remoteAddr = p.Addr.String()
+	}
+	result, err := limiter.Allow(ctx, storageKey(method, clientKey(ctx, remoteAddr)), limit)
+	if err != nil {
+		logger.FromContext(ctx).WarnContext(ctx, "rate limiter unavailable", "error", err)
+		return nil, nil
+	}
+
+	md := metadata.Pairs(
+		"ratelimit-limit", strconv.Itoa(result.Limit),
+		"ratelimit-remaining", strconv.Itoa(result.Remaining),
+		"ratelimit-reset", strconv.Itoa(ceilSeconds(result.Reset)),
+	)
+	if !result.Allowed {
+		md.Set("retry-after", strconv.Itoa(max(1, ceilSeconds(result.RetryAfter))))
+		return md, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+	return md, nil
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"Projectapirest/internal/clock"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// fakeServerStream — поток клиента с адресом addr, запоминающий заголовок ответа.
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	header metadata.MD
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeServerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	policy := Policy{
+		Default: Limit{TokenBucket, 2, time.Minute},
+		Routes:  map[string]Limit{"/grpc.health.v1.Health/Watch": {}},
+	}
+	interceptor := StreamServerInterceptor(NewMemoryLimiter(clock.Fixed(t0)), policy)
+
+	tests := []struct {
+		name          string
+		method        string
+		addr          string
+		wantCode      codes.Code
+		wantRemaining string
+	}{
+		{name: "first stream", method: "/users.UserService/StreamUsers", addr: "10.0.0.1:1000", wantRemaining: "1"},
+		{name: "second stream", method: "/users.UserService/StreamUsers", addr: "10.0.0.1:1001", wantRemaining: "0"},
+		{name: "over limit", method: "/users.UserService/StreamUsers", addr: "10.0.0.1:1002", wantCode: codes.ResourceExhausted, wantRemaining: "0"},
+		{name: "other client", method: "/users.UserService/StreamUsers", addr: "10.0.0.2:1000", wantRemaining: "1"},
+		{name: "unlimited method", method: "/grpc.health.v1.Health/Watch", addr: "10.0.0.1:1003"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, err := net.ResolveTCPAddr("tcp", tt.addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ss := &fakeServerStream{ctx: peer.NewContext(context.Background(), &peer.Peer{Addr: addr})}
+			var called bool
+			err = interceptor(nil, ss, &grpc.StreamServerInfo{FullMethod: tt.method}, func(interface{}, grpc.ServerStream) error {
+				called = true
+				return nil
+			})
+			if status.Code(err) != tt.wantCode || called != (tt.wantCode == codes.OK) {
+				t.Fatalf("code = %v, handler called = %v, want %v", status.Code(err), called, tt.wantCode)
+			}
+			if got := ss.header.Get("ratelimit-remaining"); tt.wantRemaining == "" && len(got) != 0 ||
+				tt.wantRemaining != "" && (len(got) != 1 || got[0] != tt.wantRemaining) {
+				t.Errorf("ratelimit-remaining = %v, want %q", got, tt.wantRemaining)
+			}
+			if tt.wantCode == codes.ResourceExhausted && len(ss.header.Get("retry-after")) != 1 {
+				t.Errorf("header %v lacks retry-after", ss.header)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"Projectapirest/internal/auth"
+	"Projectapirest/internal/logger"
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// keyPrefix отделяет ключи лимитов от остальных ключей Redis.
+const keyPrefix = "ratelimit:"
+
+// HTTPMiddleware ограничивает запросы по маршруту и клиенту. Ответ содержит
+// заголовки RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset и
+// RateLimit-Policy; отклоненный запрос получает 429 с Retry-After. При
+// недоступном хранилище запрос пропускается. routeOf возвращает шаблон
+// маршрута; запросы без маршрута ограничиваются по умолчанию.
+func HTTPMiddleware(limiter Limiter, policy Policy, next http.Handler, routeOf func(r *http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeOf(r)
+		limit := policy.For(route)
+		if limit.IsZero() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if route == "" {
+			route = "unmatched"
+		}
+
+		ctx := r.Context()
+		result, err := limiter.Allow(ctx, storageKey(route, clientKey(ctx, r.RemoteAddr)), limit)
+		if err != nil {
+			logger.FromContext(ctx).WarnContext(ctx, "rate limiter unavailable", "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Window)))
+		if !result.Allowed {
+			header.Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(result.RetryAfter))))
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientKey определяет клиента: API-ключ, пользователь или IP-адрес.
+func clientKey(ctx context.Context, remoteAddr string) string {
+	if principal, ok := auth.FromContext(ctx); ok {
+		if principal.APIKeyID != 0 {
+			return fmt.Sprintf("apikey:%d", principal.APIKeyID)
+		}
+		return fmt.Sprintf("user:%d", principal.UserID)
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return "ip:" + host
+}
+
+// storageKey возвращает ключ состояния лимита для маршрута и клиента.
+func storageKey(route, client string) string {
+	return keyPrefix + route + ":" + client
+}
+
+// ceilSeconds округляет длительность вверх до целых секунд.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
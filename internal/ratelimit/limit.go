@@ -0,0 +1,142 @@
+// Package ratelimit ограничивает частоту запросов клиентов по алгоритмам
+// token bucket и sliding window с хранением состояния в памяти или в Redis.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Algorithm — алгоритм ограничения.
+type Algorithm string
+
+const (
+	// TokenBucket допускает всплески до Limit.Requests запросов и пополняет
+	// ведро равномерно: Requests токенов за Window.
+	TokenBucket Algorithm = "token_bucket"
+	// SlidingWindow допускает не более Limit.Requests запросов за любое окно
+	// длиной Window (оценка по счетчикам текущего и предыдущего окна).
+	SlidingWindow Algorithm = "sliding_window"
+)
+
+// Limit описывает ограничение: Requests запросов за Window.
+type Limit struct {
+	Algorithm Algorithm
+	Requests  int
+	Window    time.Duration
+}
+
+// IsZero сообщает, что ограничение не задано.
+func (l Limit) IsZero() bool {
+	return l.Requests <= 0 || l.Window <= 0
+}
+
+// String возвращает ограничение в формате конфигурации: "token_bucket:100/1m".
+func (l Limit) String() string {
+	return fmt.Sprintf("%s:%d/%s", l.Algorithm, l.Requests, l.Window)
+}
+
+// Result — решение лимитера по одному запросу.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset — время до полного восстановления лимита.
+	Reset time.Duration
+	// RetryAfter — через сколько повторить отклоненный запрос.
+	RetryAfter time.Duration
+}
+
+// Limiter принимает решение по запросу с ключом key.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// ParseLimit разбирает ограничение вида "<algorithm>:<requests>/<window>",
+// например "token_bucket:100/1m" или "sliding_window:10/1s".
+func ParseLimit(s string) (Limit, error) {
+	algorithm, rest, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return Limit{}, fmt.Errorf("ratelimit: invalid limit %q: want <algorithm>:<requests>/<window>", s)
+	}
+	requests, window, ok := strings.Cut(rest, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("ratelimit: invalid limit %q: want <algorithm>:<requests>/<window>", s)
+	}
+
+	limit := Limit{Algorithm: Algorithm(algorithm)}
+	if limit.Algorithm != TokenBucket && limit.Algorithm != SlidingWindow {
+		return Limit{}, fmt.Errorf("ratelimit: unknown algorithm %q", algorithm)
+	}
+	var err error
+	if limit.Requests, err = strconv.Atoi(requests); err != nil || limit.Requests <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: invalid request count in %q", s)
+	}
+	if limit.Window, err = time.ParseDuration(window); err != nil || limit.Window <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: invalid window in %q", s)
+	}
+	return limit, nil
+}
+
+// tokenBucketResult вычисляет результат по остатку токенов в ведре.
+func tokenBucketResult(limit Limit, tokens float64, allowed bool) Result {
+	rate := float64(limit.Requests) / limit.Window.Seconds()
+	result := Result{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Requests) - tokens) / rate),
+	}
+	if !allowed {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return result
+}
+
+// slidingWindowResult вычисляет результат по счетчикам текущего (current)
+// и предыдущего (previous) окон; elapsed — время от начала текущего окна.
+// current уже учитывает разрешенный запрос.
+func slidingWindowResult(limit Limit, current, previous int, elapsed time.Duration, allowed bool) Result {
+	window := limit.Window.Seconds()
+	weight := 1 - elapsed.Seconds()/window
+	estimate := float64(previous)*weight + float64(current)
+
+	result := Result{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: max(0, limit.Requests-int(math.Ceil(estimate))),
+		Reset:     limit.Window - elapsed,
+	}
+	if !allowed {
+		result.RetryAfter = slidingRetryAfter(limit, current, previous, elapsed)
+	}
+	return result
+}
+
+// slidingRetryAfter возвращает время, когда оценка опустится настолько,
+// что следующий запрос уложится в лимит.
+func slidingRetryAfter(limit Limit, current, previous int, elapsed time.Duration) time.Duration {
+	untilNextWindow := limit.Window - elapsed
+	if current+1 > limit.Requests || previous == 0 {
+		return untilNextWindow
+	}
+	// previous * (1 - t/window) + current + 1 <= limit
+	needWeight := float64(limit.Requests-current-1) / float64(previous)
+	at := time.Duration((1 - needWeight) * float64(limit.Window))
+	if at <= elapsed {
+		return 0
+	}
+	return min(at-elapsed, untilNextWindow)
+}
+
+// seconds переводит дробные секунды в Duration.
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr string
+	}{
+		{in: "token_bucket:100/1m", want: Limit{Algorithm: TokenBucket, Requests: 100, Window: time.Minute}},
+		{in: " sliding_window:10/1s ", want: Limit{Algorithm: SlidingWindow, Requests: 10, Window: time.Second}},
+		{in: "token_bucket", wantErr: "want <algorithm>:<requests>/<window>"},
+		{in: "token_bucket:100", wantErr: "want <algorithm>:<requests>/<window>"},
+		{in: "leaky_bucket:100/1m", wantErr: "unknown algorithm"},
+		{in: "token_bucket:x/1m", wantErr: "invalid request count"},
+		{in: "token_bucket:0/1m", wantErr: "invalid request count"},
+		{in: "token_bucket:100/minute", wantErr: "invalid window"},
+		{in: "token_bucket:100/-1s", wantErr: "invalid window"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseLimit(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseLimit error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParseLimit = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		defaultLimit string
+		routes       string
+		want         Policy
+		wantErr      string
+	}{
+		{
+			name:         "default only",
+			defaultLimit: "token_bucket:100/1m",
+			want:         Policy{Default: Limit{TokenBucket, 100, time.Minute}, Routes: map[string]Limit{}},
+		},
+		{
+			name:         "route overrides",
+			defaultLimit: Unlimited,
+			routes:       " POST /api/v1/users = sliding_window:10/1m ;GET /healthz=none;",
+			want: Policy{Routes: map[string]Limit{
+				"POST /api/v1/users": {SlidingWindow, 10, time.Minute},
+				"GET /healthz":       {},
+			}},
+		},
+		{name: "invalid default", defaultLimit: "token_bucket:0/1m", wantErr: "invalid request count"},
+		{name: "route without limit", routes: "POST /api/v1/users", wantErr: "want <route>=<limit>"},
+		{name: "invalid route limit", routes: "POST /api/v1/users=fixed:1/1s", wantErr: "unknown algorithm"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePolicy(tt.defaultLimit, tt.routes)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParsePolicy error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePolicy = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyFor(t *testing.T) {
+	policy, err := ParsePolicy("token_bucket:100/1m",
+		"POST /api/v1/users=sliding_window:10/1m;GET /healthz=none;/users.UserService/CreateUser=token_bucket:5/1s")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		route string
+		want  Limit
+	}{
+		{route: "POST /api/v1/users", want: Limit{SlidingWindow, 10, time.Minute}},
+		{route: "/users.UserService/CreateUser", want: Limit{TokenBucket, 5, time.Second}},
+		{route: "GET /healthz", want: Limit{}},
+		{route: "GET /api/v1/users", want: Limit{TokenBucket, 100, time.Minute}},
+		{route: "", want: Limit{TokenBucket, 100, time.Minute}},
+	}
+	for _, tt := range tests {
+		if got := policy.For(tt.route); got != tt.want {
+			t.Errorf("For(%q) = %v, want %v", tt.route, got, tt.want)
+		}
+	}
+	if !policy.For("GET /healthz").IsZero() {
+		t.Error("route limited with none is not unlimited")
+	}
+}
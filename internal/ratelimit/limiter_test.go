@@ -0,0 +1,148 @@
+package ratelimit
+
+import (
+	"Projectapirest/internal/clock"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// t0 — начало окна минутных ограничений.
+var t0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// step — запрос клиента через advance после предыдущего.
+type step struct {
+	advance        time.Duration
+	wantAllowed    bool
+	wantRemaining  int
+	wantRetryAfter time.Duration
+}
+
+var limiterTests = []struct {
+	name  string
+	limit Limit
+	steps []step
+}{
+	{
+		name:  "token bucket refill",
+		limit: Limit{TokenBucket, 2, time.Minute},
+		steps: []step{
+			{wantAllowed: true, wantRemaining: 1},
+			{wantAllowed: true, wantRemaining: 0},
+			{wantAllowed: false, wantRemaining: 0, wantRetryAfter: 30 * time.Second},
+			// За полминуты ведро пополняется на один токен
+			{advance: 30 * time.Second, wantAllowed: true, wantRemaining: 0},
+			// Ведро не наполняется сверх емкости
+			{advance: 2 * time.Minute, wantAllowed: true, wantRemaining: 1},
+		},
+	},
+	{
+		name:  "sliding window edges",
+		limit: Limit{SlidingWindow, 2, time.Minute},
+		steps: []step{
+			{wantAllowed: true, wantRemaining: 1},
+			{advance: 10 * time.Second, wantAllowed: true, wantRemaining: 0},
+			{advance: 10 * time.Second, wantAllowed: false, wantRemaining: 0, wantRetryAfter: 40 * time.Second},
+			// На границе окна предыдущее окно учитывается полностью
+			{advance: 40 * time.Second, wantAllowed: false, wantRemaining: 0, wantRetryAfter: 30 * time.Second},
+			// В середине окна — наполовину
+			{advance: 30 * time.Second, wantAllowed: true, wantRemaining: 0},
+			// После пропущенного окна счетчики обнуляются
+			{advance: 90 * time.Second, wantAllowed: true, wantRemaining: 1},
+		},
+	},
+}
+
+// testLimiters создает лимитеры, время которых переставляет тест через now.
+var testLimiters = map[string]func(t *testing.T, now *time.Time) Limiter{
+	"memory": func(t *testing.T, now *time.Time) Limiter {
+		return NewMemoryLimiter(clock.Func(func() time.Time { return *now }))
+	},
+	"redis": func(t *testing.T, now *time.Time) Limiter {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+		return limiterFunc(func(ctx context.Context, key string, limit Limit) (Result, error) {
+			server.SetTime(*now)
+			return NewRedisLimiter(client).Allow(ctx, key, limit)
+		})
+	},
+}
+
+// limiterFunc позволяет использовать функцию как Limiter.
+type limiterFunc func(ctx context.Context, key string, limit Limit) (Result, error)
+
+func (f limiterFunc) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	return f(ctx, key, limit)
+}
+
+func TestLimiters(t *testing.T) {
+	ctx := context.Background()
+	for backend, newLimiter := range testLimiters {
+		for _, tt := range limiterTests {
+			t.Run(backend+"/"+tt.name, func(t *testing.T) {
+				now := t0
+				limiter := newLimiter(t, &now)
+				for i, s := range tt.steps {
+					now = now.Add(s.advance)
+					result, err := limiter.Allow(ctx, "client", tt.limit)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if result.Allowed != s.wantAllowed || result.Remaining != s.wantRemaining ||
+						result.RetryAfter.Round(time.Millisecond) != s.wantRetryAfter {
+						t.Errorf("step %d at %s: got %+v, want allowed %v, remaining %d, retry after %s",
+							i, now.Sub(t0), result, s.wantAllowed, s.wantRemaining, s.wantRetryAfter)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestLimitersKeepClientsApart(t *testing.T) {
+	ctx := context.Background()
+	limit := Limit{TokenBucket, 1, time.Minute}
+	for backend, newLimiter := range testLimiters {
+		t.Run(backend, func(t *testing.T) {
+			now := t0
+			limiter := newLimiter(t, &now)
+			for _, key := range []string{"a", "b"} {
+				if result, err := limiter.Allow(ctx, key, limit); err != nil || !result.Allowed {
+					t.Errorf("first request of %s = %+v, %v", key, result, err)
+				}
+			}
+			if result, _ := limiter.Allow(ctx, "a", limit); result.Allowed {
+				t.Error("second request of a allowed")
+			}
+		})
+	}
+}
+
+func TestRedisLimiterUsesOnlyDeclaredKeys(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	limiter := NewRedisLimiter(client)
+
+	// Скрипты вызываются в разных окнах, чтобы затронуть счетчики обоих окон
+	for _, at := range []time.Duration{0, time.Minute, 3 * time.Minute} {
+		server.SetTime(t0.Add(at))
+		if _, err := limiter.Allow(ctx, "ratelimit:bucket", Limit{TokenBucket, 2, time.Minute}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := limiter.Allow(ctx, "ratelimit:window", Limit{SlidingWindow, 2, time.Minute}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := server.Keys(); len(got) != 2 || got[0] != "ratelimit:bucket" || got[1] != "ratelimit:window" {
+		t.Errorf("keys = %v, want only the keys passed to the scripts", got)
+	}
+	if ttl := server.TTL("ratelimit:window"); ttl != 2*time.Minute {
+		t.Errorf("window TTL = %v, want %v", ttl, 2*time.Minute)
+	}
+}
@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"Projectapirest/internal/clock"
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepEvery задает, через сколько вызовов Allow удалять неактивные ключи.
+const sweepEvery = 1024
+
+// MemoryLimiter хранит состояние лимитов в памяти процесса. Подходит для
+// одного узла: у каждой реплики свои счетчики.
+type MemoryLimiter struct {
+	clock clock.Clock
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	windows map[string]*window
+	calls   int
+}
+
+// bucket — состояние token bucket.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	idle    time.Duration // через сколько бездействия ведро снова полное
+}
+
+// window — состояние sliding window.
+type window struct {
+	start    time.Time
+	current  int
+	previous int
+	length   time.Duration
+}
+
+// NewMemoryLimiter создает лимитер в памяти.
+func NewMemoryLimiter(clk clock.Clock) *MemoryLimiter {
+	return &MemoryLimiter{
+		clock:   clk,
+		buckets: make(map[string]*bucket),
+		windows: make(map[string]*window),
+	}
+}
+
+// Allow принимает решение по запросу с ключом key.
+func (l *MemoryLimiter) Allow(_ context.Context, key string, limit Limit) (Result, error) {
+	now := l.clock.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.calls++; l.calls%sweepEvery == 0 {
+		l.sweep(now)
+	}
+	if limit.Algorithm == SlidingWindow {
+		return l.slidingWindow(now, key, limit), nil
+	}
+	return l.tokenBucket(now, key, limit), nil
+}
+
+func (l *MemoryLimiter) tokenBucket(now time.Time, key string, limit Limit) Result {
+	capacity := float64(limit.Requests)
+	rate := capacity / limit.Window.Seconds()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now, idle: limit.Window}
+		l.buckets[key] = b
+	}
+	elapsed := math.Max(0, now.Sub(b.updated).Seconds())
+	b.tokens = math.Min(capacity, b.tokens+elapsed*rate)
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return tokenBucketResult(limit, b.tokens, allowed)
+}
+
+func (l *MemoryLimiter) slidingWindow(now time.Time, key string, limit Limit) Result {
+	start := now.Truncate(limit.Window)
+
+	w, ok := l.windows[key]
+	if !ok {
+		w = &window{start: start, length: limit.Window}
+		l.windows[key] = w
+	}
+	if !w.start.Equal(start) {
+		if start.Sub(w.start) == limit.Window {
+			w.previous = w.current
+		} else {
+			w.previous = 0
+		}
+		w.current = 0
+		w.start = start
+	}
+
+	elapsed := now.Sub(start)
+	weight := 1 - elapsed.Seconds()/limit.Window.Seconds()
+	allowed := float64(w.previous)*weight+float64(w.current)+1 <= float64(limit.Requests)
+	if allowed {
+		w.current++
+	}
+	return slidingWindowResult(limit, w.current, w.previous, elapsed, allowed)
+}
+
+// sweep удаляет ключи, которые не использовались дольше своего окна:
+// их состояние совпадает с состоянием нового ключа. Вызывается под блокировкой.
+func (l *MemoryLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) > b.idle {
+			delete(l.buckets, key)
+		}
+	}
+	for key, w := range l.windows {
+		if now.Sub(w.start) > 2*w.length {
+			delete(l.windows, key)
+		}
+	}
+}
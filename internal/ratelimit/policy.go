@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Unlimited в конфигурации маршрута отключает ограничение.
+const Unlimited = "none"
+
+// Policy сопоставляет маршрутам ограничения. Маршрут HTTP — шаблон вида
+// "POST /api/v1/products", маршрут gRPC — полное имя метода.
+type Policy struct {
+	Default Limit
+	Routes  map[string]Limit
+}
+
+// For возвращает ограничение маршрута; нулевое значение означает, что
+// маршрут не ограничен.
+func (p Policy) For(route string) Limit {
+	if limit, ok := p.Routes[route]; ok {
+		return limit
+	}
+	return p.Default
+}
+
+// ParsePolicy разбирает ограничение по умолчанию и список ограничений
+// маршрутов вида "POST /api/v1/users=sliding_window:10/1m;GET /healthz=none".
+func ParsePolicy(defaultLimit, routes string) (Policy, error) {
+	policy := Policy{Routes: make(map[string]Limit)}
+	var err error
+	if policy.Default, err = parseRouteLimit(defaultLimit); err != nil {
+		return Policy{}, err
+	}
+	for _, entry := range strings.Split(routes, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		route, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			return Policy{}, fmt.Errorf("ratelimit: invalid route limit %q: want <route>=<limit>", entry)
+		}
+		limit, err := parseRouteLimit(spec)
+		if err != nil {
+			return Policy{}, err
+		}
+		policy.Routes[strings.TrimSpace(route)] = limit
+	}
+	return policy, nil
+}
+
+// parseRouteLimit разбирает ограничение, допуская пустое значение и Unlimited.
+func parseRouteLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == Unlimited {
+		return Limit{}, nil
+	}
+	return ParseLimit(s)
+}
@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Скрипты берут время из redis.call('TIME'), чтобы все реплики считали
+// лимит по одним часам.
+
+// tokenBucketScript пополняет ведро по прошедшему времени и забирает токен.
+// KEYS[1] — ключ ведра; ARGV: емкость, окно в миллисекундах.
+// Возвращает {разрешен, остаток токенов строкой}.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil then
+	tokens = capacity
+	ts = now
+end
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * capacity / window)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], window)
+return {allowed, tostring(tokens)}
+`)
+
+// slidingWindowScript учитывает запрос в счетчике текущего окна. Счетчики
+// текущего и предыдущего окон хранятся в одном хеше, как в MemoryLimiter,
+// поэтому скрипт обращается только к ключам из KEYS.
+// KEYS[1] — ключ окна; ARGV: лимит, окно в миллисекундах.
+// Возвращает {разрешен, текущий счетчик, предыдущий счетчик, мс от начала окна}.
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local start = now - now % window
+local elapsed = now - start
+
+local state = redis.call('HMGET', KEYS[1], 'start', 'current', 'previous')
+local current = tonumber(state[2]) or 0
+local previous = tonumber(state[3]) or 0
+local last = tonumber(state[1])
+if last ~= start then
+	if last == start - window then
+		previous = current
+	else
+		previous = 0
+	end
+	current = 0
+end
+
+local allowed = 0
+if previous * (1 - elapsed / window) + current + 1 <= limit then
+	current = current + 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'start', start, 'current', current, 'previous', previous)
+redis.call('PEXPIRE', KEYS[1], window * 2)
+return {allowed, current, previous, elapsed}
+`)
+
+// RedisLimiter хранит состояние лимитов в Redis, поэтому ограничение
+// действует на весь кластер.
+type RedisLimiter struct {
+	client redis.Scripter
+}
+
+// NewRedisLimiter создает лимитер поверх клиента Redis.
+func NewRedisLimiter(client redis.Scripter) *RedisLimiter {
+	return &RedisLimiter{client: client}
+}
+
+// Allow принимает решение по запросу с ключом key.
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	window := limit.Window.Milliseconds()
+	if window <= 0 {
+		return Result{}, fmt.Errorf("ratelimit: window %s is shorter than 1ms", limit.Window)
+	}
+
+	if limit.Algorithm == SlidingWindow {
+		values, err := slidingWindowScript.Run(ctx, l.client, []string{key}, limit.Requests, window).Int64Slice()
+		if err != nil {
+			return Result{}, fmt.Errorf("ratelimit: sliding window: %w", err)
+		}
+		if len(values) != 4 {
+			return Result{}, fmt.Errorf("ratelimit: sliding window: unexpected reply %v", values)
+		}
+		elapsed := time.Duration(values[3]) * time.Millisecond
+		return slidingWindowResult(limit, int(values[1]), int(values[2]), elapsed, values[0] == 1), nil
+	}
+
+	reply, err := tokenBucketScript.Run(ctx, l.client, []string{key}, limit.Requests, window).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("ratelimit: token bucket: %w", err)
+	}
+	if len(reply) != 2 {
+		return Result{}, fmt.Errorf("ratelimit: token bucket: unexpected reply %v", reply)
+	}
+	allowed, _ := reply[0].(int64)
+	raw, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("ratelimit: token bucket: invalid tokens %q", raw)
+	}
+	return tokenBucketResult(limit, tokens, allowed == 1), nil
+}
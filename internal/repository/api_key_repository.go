@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"Projectapirest/internal/clock"
+	"Projectapirest/internal/entity"
+	"Projectapirest/internal/tracing"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// APIKeyRepositoryInterface описывает методы работы с API-ключами.
+type APIKeyRepositoryInterface interface {
+	// Create сохраняет ключ; ссылка на отсутствующего пользователя — ErrUserReference.
+	Create(ctx context.Context, key entity.APIKey) (entity.APIKey, error)
+	// FindByHash находит ключ по SHA-256, в том числе отозванный.
+	FindByHash(ctx context.Context, hash []byte) (entity.APIKey, error)
+	// FindByUser возвращает ключи пользователя, упорядоченные по ID.
+	FindByUser(ctx context.Context, userID int) ([]entity.APIKey, error)
+	// Revoke отзывает ключ пользователя и возвращает сохраненную запись.
+	// Если ключа нет или он уже отозван, возвращается ErrNotFound.
+	Revoke(ctx context.Context, userID, id int) (entity.APIKey, error)
+	// TouchLastUsed обновляет время последнего использования ключа.
+	TouchLastUsed(ctx context.Context, id int, at time.Time) error
+}
+
+// apiKeyColumns — столбцы, которые читают запросы APIKeyRepository.
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at`
+
+// APIKeyRepository реализует APIKeyRepositoryInterface поверх PostgreSQL.
+type APIKeyRepository struct {
+	db    *sql.DB
+	clock clock.Clock
+}
+
+// NewAPIKeyRepository создает новый репозиторий API-ключей.
+func NewAPIKeyRepository(db *sql.DB, clk clock.Clock) APIKeyRepositoryInterface {
+	return &APIKeyRepository{db: db, clock: clk}
+}
+
+// Create добавляет новый ключ.
+func (r *APIKeyRepository) Create(ctx context.Context, key entity.APIKey) (entity.APIKey, error) {
+	query := `
+        INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`
+	ctx, span := startQuerySpan(ctx, "APIKeyRepository.Create", "INSERT", "api_keys", query)
+	defer span.End()
+	if key.Scopes == nil {
+		key.Scopes = []string{}
+	}
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.Hash,
+		pq.Array(key.Scopes),
+		key.ExpiresAt,
+		r.clock.Now(),
+	).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return key, tracing.RecordError(span, translateError(err))
+	}
+	normalizeAPIKeyTimes(&key)
+	return key, nil
+}
+
+// FindByHash находит ключ по хешу.
+func (r *APIKeyRepository) FindByHash(ctx context.Context, hash []byte) (entity.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1`
+	ctx, span := startQuerySpan(ctx, "APIKeyRepository.FindByHash", "SELECT", "api_keys", query)
+	defer span.End()
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, query, hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return key, ErrNotFound
+	}
+	if err != nil {
+		return key, tracing.RecordError(span, translateError(err))
+	}
+	return key, nil
+}
+
+// FindByUser возвращает ключи пользователя.
+func (r *APIKeyRepository) FindByUser(ctx context.Context, userID int) ([]entity.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE user_id = $1 ORDER BY id`
+	ctx, span := startQuerySpan(ctx, "APIKeyRepository.FindByUser", "SELECT", "api_keys", query)
+	defer span.End()
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, tracing.RecordError(span, translateError(err))
+	}
+	defer rows.Close()
+
+	keys := []entity.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, tracing.RecordError(span, translateError(err))
+		}
+		keys = append(keys, key)
+	}
+	return keys, tracing.RecordError(span, rows.Err())
+}
+
+// Revoke отзывает ключ пользователя.
+func (r *APIKeyRepository) Revoke(ctx context.Context, userID, id int) (entity.APIKey, error) {
+	query := `
+        UPDATE api_keys
+        SET revoked_at = $1
+        WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL
+        RETURNING ` + apiKeyColumns
+	ctx, span := startQuerySpan(ctx, "APIKeyRepository.Revoke", "UPDATE", "api_keys", query)
+	defer span.End()
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, query, r.clock.Now(), id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.APIKey{}, ErrNotFound
+	}
+	if err != nil {
+		return entity.APIKey{}, tracing.RecordError(span, translateError(err))
+	}
+	return key, nil
+}
+
+// TouchLastUsed обновляет время последнего использования ключа.
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id int, at time.Time) error {
+	query := `UPDATE api_keys SET last_used_at = $1 WHERE id = $2`
+	ctx, span := startQuerySpan(ctx, "APIKeyRepository.TouchLastUsed", "UPDATE", "api_keys", query)
+	defer span.End()
+	result, err := r.db.ExecContext(ctx, query, at, id)
+	if err != nil {
+		return tracing.RecordError(span, translateError(err))
+	}
+	err = requireAffected(result)
+	if errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return tracing.RecordError(span, err)
+}
+
+// rowScanner — общий интерфейс *sql.Row и *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAPIKey читает строку со столбцами apiKeyColumns.
+func scanAPIKey(row rowScanner) (entity.APIKey, error) {
+	var (
+		key                            entity.APIKey
+		expiresAt, lastUsed, revokedAt sql.NullTime
+	)
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.Hash,
+		pq.Array(&key.Scopes),
+		&expiresAt,
+		&lastUsed,
+		&revokedAt,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return entity.APIKey{}, err
+	}
+	key.ExpiresAt = nullTime(expiresAt)
+	key.LastUsedAt = nullTime(lastUsed)
+	key.RevokedAt = nullTime(revokedAt)
+	normalizeAPIKeyTimes(&key)
+	return key, nil
+}
+
+// nullTime переводит NULL в nil.
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// normalizeAPIKeyTimes приводит все отметки времени ключа к UTC.
+func normalizeAPIKeyTimes(key *entity.APIKey) {
+	normalizeTimes(&key.CreatedAt)
+	for _, t := range []*time.Time{key.ExpiresAt, key.LastUsedAt, key.RevokedAt} {
+		if t != nil {
+			normalizeTimes(t)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package repository_test
+
+import (
+	"Projectapirest/internal/clock"
+	"Projectapirest/internal/repository"
+	"Projectapirest/internal/repository/repotest"
+	"context"
+	"testing"
+)
+
+func TestMemoryRepositoryContract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T, clk clock.Clock) (repository.UserRepositoryInterface, repository.ProductRepositoryInterface) {
+		store := repository.NewMemoryStore(clk)
+		return repository.NewMemoryUserRepository(store), repository.NewMemoryProductRepository(store)
+	})
+}
+
+// TestPostgresRepositoryContract запускается при заданной TEST_DATABASE_URL
+// и очищает таблицы перед каждым подтестом.
+func TestPostgresRepositoryContract(t *testing.T) {
+	db := openTestDB(t)
+	repotest.Run(t, func(t *testing.T, clk clock.Clock) (repository.UserRepositoryInterface, repository.ProductRepositoryInterface) {
+		if _, err := db.ExecContext(context.Background(), `TRUNCATE api_keys, products, users RESTART IDENTITY`); err != nil {
+			t.Fatalf("truncate tables: %v", err)
+		}
+		return repository.NewUserRepository(db, clk), repository.NewProductRepository(db, clk)
+	})
+}
+
+func TestMemoryAPIKeyRepositoryContract(t *testing.T) {
+	repotest.RunAPIKeys(t, func(t *testing.T, clk clock.Clock) (repository.UserRepositoryInterface, repository.APIKeyRepositoryInterface) {
+		store := repository.NewMemoryStore(clk)
+		return repository.NewMemoryUserRepository(store), repository.NewMemoryAPIKeyRepository(store)
+	})
+}
+
+func TestPostgresAPIKeyRepositoryContract(t *testing.T) {
+	db := openTestDB(t)
+	repotest.RunAPIKeys(t, func(t *testing.T, clk clock.Clock) (repository.UserRepositoryInterface, repository.APIKeyRepositoryInterface) {
+		if _, err := db.ExecContext(context.Background(), `TRUNCATE api_keys, products, users RESTART IDENTITY`); err != nil {
+			t.Fatalf("truncate tables: %v", err)
+		}
+		return repository.NewUserRepository(db, clk), repository.NewAPIKeyRepository(db, clk)
+	})
+}
+
+func TestMemoryOutboxContract(t *testing.T) {
+	repotest.RunOutbox(t, func(t *testing.T, clk clock.Clock) (repository.Transactor, repository.UserRepositoryInterface, repository.OutboxRepositoryInterface) {
+		store := repository.NewMemoryStore(clk)
+		return repository.NewMemoryTransactor(store), repository.NewMemoryUserRepository(store), repository.NewMemoryOutboxRepository(store)
+	})
+}
+
+func TestPostgresOutboxContract(t *testing.T) {
+	db := openTestDB(t)
+	repotest.RunOutbox(t, func(t *testing.T, clk clock.Clock) (repository.Transactor, repository.UserRepositoryInterface, repository.OutboxRepositoryInterface) {
+		if _, err := db.ExecContext(context.Background(), `TRUNCATE outbox, api_keys, products, users RESTART IDENTITY`); err != nil {
+			t.Fatalf("truncate tables: %v", err)
+		}
+		return repository.NewTransactor(db, clk), repository.NewUserRepository(db, clk), repository.NewOutboxRepository(db, clk)
+	})
+}
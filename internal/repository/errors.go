@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound возвращается, когда запись с указанным идентификатором отсутствует.
+	ErrNotFound = errors.New("запись не найдена")
+	// ErrDuplicateEmail возвращается при попытке сохранить занятый email.
+	ErrDuplicateEmail = errors.New("email уже используется")
+	// ErrDuplicateAPIKey возвращается при совпадении хеша нового API-ключа
+	// с уже сохраненным.
+	ErrDuplicateAPIKey = errors.New("API-ключ уже существует")
+	// ErrUserReference возвращается, когда продукт ссылается на несуществующего
+	// пользователя или удаляемый пользователь владеет продуктами.
+	ErrUserReference = errors.New("нарушена ссылка на пользователя")
+	// ErrInvalidPage возвращается при неположительном limit или отрицательном offset.
+	ErrInvalidPage = errors.New("некорректные параметры страницы")
+)
+
+// Коды ошибок PostgreSQL, которые репозитории переводят в свои ошибки.
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+)
+
+// apiKeyHashConstraint — уникальное ограничение на хеш API-ключа, которое
+// PostgreSQL по умолчанию называет по таблице и столбцу.
+const apiKeyHashConstraint = "api_keys_key_hash_key"
+
+// translateError переводит ошибки ограничений PostgreSQL в ошибки репозитория,
+// чтобы все реализации хранилища сообщали о них одинаково.
+func translateError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch pqErr.Code {
+	case pqUniqueViolation:
+		if pqErr.Constraint == apiKeyHashConstraint {
+			return ErrDuplicateAPIKey
+		}
+		return ErrDuplicateEmail
+	case pqForeignKeyViolation:
+		return ErrUserReference
+	}
+	return err
+}
+
+// validatePage проверяет параметры постраничной выборки.
+func validatePage(limit, offset int) error {
+	if limit <= 0 || offset < 0 {
+		return ErrInvalidPage
+	}
+	return nil
+}
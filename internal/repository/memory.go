@@ -0,0 +1,609 @@
+package repository
+
+import (
+	"Projectapirest/internal/clock"
+	"Projectapirest/internal/entity"
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore хранит пользователей, продукты и API-ключи в памяти процесса. Общее
+// хранилище нужно, чтобы репозитории проверяли ссылки продуктов на
+// пользователей так же, как внешний ключ в PostgreSQL.
+type MemoryStore struct {
+	clock         clock.Clock
+	mu            sync.RWMutex
+	users         map[int]entity.User
+	products      map[int]entity.Product
+	apiKeys       map[int]entity.APIKey
+	outbox        map[int64]outboxRecord
+	nextUserID    int
+	nextProductID int
+	nextAPIKeyID  int
+	nextOutboxID  int64
+}
+
+// NewMemoryStore создает пустое хранилище в памяти.
+func NewMemoryStore(clk clock.Clock) *MemoryStore {
+	return &MemoryStore{
+		clock:    clk,
+		users:    make(map[int]entity.User),
+		products: make(map[int]entity.Product),
+		apiKeys:  make(map[int]entity.APIKey),
+		outbox:   make(map[int64]outboxRecord),
+	}
+}
+
+// lock захватывает хранилище на запись и возвращает функцию освобождения.
+// Внутри транзакции блокировку уже держит WithinTx.
+func (s *MemoryStore) lock(tx *memoryTx) (unlock func()) {
+	if tx != nil {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// rlock захватывает хранилище на чтение, как lock.
+func (s *MemoryStore) rlock(tx *memoryTx) (unlock func()) {
+	if tx != nil {
+		return func() {}
+	}
+	s.mu.RLock()
+	return s.mu.RUnlock
+}
+
+// now возвращает время в том виде, в каком его сохранил бы PostgreSQL:
+// в UTC и с точностью до микросекунд.
+func (s *MemoryStore) now() time.Time {
+	return s.clock.Now().UTC().Truncate(time.Microsecond)
+}
+
+// emailTaken сообщает, занят ли email другим пользователем. Вызывается под блокировкой.
+func (s *MemoryStore) emailTaken(email string, exceptID int) bool {
+	for id, user := range s.users {
+		if id != exceptID && user.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+// userReferenced сообщает, владеет ли пользователь продуктами. Вызывается под блокировкой.
+func (s *MemoryStore) userReferenced(userID int) bool {
+	for _, product := range s.products {
+		if product.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// userExists проверяет ссылку продукта на пользователя; нулевой ID означает NULL.
+// Вызывается под блокировкой.
+func (s *MemoryStore) userExists(userID int) bool {
+	if userID == 0 {
+		return true
+	}
+	_, ok := s.users[userID]
+	return ok
+}
+
+// MemoryUserRepository реализует UserRepositoryInterface поверх MemoryStore.
+type MemoryUserRepository struct {
+	store *MemoryStore
+	tx    *memoryTx
+}
+
+// NewMemoryUserRepository создает репозиторий пользователей в памяти.
+func NewMemoryUserRepository(store *MemoryStore) UserRepositoryInterface {
+	return &MemoryUserRepository{store: store}
+}
+
+// Create добавляет нового пользователя.
+func (r *MemoryUserRepository) Create(_ context.Context, user entity.User) (entity.User, error) {
+	defer r.store.lock(r.tx)()
+
+	if r.store.emailTaken(user.Email, 0) {
+		return user, ErrDuplicateEmail
+	}
+	r.store.nextUserID++
+	user.ID = r.store.nextUserID
+
+	user.CreatedAt = r.store.now()
+	user.UpdatedAt = user.CreatedAt
+	r.store.users[user.ID] = user
+	r.tx.onRollback(func() { delete(r.store.users, user.ID) })
+	return user, nil
+}
+
+// FindByID находит пользователя по ID.
+func (r *MemoryUserRepository) FindByID(_ context.Context, id int) (entity.User, error) {
+	defer r.store.rlock(r.tx)()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return entity.User{}, ErrNotFound
+	}
+	return user, nil
+}
+
+// Update обновляет имя и email пользователя и возвращает сохраненную запись.
+func (r *MemoryUserRepository) Update(_ context.Context, user entity.User) (entity.User, error) {
+	defer r.store.lock(r.tx)()
+
+	stored, ok := r.store.users[user.ID]
+	if !ok {
+		return entity.User{}, ErrNotFound
+	}
+	if r.store.emailTaken(user.Email, user.ID) {
+		return entity.User{}, ErrDuplicateEmail
+	}
+	r.tx.onRollback(func(original entity.User) func() {
+		return func() { r.store.users[original.ID] = original }
+	}(stored))
+	stored.Name = user.Name
+	stored.Email = user.Email
+	stored.UpdatedAt = r.store.now()
+	r.store.users[user.ID] = stored
+	return stored, nil
+}
+
+// Delete удаляет пользователя, если на него не ссылаются продукты.
+// API-ключи пользователя удаляются вместе с ним, как ON DELETE CASCADE.
+func (r *MemoryUserRepository) Delete(_ context.Context, id int) error {
+	defer r.store.lock(r.tx)()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if r.store.userReferenced(id) {
+		return ErrUserReference
+	}
+	delete(r.store.users, id)
+	r.tx.onRollback(func() { r.store.users[id] = user })
+	for keyID, key := range r.store.apiKeys {
+		if key.UserID == id {
+			delete(r.store.apiKeys, keyID)
+			r.tx.onRollback(func() { r.store.apiKeys[keyID] = key })
+		}
+	}
+	return nil
+}
+
+// FindAll возвращает всех пользователей, упорядоченных по ID.
+func (r *MemoryUserRepository) FindAll(_ context.Context) ([]entity.User, error) {
+	defer r.store.rlock(r.tx)()
+
+	users := make([]entity.User, 0, len(r.store.users))
+	for _, user := range r.store.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+// FindPage возвращает страницу пользователей, упорядоченных по ID.
+func (r *MemoryUserRepository) FindPage(ctx context.Context, limit, offset int) ([]entity.User, error) {
+	if err := validatePage(limit, offset); err != nil {
+		return nil, err
+	}
+	users, _ := r.FindAll(ctx)
+	return page(users, limit, offset), nil
+}
+
+// FindAfter возвращает пользователей после курсора afterID, упорядоченных по ID.
+func (r *MemoryUserRepository) FindAfter(ctx context.Context, afterID, limit int) ([]entity.User, error) {
+	if err := validatePage(limit, 0); err != nil {
+		return nil, err
+	}
+	users, _ := r.FindAll(ctx)
+	start := sort.Search(len(users), func(i int) bool { return users[i].ID > afterID })
+	return page(users[start:], limit, 0), nil
+}
+
+// MemoryProductRepository реализует ProductRepositoryInterface поверх MemoryStore.
+type MemoryProductRepository struct {
+	store *MemoryStore
+	tx    *memoryTx
+}
+
+// NewMemoryProductRepository создает репозиторий продуктов в памяти.
+func NewMemoryProductRepository(store *MemoryStore) ProductRepositoryInterface {
+	return &MemoryProductRepository{store: store}
+}
+
+// Create добавляет новый продукт.
+func (r *MemoryProductRepository) Create(_ context.Context, product entity.Product) (entity.Product, error) {
+	defer r.store.lock(r.tx)()
+
+	if !r.store.userExists(product.UserID) {
+		return product, ErrUserReference
+	}
+	r.store.nextProductID++
+	product.ID = r.store.nextProductID
+
+	product.CreatedAt = r.store.now()
+	product.UpdatedAt = product.CreatedAt
+	r.store.products[product.ID] = product
+	r.tx.onRollback(func() { delete(r.store.products, product.ID) })
+	return product, nil
+}
+
+// FindByID находит продукт по ID.
+func (r *MemoryProductRepository) FindByID(_ context.Context, id int) (entity.Product, error) {
+	defer r.store.rlock(r.tx)()
+
+	product, ok := r.store.products[id]
+	if !ok {
+		return entity.Product{}, ErrNotFound
+	}
+	return product, nil
+}
+
+// FindByIDForUpdate находит продукт по ID. Транзакция MemoryStore держит
+// блокировку всего хранилища, поэтому отдельная блокировка записи не нужна.
+func (r *MemoryProductRepository) FindByIDForUpdate(ctx context.Context, id int) (entity.Product, error) {
+	return r.FindByID(ctx, id)
+}
+
+// Update обновляет информацию о продукте и возвращает сохраненную запись.
+func (r *MemoryProductRepository) Update(_ context.Context, product entity.Product) (entity.Product, error) {
+	defer r.store.lock(r.tx)()
+
+	stored, ok := r.store.products[product.ID]
+	if !ok {
+		return entity.Product{}, ErrNotFound
+	}
+	if !r.store.userExists(product.UserID) {
+		return entity.Product{}, ErrUserReference
+	}
+	r.tx.onRollback(func(original entity.Product) func() {
+		return func() { r.store.products[original.ID] = original }
+	}(stored))
+	stored.Name = product.Name
+	stored.Description = product.Description
+	stored.Price = product.Price
+	stored.UserID = product.UserID
+	stored.UpdatedAt = r.store.now()
+	r.store.products[product.ID] = stored
+	return stored, nil
+}
+
+// Delete удаляет продукт.
+func (r *MemoryProductRepository) Delete(_ context.Context, id int) error {
+	defer r.store.lock(r.tx)()
+
+	product, ok := r.store.products[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(r.store.products, id)
+	r.tx.onRollback(func() { r.store.products[id] = product })
+	return nil
+}
+
+// FindAll возвращает все продукты, упорядоченные по ID.
+func (r *MemoryProductRepository) FindAll(_ context.Context) ([]entity.Product, error) {
+	defer r.store.rlock(r.tx)()
+
+	products := make([]entity.Product, 0, len(r.store.products))
+	for _, product := range r.store.products {
+		products = append(products, product)
+	}
+	sort.Slice(products, func(i, j int) bool { return products[i].ID < products[j].ID })
+	return products, nil
+}
+
+// FindPage возвращает страницу продуктов, упорядоченных по ID.
+func (r *MemoryProductRepository) FindPage(ctx context.Context, limit, offset int) ([]entity.Product, error) {
+	if err := validatePage(limit, offset); err != nil {
+		return nil, err
+	}
+	products, _ := r.FindAll(ctx)
+	return page(products, limit, offset), nil
+}
+
+// FindAfter возвращает продукты после курсора afterID, упорядоченные по ID.
+func (r *MemoryProductRepository) FindAfter(ctx context.Context, afterID, limit int) ([]entity.Product, error) {
+	if err := validatePage(limit, 0); err != nil {
+		return nil, err
+	}
+	products, _ := r.FindAll(ctx)
+	start := sort.Search(len(products), func(i int) bool { return products[i].ID > afterID })
+	return page(products[start:], limit, 0), nil
+}
+
+// page вырезает из упорядоченного среза окно, как LIMIT/OFFSET в SQL.
+func page[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return items[:0]
+	}
+	items = items[offset:]
+	if limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// MemoryAPIKeyRepository реализует APIKeyRepositoryInterface поверх MemoryStore.
+type MemoryAPIKeyRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryAPIKeyRepository создает репозиторий API-ключей в памяти.
+func NewMemoryAPIKeyRepository(store *MemoryStore) APIKeyRepositoryInterface {
+	return &MemoryAPIKeyRepository{store: store}
+}
+
+// Create добавляет новый ключ.
+func (r *MemoryAPIKeyRepository) Create(_ context.Context, key entity.APIKey) (entity.APIKey, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[key.UserID]; !ok {
+		return key, ErrUserReference
+	}
+	for _, stored := range r.store.apiKeys {
+		if string(stored.Hash) == string(key.Hash) {
+			return key, ErrDuplicateAPIKey
+		}
+	}
+	r.store.nextAPIKeyID++
+	key.ID = r.store.nextAPIKeyID
+	key.CreatedAt = r.store.now()
+	key.LastUsedAt, key.RevokedAt = nil, nil
+	if key.Scopes == nil {
+		key.Scopes = []string{}
+	}
+	key = copyAPIKey(key)
+	if key.ExpiresAt != nil {
+		*key.ExpiresAt = key.ExpiresAt.UTC().Truncate(time.Microsecond)
+	}
+	r.store.apiKeys[key.ID] = key
+	return copyAPIKey(key), nil
+}
+
+// FindByHash находит ключ по хешу.
+func (r *MemoryAPIKeyRepository) FindByHash(_ context.Context, hash []byte) (entity.APIKey, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, key := range r.store.apiKeys {
+		if string(key.Hash) == string(hash) {
+			return copyAPIKey(key), nil
+		}
+	}
+	return entity.APIKey{}, ErrNotFound
+}
+
+// FindByUser возвращает ключи пользователя, упорядоченные по ID.
+func (r *MemoryAPIKeyRepository) FindByUser(_ context.Context, userID int) ([]entity.APIKey, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	keys := []entity.APIKey{}
+	for _, key := range r.store.apiKeys {
+		if key.UserID == userID {
+			keys = append(keys, copyAPIKey(key))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+// Revoke отзывает ключ пользователя.
+func (r *MemoryAPIKeyRepository) Revoke(_ context.Context, userID, id int) (entity.APIKey, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key, ok := r.store.apiKeys[id]
+	if !ok || key.UserID != userID || key.RevokedAt != nil {
+		return entity.APIKey{}, ErrNotFound
+	}
+	now := r.store.now()
+	key.RevokedAt = &now
+	r.store.apiKeys[id] = key
+	return copyAPIKey(key), nil
+}
+
+// TouchLastUsed обновляет время последнего использования ключа.
+func (r *MemoryAPIKeyRepository) TouchLastUsed(_ context.Context, id int, at time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key, ok := r.store.apiKeys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	at = at.UTC().Truncate(time.Microsecond)
+	key.LastUsedAt = &at
+	r.store.apiKeys[id] = key
+	return nil
+}
+
+// copyAPIKey копирует срезы и указатели ключа, чтобы вызывающий код
+// не мог изменить запись хранилища.
+func copyAPIKey(key entity.APIKey) entity.APIKey {
+	key.Hash = append([]byte(nil), key.Hash...)
+	key.Scopes = append([]string{}, key.Scopes...)
+	for _, t := range []**time.Time{&key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt} {
+		if *t != nil {
+			copied := **t
+			*t = &copied
+		}
+	}
+	return key
+}
+
+// memoryTx — журнал отката транзакции в памяти. Репозитории вне транзакции
+// получают nil и ничего не записывают.
+type memoryTx struct {
+	undo []func()
+}
+
+// onRollback запоминает действие, отменяющее изменение.
+func (tx *memoryTx) onRollback(undo func()) {
+	if tx != nil {
+		tx.undo = append(tx.undo, undo)
+	}
+}
+
+// rollback отменяет изменения в обратном порядке.
+func (tx *memoryTx) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		tx.undo[i]()
+	}
+}
+
+// memoryTransactor реализует Transactor поверх MemoryStore.
+type memoryTransactor struct {
+	store *MemoryStore
+}
+
+// NewMemoryTransactor создает Transactor для репозиториев в памяти.
+// Транзакции держат хранилище целиком, поэтому выполняются по одной.
+func NewMemoryTransactor(store *MemoryStore) Transactor {
+	return &memoryTransactor{store: store}
+}
+
+// WithinTx выполняет fn под блокировкой хранилища и при ошибке или панике
+// отменяет сделанные изменения по журналу.
+func (t *memoryTransactor) WithinTx(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	tx := &memoryTx{}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.rollback()
+		}
+	}()
+	err := fn(ctx, Tx{
+		Users:    &MemoryUserRepository{store: t.store, tx: tx},
+		Products: &MemoryProductRepository{store: t.store, tx: tx},
+		Outbox:   &MemoryOutboxRepository{store: t.store, tx: tx},
+	})
+	committed = err == nil
+	return err
+}
+
+// outboxRecord — событие outbox и состояние его доставки.
+type outboxRecord struct {
+	event       entity.DomainEvent
+	availableAt time.Time
+	publishedAt *time.Time
+	lastError   string
+}
+
+// MemoryOutboxRepository реализует OutboxRepositoryInterface поверх MemoryStore.
+type MemoryOutboxRepository struct {
+	store *MemoryStore
+	tx    *memoryTx
+}
+
+// NewMemoryOutboxRepository создает репозиторий outbox в памяти.
+func NewMemoryOutboxRepository(store *MemoryStore) OutboxRepositoryInterface {
+	return &MemoryOutboxRepository{store: store}
+}
+
+// Add добавляет события в outbox.
+func (r *MemoryOutboxRepository) Add(_ context.Context, events ...entity.DomainEvent) error {
+	defer r.store.lock(r.tx)()
+
+	now := r.store.now()
+	for _, event := range events {
+		r.store.nextOutboxID++
+		event.ID = r.store.nextOutboxID
+		event.Payload = append([]byte(nil), event.Payload...)
+		event.OccurredAt = event.OccurredAt.UTC().Truncate(time.Microsecond)
+		event.Attempts = 0
+		r.store.outbox[event.ID] = outboxRecord{event: event, availableAt: now}
+		r.tx.onRollback(func() { delete(r.store.outbox, event.ID) })
+	}
+	return nil
+}
+
+// Claim выдает готовые к отправке события и продлевает их аренду.
+func (r *MemoryOutboxRepository) Claim(_ context.Context, limit int, lease time.Duration) ([]entity.DomainEvent, error) {
+	if err := validatePage(limit, 0); err != nil {
+		return nil, err
+	}
+	defer r.store.lock(r.tx)()
+
+	now := r.store.now()
+	ids := []int64{}
+	for id, record := range r.store.outbox {
+		if record.publishedAt == nil && !record.availableAt.After(now) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	events := []entity.DomainEvent{}
+	for _, id := range page(ids, limit, 0) {
+		record := r.store.outbox[id]
+		r.tx.onRollback(r.restore(record))
+		record.availableAt = now.Add(lease)
+		record.event.Attempts++
+		r.store.outbox[id] = record
+		event := record.event
+		event.Payload = append([]byte(nil), event.Payload...)
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// MarkPublished отмечает события доставленными.
+func (r *MemoryOutboxRepository) MarkPublished(_ context.Context, ids ...int64) error {
+	defer r.store.lock(r.tx)()
+
+	now := r.store.now()
+	for _, id := range ids {
+		if record, ok := r.store.outbox[id]; ok {
+			r.tx.onRollback(r.restore(record))
+			record.publishedAt = &now
+			r.store.outbox[id] = record
+		}
+	}
+	return nil
+}
+
+// Retry откладывает повторную выдачу недоставленного события.
+func (r *MemoryOutboxRepository) Retry(_ context.Context, id int64, at time.Time, reason string) error {
+	defer r.store.lock(r.tx)()
+
+	record, ok := r.store.outbox[id]
+	if !ok || record.publishedAt != nil {
+		return ErrNotFound
+	}
+	r.tx.onRollback(r.restore(record))
+	record.availableAt = at.UTC().Truncate(time.Microsecond)
+	record.lastError = reason
+	r.store.outbox[id] = record
+	return nil
+}
+
+// DeletePublished удаляет доставленные события старше before.
+func (r *MemoryOutboxRepository) DeletePublished(_ context.Context, before time.Time) (int, error) {
+	defer r.store.lock(r.tx)()
+
+	deleted := 0
+	for id, record := range r.store.outbox {
+		if record.publishedAt != nil && record.publishedAt.Before(before) {
+			delete(r.store.outbox, id)
+			r.tx.onRollback(r.restore(record))
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// restore возвращает действие, восстанавливающее запись outbox в прежнем виде.
+func (r *MemoryOutboxRepository) restore(record outboxRecord) func() {
+	return func() { r.store.outbox[record.event.ID] = record }
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"Projectapirest/internal/clock"
+	"Projectapirest/internal/entity"
+	"Projectapirest/internal/tracing"
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// OutboxRepositoryInterface описывает хранилище исходящих событий
+// (transactional outbox).
+type OutboxRepositoryInterface interface {
+	// Add сохраняет события. В транзакции Transactor запись атомарна
+	// с изменением сущности.
+	Add(ctx context.Context, events ...entity.DomainEvent) error
+	// Claim выдает до limit готовых к отправке событий по возрастанию ID,
+	// увеличивает их счетчик попыток и скрывает их от других вызовов на lease.
+	// Событие, не подтвержденное за это время, будет выдано снова.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]entity.DomainEvent, error)
+	// MarkPublished отмечает события доставленными.
+	MarkPublished(ctx context.Context, ids ...int64) error
+	// Retry откладывает повторную выдачу недоставленного события до at
+	// и сохраняет причину ошибки.
+	Retry(ctx context.Context, id int64, at time.Time, reason string) error
+	// DeletePublished удаляет события, доставленные раньше before,
+	// и возвращает их количество.
+	DeletePublished(ctx context.Context, before time.Time) (int, error)
+}
+
+// OutboxRepository реализует OutboxRepositoryInterface поверх PostgreSQL.
+// Claim блокирует строки с SKIP LOCKED, поэтому несколько реплик могут
+// разбирать outbox одновременно, не получая одни и те же события.
+type OutboxRepository struct {
+	db    dbtx
+	clock clock.Clock
+}
+
+// NewOutboxRepository создает репозиторий outbox.
+func NewOutboxRepository(db *sql.DB, clk clock.Clock) OutboxRepositoryInterface {
+	return &OutboxRepository{db: db, clock: clk}
+}
+
+// Add добавляет события в outbox.
+func (r *OutboxRepository) Add(ctx context.Context, events ...entity.DomainEvent) error {
+	query := `
+        INSERT INTO outbox (event_type, aggregate_type, aggregate_id, payload, occurred_at, available_at)
+        VALUES ($1, $2, $3, $4, $5, $6)`
+	ctx, span := startQuerySpan(ctx, "OutboxRepository.Add", "INSERT", "outbox", query)
+	defer span.End()
+	now := r.clock.Now()
+	for _, event := range events {
+		_, err := r.db.ExecContext(
+			ctx,
+			query,
+			string(event.Type),
+			event.AggregateType,
+			event.AggregateID,
+			[]byte(event.Payload),
+			event.OccurredAt,
+			now,
+		)
+		if err != nil {
+			return tracing.RecordError(span, translateError(err))
+		}
+	}
+	return nil
+}
+
+// Claim выдает готовые к отправке события и продлевает их аренду.
+func (r *OutboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]entity.DomainEvent, error) {
+	if err := validatePage(limit, 0); err != nil {
+		return nil, err
+	}
+	query := `
+        UPDATE outbox SET available_at = $1, attempts = attempts + 1
+        WHERE id IN (
+            SELECT id FROM outbox
+            WHERE published_at IS NULL AND available_at <= $2
+            ORDER BY id
+            LIMIT $3
+            FOR UPDATE SKIP LOCKED)
+        RETURNING id, event_type, aggregate_type, aggregate_id, payload, occurred_at, attempts`
+	ctx, span := startQuerySpan(ctx, "OutboxRepository.Claim", "UPDATE", "outbox", query)
+	defer span.End()
+	now := r.clock.Now()
+	rows, err := r.db.QueryContext(ctx, query, now.Add(lease), now, limit)
+	if err != nil {
+		return nil, tracing.RecordError(span, translateError(err))
+	}
+	defer rows.Close()
+
+	events := []entity.DomainEvent{}
+	for rows.Next() {
+		var event entity.DomainEvent
+		var payload []byte
+		err := rows.Scan(
+			&event.ID,
+			&event.Type,
+			&event.AggregateType,
+			&event.AggregateID,
+			&payload,
+			&event.OccurredAt,
+			&event.Attempts,
+		)
+		if err != nil {
+			return nil, tracing.RecordError(span, translateError(err))
+		}
+		event.Payload = payload
+		normalizeTimes(&event.OccurredAt)
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+	// RETURNING не сохраняет порядок подзапроса
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, nil
+}
+
+// MarkPublished отмечает события доставленными.
+func (r *OutboxRepository) MarkPublished(ctx context.Context, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	query := `UPDATE outbox SET published_at = $1 WHERE id = ANY($2)`
+	ctx, span := startQuerySpan(ctx, "OutboxRepository.MarkPublished", "UPDATE", "outbox", query)
+	defer span.End()
+	_, err := r.db.ExecContext(ctx, query, r.clock.Now(), pq.Array(ids))
+	return tracing.RecordError(span, translateError(err))
+}
+
+// Retry откладывает повторную выдачу события. Если события нет или оно
+// уже доставлено, возвращается ErrNotFound.
+func (r *OutboxRepository) Retry(ctx context.Context, id int64, at time.Time, reason string) error {
+	query := `UPDATE outbox SET available_at = $1, last_error = $2 WHERE id = $3 AND published_at IS NULL`
+	ctx, span := startQuerySpan(ctx, "OutboxRepository.Retry", "UPDATE", "outbox", query)
+	defer span.End()
+	result, err := r.db.ExecContext(ctx, query, at, reason, id)
+	if err != nil {
+		return tracing.RecordError(span, translateError(err))
+	}
+	return requireAffected(result)
+}
+
+// DeletePublished удаляет доставленные события старше before.
+func (r *OutboxRepository) DeletePublished(ctx context.Context, before time.Time) (int, error) {
+	query := `DELETE FROM outbox WHERE published_at < $1`
+	ctx, span := startQuerySpan(ctx, "OutboxRepository.DeletePublished", "DELETE", "outbox", query)
+	defer span.End()
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, tracing.RecordError(span, translateError(err))
+	}
+	deleted, err := result.RowsAffected()
+	return int(deleted), tracing.RecordError(span, err)
+}
@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"Projectapirest/internal/clock"
 	"Projectapirest/internal/entity"
+	"Projectapirest/internal/tracing"
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 )
 
@@ -11,91 +14,178 @@ import (
 type ProductRepositoryInterface interface {
 	Create(ctx context.Context, product entity.Product) (entity.Product, error)
 	FindByID(ctx context.Context, id int) (entity.Product, error)
-	Update(ctx context.Context, product entity.Product) error
+	// FindByIDForUpdate находит продукт и блокирует его до конца транзакции,
+	// чтобы прочитанное состояние не изменилось до записи.
+	FindByIDForUpdate(ctx context.Context, id int) (entity.Product, error)
+	Update(ctx context.Context, product entity.Product) (entity.Product, error)
 	Delete(ctx context.Context, id int) error
 	FindAll(ctx context.Context) ([]entity.Product, error)
+	// FindPage возвращает не более limit продуктов, пропустив первые offset.
+	FindPage(ctx context.Context, limit, offset int) ([]entity.Product, error)
+	// FindAfter возвращает не более limit продуктов с ID больше afterID
+	// по возрастанию ID. Курсор не сбивается от вставок и удалений между вызовами.
+	FindAfter(ctx context.Context, afterID, limit int) ([]entity.Product, error)
 }
 
 // ProductRepository содержит ссылку на базу данных и реализует интерфейс ProductRepositoryInterface.
 type ProductRepository struct {
-	db *sql.DB
+	db    dbtx
+	clock clock.Clock
 }
 
 // NewProductRepository создает новый репозиторий продуктов.
-func NewProductRepository(db *sql.DB) ProductRepositoryInterface {
-	return &ProductRepository{db: db} // Возвращаем интерфейс
+func NewProductRepository(db *sql.DB, clk clock.Clock) ProductRepositoryInterface {
+	return &ProductRepository{db: db, clock: clk} // Возвращаем интерфейс
 }
 
 // Create добавляет новый продукт в базу данных.
 func (r *ProductRepository) Create(ctx context.Context, product entity.Product) (entity.Product, error) {
 	query := `
-        INSERT INTO product (name, description, price, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5) RETURNING id`
+        INSERT INTO products (name, description, price, user_id, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $5) RETURNING id, created_at, updated_at`
+	ctx, span := startQuerySpan(ctx, "ProductRepository.Create", "INSERT", "products", query)
+	defer span.End()
 	err := r.db.QueryRowContext(
 		ctx,
 		query,
 		product.Name,
 		product.Description,
 		product.Price,
-		time.Now(),
-		time.Now(),
-	).Scan(&product.ID)
+		nullableID(product.UserID),
+		r.clock.Now(),
+	).Scan(&product.ID, &product.CreatedAt, &product.UpdatedAt)
 	if err != nil {
-		return product, err
+		return product, tracing.RecordError(span, translateError(err))
 	}
+	normalizeTimes(&product.CreatedAt, &product.UpdatedAt)
 	return product, nil
 }
 
 // FindByID находит продукт по ID.
 func (r *ProductRepository) FindByID(ctx context.Context, id int) (entity.Product, error) {
-	query := `SELECT id, name, description, price, created_at, updated_at FROM product WHERE id = $1`
+	query := `SELECT id, name, COALESCE(description, ''), price, COALESCE(user_id, 0), created_at, updated_at FROM products WHERE id = $1`
+	return r.findOne(ctx, "ProductRepository.FindByID", query, id)
+}
+
+// FindByIDForUpdate находит продукт по ID и блокирует строку (SELECT ... FOR
+// UPDATE). Вне транзакции блокировка снимается сразу после запроса.
+func (r *ProductRepository) FindByIDForUpdate(ctx context.Context, id int) (entity.Product, error) {
+	query := `SELECT id, name, COALESCE(description, ''), price, COALESCE(user_id, 0), created_at, updated_at FROM products WHERE id = $1 FOR UPDATE`
+	return r.findOne(ctx, "ProductRepository.FindByIDForUpdate", query, id)
+}
+
+// findOne выполняет запрос одного продукта по ID.
+func (r *ProductRepository) findOne(ctx context.Context, spanName, query string, id int) (entity.Product, error) {
+	ctx, span := startQuerySpan(ctx, spanName, "SELECT", "products", query)
+	defer span.End()
 	var product entity.Product
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&product.ID,
 		&product.Name,
 		&product.Description,
 		&product.Price,
+		&product.UserID,
 		&product.CreatedAt,
 		&product.UpdatedAt,
 	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return product, ErrNotFound
+	}
 	if err != nil {
-		return product, err
+		return product, tracing.RecordError(span, translateError(err))
 	}
+	normalizeTimes(&product.CreatedAt, &product.UpdatedAt)
 	return product, nil
 }
 
-// Update обновляет информацию о продукте.
-func (r *ProductRepository) Update(ctx context.Context, product entity.Product) error {
+// Update обновляет информацию о продукте и возвращает сохраненную запись.
+// Если продукта нет, возвращается ErrNotFound.
+func (r *ProductRepository) Update(ctx context.Context, product entity.Product) (entity.Product, error) {
 	query := `
-        UPDATE product
-        SET name = $1, description = $2, price = $3, updated_at = $4
-        WHERE id = $5`
-	_, err := r.db.ExecContext(
+        UPDATE products
+        SET name = $1, description = $2, price = $3, user_id = $4, updated_at = $5
+        WHERE id = $6
+        RETURNING id, name, COALESCE(description, ''), price, COALESCE(user_id, 0), created_at, updated_at`
+	ctx, span := startQuerySpan(ctx, "ProductRepository.Update", "UPDATE", "products", query)
+	defer span.End()
+	var updated entity.Product
+	err := r.db.QueryRowContext(
 		ctx,
 		query,
 		product.Name,
 		product.Description,
 		product.Price,
-		time.Now(),
+		nullableID(product.UserID),
+		r.clock.Now(),
 		product.ID,
+	).Scan(
+		&updated.ID,
+		&updated.Name,
+		&updated.Description,
+		&updated.Price,
+		&updated.UserID,
+		&updated.CreatedAt,
+		&updated.UpdatedAt,
 	)
-	return err
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.Product{}, ErrNotFound
+	}
+	if err != nil {
+		return entity.Product{}, tracing.RecordError(span, translateError(err))
+	}
+	normalizeTimes(&updated.CreatedAt, &updated.UpdatedAt)
+	return updated, nil
 }
 
-// Delete удаляет продукт из базы данных.
+// Delete удаляет продукт из базы данных. Если продукта нет,
+// возвращается ErrNotFound.
 func (r *ProductRepository) Delete(ctx context.Context, id int) error {
-	query := `DELETE FROM product WHERE id = $1`
-	_, err := r.db.ExecContext(ctx, query, id)
-	return err
+	query := `DELETE FROM products WHERE id = $1`
+	ctx, span := startQuerySpan(ctx, "ProductRepository.Delete", "DELETE", "products", query)
+	defer span.End()
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return tracing.RecordError(span, translateError(err))
+	}
+	err = requireAffected(result)
+	if errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return tracing.RecordError(span, err)
 }
 
 // FindAll возвращает список всех продуктов.
 func (r *ProductRepository) FindAll(ctx context.Context) ([]entity.Product, error) {
-	query := `SELECT id, name, description, price, created_at, updated_at FROM product`
-	rows, err := r.db.QueryContext(ctx, query)
-	if err != nil {
+	query := `SELECT id, name, COALESCE(description, ''), price, COALESCE(user_id, 0), created_at, updated_at FROM products ORDER BY id`
+	return r.list(ctx, "ProductRepository.FindAll", query)
+}
+
+// FindPage возвращает страницу продуктов, упорядоченных по ID.
+func (r *ProductRepository) FindPage(ctx context.Context, limit, offset int) ([]entity.Product, error) {
+	if err := validatePage(limit, offset); err != nil {
+		return nil, err
+	}
+	query := `SELECT id, name, COALESCE(description, ''), price, COALESCE(user_id, 0), created_at, updated_at FROM products ORDER BY id LIMIT $1 OFFSET $2`
+	return r.list(ctx, "ProductRepository.FindPage", query, limit, offset)
+}
+
+// FindAfter возвращает продукты после курсора afterID, упорядоченные по ID.
+func (r *ProductRepository) FindAfter(ctx context.Context, afterID, limit int) ([]entity.Product, error) {
+	if err := validatePage(limit, 0); err != nil {
 		return nil, err
 	}
+	query := `SELECT id, name, COALESCE(description, ''), price, COALESCE(user_id, 0), created_at, updated_at FROM products WHERE id > $1 ORDER BY id LIMIT $2`
+	return r.list(ctx, "ProductRepository.FindAfter", query, afterID, limit)
+}
+
+// list выполняет выборку продуктов и сканирует строки результата.
+func (r *ProductRepository) list(ctx context.Context, spanName, query string, args ...interface{}) ([]entity.Product, error) {
+	ctx, span := startQuerySpan(ctx, spanName, "SELECT", "products", query)
+	defer span.End()
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, tracing.RecordError(span, translateError(err))
+	}
 	defer rows.Close()
 
 	products := []entity.Product{}
@@ -106,13 +196,43 @@ func (r *ProductRepository) FindAll(ctx context.Context) ([]entity.Product, erro
 			&product.Name,
 			&product.Description,
 			&product.Price,
+			&product.UserID,
 			&product.CreatedAt,
 			&product.UpdatedAt,
 		)
 		if err != nil {
-			return nil, err
+			return nil, tracing.RecordError(span, translateError(err))
 		}
+		normalizeTimes(&product.CreatedAt, &product.UpdatedAt)
 		products = append(products, product)
 	}
-	return products, nil
+	return products, tracing.RecordError(span, rows.Err())
+}
+
+// nullableID преобразует нулевой внешний ключ в NULL.
+func nullableID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// normalizeTimes приводит отметки времени к UTC: драйвер возвращает
+// TIMESTAMPTZ в часовом поясе сессии.
+func normalizeTimes(times ...*time.Time) {
+	for _, t := range times {
+		*t = t.UTC()
+	}
+}
+
+// requireAffected возвращает ErrNotFound, если запрос не затронул ни одной строки.
+func requireAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
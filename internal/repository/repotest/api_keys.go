@@ -0,0 +1,185 @@
+package repotest
+
+import (
+	"Projectapirest/internal/clock"
+	"Projectapirest/internal/entity"
+	"Projectapirest/internal/repository"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// APIKeyFactory возвращает репозитории пользователей и API-ключей поверх
+// общего пустого хранилища.
+type APIKeyFactory func(t *testing.T, clk clock.Clock) (repository.UserRepositoryInterface, repository.APIKeyRepositoryInterface)
+
+// RunAPIKeys прогоняет контракт APIKeyRepositoryInterface.
+func RunAPIKeys(t *testing.T, factory APIKeyFactory) {
+	ctx := context.Background()
+	created := time.Date(2024, 3, 1, 15, 4, 5, 123456789, time.FixedZone("MSK", 3*60*60))
+	want := func(ts time.Time) time.Time { return ts.UTC().Truncate(time.Microsecond) }
+
+	t.Run("CreateAndFindByHash", func(t *testing.T) {
+		users, keys := factory(t, &manualClock{now: created})
+		user := mustCreateUser(t, users, "alice", "alice@example.com")
+		expires := created.Add(24 * time.Hour)
+		key := mustCreateAPIKey(t, keys, user.ID, "ci", &expires, "read", "write")
+		if key.ID == 0 {
+			t.Fatal("Create did not assign an ID")
+		}
+		if key.CreatedAt != want(created) {
+			t.Errorf("CreatedAt = %v, want %v", key.CreatedAt, want(created))
+		}
+
+		got, err := keys.FindByHash(ctx, key.Hash)
+		if err != nil {
+			t.Fatalf("FindByHash: %v", err)
+		}
+		if got.ID != key.ID || got.UserID != user.ID || got.Name != "ci" || got.Prefix != key.Prefix {
+			t.Errorf("FindByHash = %+v, want %+v", got, key)
+		}
+		if len(got.Scopes) != 2 || got.Scopes[0] != "read" || got.Scopes[1] != "write" {
+			t.Errorf("Scopes = %v, want [read write]", got.Scopes)
+		}
+		if got.ExpiresAt == nil || *got.ExpiresAt != want(expires) {
+			t.Errorf("ExpiresAt = %v, want %v", got.ExpiresAt, want(expires))
+		}
+		if got.LastUsedAt != nil || got.RevokedAt != nil {
+			t.Errorf("new key has usage or revocation: %+v", got)
+		}
+	})
+
+	t.Run("FindMissing", func(t *testing.T) {
+		_, keys := factory(t, clock.Real{})
+		if _, err := keys.FindByHash(ctx, []byte("missing")); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("FindByHash(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("CreateForMissingUser", func(t *testing.T) {
+		_, keys := factory(t, clock.Real{})
+		_, err := keys.Create(ctx, entity.APIKey{UserID: 404, Name: "ci", Prefix: "pk_x", Hash: []byte("h")})
+		if !errors.Is(err, repository.ErrUserReference) {
+			t.Errorf("Create error = %v, want ErrUserReference", err)
+		}
+	})
+
+	t.Run("DuplicateHash", func(t *testing.T) {
+		users, keys := factory(t, clock.Real{})
+		user := mustCreateUser(t, users, "alice", "alice@example.com")
+		key := mustCreateAPIKey(t, keys, user.ID, "ci", nil)
+		_, err := keys.Create(ctx, entity.APIKey{UserID: user.ID, Name: "copy", Prefix: key.Prefix, Hash: key.Hash})
+		if !errors.Is(err, repository.ErrDuplicateAPIKey) {
+			t.Errorf("Create with taken hash error = %v, want ErrDuplicateAPIKey", err)
+		}
+	})
+
+	t.Run("FindByUser", func(t *testing.T) {
+		users, keys := factory(t, clock.Real{})
+		alice := mustCreateUser(t, users, "alice", "alice@example.com")
+		bob := mustCreateUser(t, users, "bob", "bob@example.com")
+		first := mustCreateAPIKey(t, keys, alice.ID, "first", nil)
+		mustCreateAPIKey(t, keys, bob.ID, "other", nil)
+		second := mustCreateAPIKey(t, keys, alice.ID, "second", nil)
+
+		got, err := keys.FindByUser(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("FindByUser: %v", err)
+		}
+		checkIDs(t, "alice keys", apiKeyIDs(got), []int{first.ID, second.ID})
+
+		got, err = keys.FindByUser(ctx, 404)
+		if err != nil || got == nil || len(got) != 0 {
+			t.Errorf("FindByUser(missing) = %v, %v; want empty non-nil slice", got, err)
+		}
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		clk := &manualClock{now: created}
+		users, keys := factory(t, clk)
+		alice := mustCreateUser(t, users, "alice", "alice@example.com")
+		bob := mustCreateUser(t, users, "bob", "bob@example.com")
+		key := mustCreateAPIKey(t, keys, alice.ID, "ci", nil)
+
+		if _, err := keys.Revoke(ctx, bob.ID, key.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Revoke(other user) error = %v, want ErrNotFound", err)
+		}
+		revokedAt := created.Add(time.Hour)
+		clk.set(revokedAt)
+		revoked, err := keys.Revoke(ctx, alice.ID, key.ID)
+		if err != nil {
+			t.Fatalf("Revoke: %v", err)
+		}
+		if revoked.RevokedAt == nil || *revoked.RevokedAt != want(revokedAt) {
+			t.Errorf("RevokedAt = %v, want %v", revoked.RevokedAt, want(revokedAt))
+		}
+		if _, err := keys.Revoke(ctx, alice.ID, key.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("second Revoke error = %v, want ErrNotFound", err)
+		}
+		stored, err := keys.FindByHash(ctx, key.Hash)
+		if err != nil {
+			t.Fatalf("FindByHash: %v", err)
+		}
+		if stored.RevokedAt == nil {
+			t.Error("revocation was not stored")
+		}
+	})
+
+	t.Run("TouchLastUsed", func(t *testing.T) {
+		users, keys := factory(t, clock.Real{})
+		user := mustCreateUser(t, users, "alice", "alice@example.com")
+		key := mustCreateAPIKey(t, keys, user.ID, "ci", nil)
+
+		if err := keys.TouchLastUsed(ctx, key.ID, created); err != nil {
+			t.Fatalf("TouchLastUsed: %v", err)
+		}
+		stored, err := keys.FindByHash(ctx, key.Hash)
+		if err != nil {
+			t.Fatalf("FindByHash: %v", err)
+		}
+		if stored.LastUsedAt == nil || *stored.LastUsedAt != want(created) {
+			t.Errorf("LastUsedAt = %v, want %v", stored.LastUsedAt, want(created))
+		}
+		if err := keys.TouchLastUsed(ctx, 404, created); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("TouchLastUsed(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("DeletedWithUser", func(t *testing.T) {
+		users, keys := factory(t, clock.Real{})
+		user := mustCreateUser(t, users, "alice", "alice@example.com")
+		key := mustCreateAPIKey(t, keys, user.ID, "ci", nil)
+
+		if err := users.Delete(ctx, user.ID); err != nil {
+			t.Fatalf("delete user: %v", err)
+		}
+		if _, err := keys.FindByHash(ctx, key.Hash); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("FindByHash after user deletion error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func mustCreateAPIKey(t *testing.T, keys repository.APIKeyRepositoryInterface, userID int, name string, expiresAt *time.Time, scopes ...string) entity.APIKey {
+	t.Helper()
+	key, err := keys.Create(context.Background(), entity.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    "pk_" + name,
+		Hash:      []byte(name + "-hash"),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("create API key %q: %v", name, err)
+	}
+	return key
+}
+
+func apiKeyIDs(keys []entity.APIKey) []int {
+	ids := make([]int, 0, len(keys))
+	for _, key := range keys {
+		ids = append(ids, key.ID)
+	}
+	return ids
+}
@@ -0,0 +1,475 @@
+// Package repotest содержит контрактные тесты, которые обязана проходить
+// каждая реализация хранилища репозиториев.
+package repotest
+
+import (
+	"Projectapirest/internal/clock"
+	"Projectapirest/internal/entity"
+	"Projectapirest/internal/repository"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Factory возвращает репозитории поверх пустого хранилища, использующие
+// часы clk. Вызывается для каждого подтеста, поэтому подтесты не видят
+// данных друг друга.
+type Factory func(t *testing.T, clk clock.Clock) (repository.UserRepositoryInterface, repository.ProductRepositoryInterface)
+
+// Run прогоняет контракт UserRepositoryInterface и ProductRepositoryInterface.
+func Run(t *testing.T, factory Factory) {
+	newRepos := func(t *testing.T) (repository.UserRepositoryInterface, repository.ProductRepositoryInterface) {
+		return factory(t, clock.Real{})
+	}
+	t.Run("Users", func(t *testing.T) { runUsers(t, newRepos) })
+	t.Run("Products", func(t *testing.T) { runProducts(t, newRepos) })
+	t.Run("Timestamps", func(t *testing.T) { runTimestamps(t, factory) })
+}
+
+// reposFunc создает репозитории с системными часами.
+type reposFunc func(t *testing.T) (repository.UserRepositoryInterface, repository.ProductRepositoryInterface)
+
+func runUsers(t *testing.T, newRepos reposFunc) {
+	ctx := context.Background()
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		users, _ := newRepos(t)
+		created := mustCreateUser(t, users, "alice", "alice@example.com")
+		if created.ID == 0 {
+			t.Fatal("Create did not assign an ID")
+		}
+		got, err := users.FindByID(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if got.ID != created.ID || got.Name != "alice" || got.Email != "alice@example.com" {
+			t.Errorf("FindByID = %+v, want alice with ID %d", got, created.ID)
+		}
+		if got.CreatedAt.IsZero() || got.UpdatedAt.IsZero() {
+			t.Errorf("timestamps are not set: %+v", got)
+		}
+	})
+
+	t.Run("FindMissing", func(t *testing.T) {
+		users, _ := newRepos(t)
+		if _, err := users.FindByID(ctx, 404); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("FindByID(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		users, _ := newRepos(t)
+		created := mustCreateUser(t, users, "alice", "alice@example.com")
+		created.Name = "alice2"
+		created.Email = "alice2@example.com"
+		updated, err := users.Update(ctx, created)
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		got, err := users.FindByID(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if got.Name != "alice2" || got.Email != "alice2@example.com" {
+			t.Errorf("after Update = %+v", got)
+		}
+		if updated != got {
+			t.Errorf("Update returned %+v, stored %+v", updated, got)
+		}
+	})
+
+	t.Run("UpdateMissing", func(t *testing.T) {
+		users, _ := newRepos(t)
+		_, err := users.Update(ctx, entity.User{ID: 404, Name: "ghost", Email: "ghost@example.com"})
+		if !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Update(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("DeleteMissing", func(t *testing.T) {
+		users, _ := newRepos(t)
+		if err := users.Delete(ctx, 404); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Delete(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		users, _ := newRepos(t)
+		created := mustCreateUser(t, users, "alice", "alice@example.com")
+		if err := users.Delete(ctx, created.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := users.FindByID(ctx, created.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("FindByID after Delete error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("DuplicateEmail", func(t *testing.T) {
+		users, _ := newRepos(t)
+		mustCreateUser(t, users, "alice", "alice@example.com")
+		bob := mustCreateUser(t, users, "bob", "bob@example.com")
+
+		_, err := users.Create(ctx, entity.User{Name: "eve", Email: "alice@example.com"})
+		if !errors.Is(err, repository.ErrDuplicateEmail) {
+			t.Errorf("Create with taken email error = %v, want ErrDuplicateEmail", err)
+		}
+		bob.Email = "alice@example.com"
+		if _, err := users.Update(ctx, bob); !errors.Is(err, repository.ErrDuplicateEmail) {
+			t.Errorf("Update to taken email error = %v, want ErrDuplicateEmail", err)
+		}
+	})
+
+	t.Run("DeleteReferenced", func(t *testing.T) {
+		users, products := newRepos(t)
+		owner := mustCreateUser(t, users, "alice", "alice@example.com")
+		mustCreateProduct(t, products, "book", owner.ID)
+		if err := users.Delete(ctx, owner.ID); !errors.Is(err, repository.ErrUserReference) {
+			t.Errorf("Delete of product owner error = %v, want ErrUserReference", err)
+		}
+	})
+
+	t.Run("OrderingAndPagination", func(t *testing.T) {
+		users, _ := newRepos(t)
+		var ids []int
+		for _, name := range []string{"c", "a", "d", "b", "e"} {
+			ids = append(ids, mustCreateUser(t, users, name, name+"@example.com").ID)
+		}
+
+		all, err := users.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("FindAll: %v", err)
+		}
+		checkIDs(t, "FindAll", userIDs(all), ids)
+
+		for _, tc := range []struct {
+			limit, offset int
+			want          []int
+		}{
+			{limit: 2, offset: 0, want: ids[:2]},
+			{limit: 2, offset: 2, want: ids[2:4]},
+			{limit: 2, offset: 4, want: ids[4:]},
+			{limit: 10, offset: 5, want: nil},
+		} {
+			page, err := users.FindPage(ctx, tc.limit, tc.offset)
+			if err != nil {
+				t.Fatalf("FindPage(%d, %d): %v", tc.limit, tc.offset, err)
+			}
+			checkIDs(t, "FindPage", userIDs(page), tc.want)
+		}
+
+		if _, err := users.FindPage(ctx, 0, 0); !errors.Is(err, repository.ErrInvalidPage) {
+			t.Errorf("FindPage(0, 0) error = %v, want ErrInvalidPage", err)
+		}
+		if _, err := users.FindPage(ctx, 1, -1); !errors.Is(err, repository.ErrInvalidPage) {
+			t.Errorf("FindPage(1, -1) error = %v, want ErrInvalidPage", err)
+		}
+	})
+
+	t.Run("Cursor", func(t *testing.T) {
+		users, _ := newRepos(t)
+		var ids []int
+		for _, name := range []string{"a", "b", "c", "d"} {
+			ids = append(ids, mustCreateUser(t, users, name, name+"@example.com").ID)
+		}
+		// Удаление за курсором не сдвигает следующую страницу
+		if err := users.Delete(ctx, ids[1]); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+
+		for _, tc := range []struct {
+			after, limit int
+			want         []int
+		}{
+			{after: 0, limit: 2, want: []int{ids[0], ids[2]}},
+			{after: ids[0], limit: 2, want: ids[2:]},
+			{after: ids[2], limit: 10, want: ids[3:]},
+			{after: ids[3], limit: 10, want: nil},
+		} {
+			batch, err := users.FindAfter(ctx, tc.after, tc.limit)
+			if err != nil {
+				t.Fatalf("FindAfter(%d, %d): %v", tc.after, tc.limit, err)
+			}
+			checkIDs(t, "FindAfter", userIDs(batch), tc.want)
+		}
+
+		if _, err := users.FindAfter(ctx, 0, 0); !errors.Is(err, repository.ErrInvalidPage) {
+			t.Errorf("FindAfter(0, 0) error = %v, want ErrInvalidPage", err)
+		}
+	})
+}
+
+func runProducts(t *testing.T, newRepos reposFunc) {
+	ctx := context.Background()
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		users, products := newRepos(t)
+		owner := mustCreateUser(t, users, "alice", "alice@example.com")
+		created := mustCreateProduct(t, products, "book", owner.ID)
+		got, err := products.FindByID(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if got.Name != "book" || got.Description != "book description" || got.Price != 9.99 || got.UserID != owner.ID {
+			t.Errorf("FindByID = %+v", got)
+		}
+		if got.CreatedAt.IsZero() || got.UpdatedAt.IsZero() {
+			t.Errorf("timestamps are not set: %+v", got)
+		}
+		if locked, err := products.FindByIDForUpdate(ctx, created.ID); err != nil || locked != got {
+			t.Errorf("FindByIDForUpdate = %+v, %v, want %+v", locked, err, got)
+		}
+	})
+
+	t.Run("CreateWithoutOwner", func(t *testing.T) {
+		_, products := newRepos(t)
+		created := mustCreateProduct(t, products, "orphan", 0)
+		got, err := products.FindByID(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if got.UserID != 0 {
+			t.Errorf("UserID = %d, want 0", got.UserID)
+		}
+	})
+
+	t.Run("FindMissing", func(t *testing.T) {
+		_, products := newRepos(t)
+		if _, err := products.FindByID(ctx, 404); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("FindByID(missing) error = %v, want ErrNotFound", err)
+		}
+		if _, err := products.FindByIDForUpdate(ctx, 404); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("FindByIDForUpdate(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("ForeignKey", func(t *testing.T) {
+		_, products := newRepos(t)
+		_, err := products.Create(ctx, entity.Product{Name: "book", Price: 1, UserID: 404})
+		if !errors.Is(err, repository.ErrUserReference) {
+			t.Errorf("Create with missing owner error = %v, want ErrUserReference", err)
+		}
+		created := mustCreateProduct(t, products, "book", 0)
+		created.UserID = 404
+		if _, err := products.Update(ctx, created); !errors.Is(err, repository.ErrUserReference) {
+			t.Errorf("Update to missing owner error = %v, want ErrUserReference", err)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		users, products := newRepos(t)
+		owner := mustCreateUser(t, users, "alice", "alice@example.com")
+		created := mustCreateProduct(t, products, "book", 0)
+		created.Name = "ebook"
+		created.Description = "digital"
+		created.Price = 4.5
+		created.UserID = owner.ID
+		updated, err := products.Update(ctx, created)
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		got, err := products.FindByID(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if got.Name != "ebook" || got.Description != "digital" || got.Price != 4.5 || got.UserID != owner.ID {
+			t.Errorf("after Update = %+v", got)
+		}
+		if updated != got {
+			t.Errorf("Update returned %+v, stored %+v", updated, got)
+		}
+	})
+
+	t.Run("UpdateMissing", func(t *testing.T) {
+		_, products := newRepos(t)
+		_, err := products.Update(ctx, entity.Product{ID: 404, Name: "ghost", Price: 1})
+		if !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Update(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("DeleteMissing", func(t *testing.T) {
+		_, products := newRepos(t)
+		if err := products.Delete(ctx, 404); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Delete(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		_, products := newRepos(t)
+		created := mustCreateProduct(t, products, "book", 0)
+		if err := products.Delete(ctx, created.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := products.FindByID(ctx, created.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("FindByID after Delete error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("OrderingAndPagination", func(t *testing.T) {
+		_, products := newRepos(t)
+		var ids []int
+		for _, name := range []string{"c", "a", "b"} {
+			ids = append(ids, mustCreateProduct(t, products, name, 0).ID)
+		}
+
+		all, err := products.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("FindAll: %v", err)
+		}
+		checkIDs(t, "FindAll", productIDs(all), ids)
+
+		page, err := products.FindPage(ctx, 2, 1)
+		if err != nil {
+			t.Fatalf("FindPage: %v", err)
+		}
+		checkIDs(t, "FindPage", productIDs(page), ids[1:])
+
+		if _, err := products.FindPage(ctx, -1, 0); !errors.Is(err, repository.ErrInvalidPage) {
+			t.Errorf("FindPage(-1, 0) error = %v, want ErrInvalidPage", err)
+		}
+	})
+
+	t.Run("Cursor", func(t *testing.T) {
+		_, products := newRepos(t)
+		var ids []int
+		for _, name := range []string{"a", "b", "c"} {
+			ids = append(ids, mustCreateProduct(t, products, name, 0).ID)
+		}
+
+		first, err := products.FindAfter(ctx, 0, 2)
+		if err != nil {
+			t.Fatalf("FindAfter: %v", err)
+		}
+		checkIDs(t, "FindAfter", productIDs(first), ids[:2])
+		// Продукт, созданный во время обхода, попадает в следующую страницу
+		ids = append(ids, mustCreateProduct(t, products, "d", 0).ID)
+		rest, err := products.FindAfter(ctx, first[len(first)-1].ID, 10)
+		if err != nil {
+			t.Fatalf("FindAfter: %v", err)
+		}
+		checkIDs(t, "FindAfter", productIDs(rest), ids[2:])
+
+		if _, err := products.FindAfter(ctx, 0, -1); !errors.Is(err, repository.ErrInvalidPage) {
+			t.Errorf("FindAfter(0, -1) error = %v, want ErrInvalidPage", err)
+		}
+	})
+}
+
+// runTimestamps проверяет, что отметки времени берутся из часов репозитория,
+// хранятся в UTC с точностью до микросекунд и совпадают в ответе Create и при чтении.
+func runTimestamps(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	moscow := time.FixedZone("MSK", 3*60*60)
+	created := time.Date(2024, 3, 1, 15, 4, 5, 123456789, moscow)
+	updated := created.Add(time.Hour)
+	want := func(ts time.Time) time.Time { return ts.UTC().Truncate(time.Microsecond) }
+
+	clk := &manualClock{now: created}
+	users, products := factory(t, clk)
+
+	user := mustCreateUser(t, users, "alice", "alice@example.com")
+	product := mustCreateProduct(t, products, "book", user.ID)
+	checkTimes(t, "created user", user.CreatedAt, user.UpdatedAt, want(created), want(created))
+	checkTimes(t, "created product", product.CreatedAt, product.UpdatedAt, want(created), want(created))
+
+	clk.set(updated)
+	if _, err := users.Update(ctx, user); err != nil {
+		t.Fatalf("update user: %v", err)
+	}
+	if _, err := products.Update(ctx, product); err != nil {
+		t.Fatalf("update product: %v", err)
+	}
+
+	storedUser, err := users.FindByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("find user: %v", err)
+	}
+	checkTimes(t, "stored user", storedUser.CreatedAt, storedUser.UpdatedAt, want(created), want(updated))
+	storedProduct, err := products.FindByID(ctx, product.ID)
+	if err != nil {
+		t.Fatalf("find product: %v", err)
+	}
+	checkTimes(t, "stored product", storedProduct.CreatedAt, storedProduct.UpdatedAt, want(created), want(updated))
+}
+
+// checkTimes сравнивает отметки времени вместе с часовым поясом.
+func checkTimes(t *testing.T, what string, gotCreated, gotUpdated, wantCreated, wantUpdated time.Time) {
+	t.Helper()
+	if gotCreated != wantCreated || gotUpdated != wantUpdated {
+		t.Errorf("%s timestamps = %v / %v, want %v / %v", what, gotCreated, gotUpdated, wantCreated, wantUpdated)
+	}
+}
+
+// manualClock — часы, показания которых переставляет тест.
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+func mustCreateUser(t *testing.T, users repository.UserRepositoryInterface, name, email string) entity.User {
+	t.Helper()
+	user, err := users.Create(context.Background(), entity.User{Name: name, Email: email})
+	if err != nil {
+		t.Fatalf("create user %q: %v", name, err)
+	}
+	return user
+}
+
+func mustCreateProduct(t *testing.T, products repository.ProductRepositoryInterface, name string, userID int) entity.Product {
+	t.Helper()
+	product, err := products.Create(context.Background(), entity.Product{
+		Name:        name,
+		Description: name + " description",
+		Price:       9.99,
+		UserID:      userID,
+	})
+	if err != nil {
+		t.Fatalf("create product %q: %v", name, err)
+	}
+	return product
+}
+
+func userIDs(users []entity.User) []int {
+	ids := make([]int, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids
+}
+
+func productIDs(products []entity.Product) []int {
+	ids := make([]int, 0, len(products))
+	for _, product := range products {
+		ids = append(ids, product.ID)
+	}
+	return ids
+}
+
+// checkIDs сравнивает идентификаторы с учетом порядка.
+func checkIDs(t *testing.T, what string, got, want []int) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s IDs = %v, want %v", what, got, want)
+		return
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("%s IDs = %v, want %v", what, got, want)
+			return
+		}
+	}
+}
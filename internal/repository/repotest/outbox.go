@@ -0,0 +1,296 @@
+package repotest
+
+import (
+	"Projectapirest/internal/clock"
+	"Projectapirest/internal/entity"
+	"Projectapirest/internal/repository"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// OutboxFactory возвращает Transactor и репозитории пользователей и outbox
+// поверх общего пустого хранилища.
+type OutboxFactory func(t *testing.T, clk clock.Clock) (repository.Transactor, repository.UserRepositoryInterface, repository.OutboxRepositoryInterface)
+
+var errRollback = errors.New("rollback")
+
+// RunOutbox прогоняет контракт Transactor и OutboxRepositoryInterface.
+func RunOutbox(t *testing.T, factory OutboxFactory) {
+	ctx := context.Background()
+	start := time.Date(2024, 12, 22, 9, 0, 0, 0, time.UTC)
+	const lease = time.Minute
+
+	t.Run("CommitWritesEntityAndEvents", func(t *testing.T) {
+		transactor, users, outbox := factory(t, clock.Fixed(start))
+		var created entity.User
+		err := transactor.WithinTx(ctx, func(ctx context.Context, tx repository.Tx) error {
+			var err error
+			created, err = tx.Users.Create(ctx, entity.User{Name: "alice", Email: "alice@example.com"})
+			if err != nil {
+				return err
+			}
+			return tx.Outbox.Add(ctx, mustEvent(t, entity.EventUserCreated, created.ID, created, start))
+		})
+		if err != nil {
+			t.Fatalf("WithinTx: %v", err)
+		}
+		if _, err := users.FindByID(ctx, created.ID); err != nil {
+			t.Fatalf("FindByID after commit: %v", err)
+		}
+
+		events, err := outbox.Claim(ctx, 10, lease)
+		if err != nil {
+			t.Fatalf("Claim: %v", err)
+		}
+		if len(events) != 1 {
+			t.Fatalf("Claim returned %d events, want 1", len(events))
+		}
+		got := events[0]
+		if got.ID == 0 || got.Type != entity.EventUserCreated || got.AggregateType != entity.AggregateUser ||
+			got.AggregateID != created.ID || got.Attempts != 1 || !got.OccurredAt.Equal(start) {
+			t.Errorf("claimed event = %+v", got)
+		}
+		var payload entity.User
+		if err := json.Unmarshal(got.Payload, &payload); err != nil || payload.Email != "alice@example.com" {
+			t.Errorf("payload = %s (%v), want created user", got.Payload, err)
+		}
+	})
+
+	t.Run("RollbackDiscardsEntityAndEvents", func(t *testing.T) {
+		transactor, users, outbox := factory(t, clock.Fixed(start))
+		existing := mustCreateUser(t, users, "bob", "bob@example.com")
+
+		err := transactor.WithinTx(ctx, func(ctx context.Context, tx repository.Tx) error {
+			created, err := tx.Users.Create(ctx, entity.User{Name: "alice", Email: "alice@example.com"})
+			if err != nil {
+				return err
+			}
+			existing.Name = "robert"
+			if _, err := tx.Users.Update(ctx, existing); err != nil {
+				return err
+			}
+			if err := tx.Outbox.Add(ctx, mustEvent(t, entity.EventUserCreated, created.ID, created, start)); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			t.Fatalf("WithinTx error = %v, want errRollback", err)
+		}
+		all, err := users.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("FindAll: %v", err)
+		}
+		if len(all) != 1 || all[0].Name != "bob" {
+			t.Errorf("users after rollback = %+v, want only unchanged bob", all)
+		}
+		checkNoEvents(t, outbox)
+	})
+
+	t.Run("PanicRollsBack", func(t *testing.T) {
+		transactor, users, outbox := factory(t, clock.Fixed(start))
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("panic was not propagated")
+				}
+			}()
+			_ = transactor.WithinTx(ctx, func(ctx context.Context, tx repository.Tx) error {
+				created := mustCreateUser(t, tx.Users, "alice", "alice@example.com")
+				if err := tx.Outbox.Add(ctx, mustEvent(t, entity.EventUserCreated, created.ID, created, start)); err != nil {
+					t.Fatalf("Add: %v", err)
+				}
+				panic("boom")
+			})
+		}()
+		if all, _ := users.FindAll(ctx); len(all) != 0 {
+			t.Errorf("users after panic = %+v, want none", all)
+		}
+		checkNoEvents(t, outbox)
+	})
+
+	t.Run("LockedReadPreventsLostUpdates", func(t *testing.T) {
+		transactor, _, _ := factory(t, clock.Fixed(start))
+		var created entity.Product
+		err := transactor.WithinTx(ctx, func(ctx context.Context, tx repository.Tx) error {
+			created = mustCreateProduct(t, tx.Products, "book", 0)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WithinTx: %v", err)
+		}
+
+		// Каждый писатель читает цену с блокировкой и увеличивает ее на 1:
+		// без блокировки часть увеличений потерялась бы
+		const writers = 10
+		var wg sync.WaitGroup
+		errs := make(chan error, writers)
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- transactor.WithinTx(ctx, func(ctx context.Context, tx repository.Tx) error {
+					product, err := tx.Products.FindByIDForUpdate(ctx, created.ID)
+					if err != nil {
+						return err
+					}
+					product.Price++
+					_, err = tx.Products.Update(ctx, product)
+					return err
+				})
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Fatalf("WithinTx: %v", err)
+			}
+		}
+
+		var got entity.Product
+		err = transactor.WithinTx(ctx, func(ctx context.Context, tx repository.Tx) error {
+			got, err = tx.Products.FindByID(ctx, created.ID)
+			return err
+		})
+		if err != nil || got.Price != created.Price+writers {
+			t.Errorf("price = %v (%v), want %v", got.Price, err, created.Price+writers)
+		}
+	})
+
+	t.Run("ClaimLease", func(t *testing.T) {
+		clk := &manualClock{now: start}
+		_, _, outbox := factory(t, clk)
+		mustAdd(t, outbox, 3)
+
+		first, err := outbox.Claim(ctx, 2, lease)
+		if err != nil {
+			t.Fatalf("Claim: %v", err)
+		}
+		second, err := outbox.Claim(ctx, 10, lease)
+		if err != nil {
+			t.Fatalf("Claim: %v", err)
+		}
+		checkEvents(t, "first claim", first, []int64{1, 2}, 1)
+		checkEvents(t, "second claim", second, []int64{3}, 1)
+		checkNoEvents(t, outbox)
+
+		// Неподтвержденные события выдаются снова после окончания аренды
+		clk.set(start.Add(lease))
+		again, err := outbox.Claim(ctx, 10, lease)
+		if err != nil {
+			t.Fatalf("Claim: %v", err)
+		}
+		checkEvents(t, "claim after lease", again, []int64{1, 2, 3}, 2)
+
+		if _, err := outbox.Claim(ctx, 0, lease); !errors.Is(err, repository.ErrInvalidPage) {
+			t.Errorf("Claim(0) error = %v, want ErrInvalidPage", err)
+		}
+	})
+
+	t.Run("PublishAndRetry", func(t *testing.T) {
+		clk := &manualClock{now: start}
+		_, _, outbox := factory(t, clk)
+		mustAdd(t, outbox, 2)
+		if _, err := outbox.Claim(ctx, 10, lease); err != nil {
+			t.Fatalf("Claim: %v", err)
+		}
+
+		if err := outbox.MarkPublished(ctx, 1); err != nil {
+			t.Fatalf("MarkPublished: %v", err)
+		}
+		retryAt := start.Add(10 * time.Second)
+		if err := outbox.Retry(ctx, 2, retryAt, "connection refused"); err != nil {
+			t.Fatalf("Retry: %v", err)
+		}
+		if err := outbox.Retry(ctx, 1, retryAt, "late failure"); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Retry(published) error = %v, want ErrNotFound", err)
+		}
+
+		clk.set(retryAt)
+		events, err := outbox.Claim(ctx, 10, lease)
+		if err != nil {
+			t.Fatalf("Claim: %v", err)
+		}
+		checkEvents(t, "claim after retry", events, []int64{2}, 2)
+	})
+
+	t.Run("DeletePublished", func(t *testing.T) {
+		clk := &manualClock{now: start}
+		_, _, outbox := factory(t, clk)
+		mustAdd(t, outbox, 2)
+		if err := outbox.MarkPublished(ctx, 1); err != nil {
+			t.Fatalf("MarkPublished: %v", err)
+		}
+
+		for _, c := range []struct {
+			before time.Time
+			want   int
+		}{{start, 0}, {start.Add(time.Second), 1}, {start.Add(time.Hour), 0}} {
+			deleted, err := outbox.DeletePublished(ctx, c.before)
+			if err != nil {
+				t.Fatalf("DeletePublished: %v", err)
+			}
+			if deleted != c.want {
+				t.Errorf("DeletePublished(%v) = %d, want %d", c.before, deleted, c.want)
+			}
+		}
+		events, err := outbox.Claim(ctx, 10, lease)
+		if err != nil {
+			t.Fatalf("Claim: %v", err)
+		}
+		checkEvents(t, "pending after cleanup", events, []int64{2}, 1)
+	})
+}
+
+func mustEvent(t *testing.T, eventType entity.DomainEventType, userID int, payload interface{}, at time.Time) entity.DomainEvent {
+	t.Helper()
+	event, err := entity.NewDomainEvent(eventType, entity.AggregateUser, userID, payload, at)
+	if err != nil {
+		t.Fatalf("NewDomainEvent: %v", err)
+	}
+	return event
+}
+
+// mustAdd добавляет n событий удаления пользователей с ID от 1 до n.
+func mustAdd(t *testing.T, outbox repository.OutboxRepositoryInterface, n int) {
+	t.Helper()
+	for id := 1; id <= n; id++ {
+		event := mustEvent(t, entity.EventUserDeleted, id, entity.Deleted{ID: id}, time.Now())
+		if err := outbox.Add(context.Background(), event); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+}
+
+// checkEvents сравнивает ID выданных событий и их счетчик попыток.
+func checkEvents(t *testing.T, what string, events []entity.DomainEvent, wantIDs []int64, wantAttempts int) {
+	t.Helper()
+	ids := []int64{}
+	for _, event := range events {
+		ids = append(ids, event.ID)
+		if event.Attempts != wantAttempts {
+			t.Errorf("%s: event %d attempts = %d, want %d", what, event.ID, event.Attempts, wantAttempts)
+		}
+	}
+	if !reflect.DeepEqual(ids, wantIDs) {
+		t.Errorf("%s IDs = %v, want %v", what, ids, wantIDs)
+	}
+}
+
+// checkNoEvents проверяет, что в outbox нет событий, готовых к отправке.
+func checkNoEvents(t *testing.T, outbox repository.OutboxRepositoryInterface) {
+	t.Helper()
+	events, err := outbox.Claim(context.Background(), 10, time.Minute)
+	if err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("outbox has %d pending events, want none", len(events))
+	}
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TableSchema описывает таблицу и столбцы, которые использует репозиторий.
+type TableSchema struct {
+	Table   string
+	Columns []string
+}
+
+// Столбцы, к которым обращаются запросы репозиториев. При изменении SQL
+// в репозитории список нужно обновить, иначе проверка схемы не заметит расхождения.
+var (
+	userSchema = TableSchema{
+		Table:   "users",
+		Columns: []string{"id", "name", "email", "created_at", "updated_at"},
+	}
+	productSchema = TableSchema{
+		Table:   "products",
+		Columns: []string{"id", "name", "description", "price", "user_id", "created_at", "updated_at"},
+	}
+	apiKeySchema = TableSchema{
+		Table:   "api_keys",
+		Columns: []string{"id", "user_id", "name", "prefix", "key_hash", "scopes", "expires_at", "last_used_at", "revoked_at", "created_at"},
+	}
+	outboxSchema = TableSchema{
+		Table:   "outbox",
+		Columns: []string{"id", "event_type", "aggregate_type", "aggregate_id", "payload", "occurred_at", "attempts", "last_error", "available_at", "published_at"},
+	}
+)
+
+// ExpectedSchemas возвращает ожидания всех репозиториев PostgreSQL.
+func ExpectedSchemas() []TableSchema {
+	return []TableSchema{userSchema, productSchema, apiKeySchema, outboxSchema}
+}
+
+// SchemaDriftError перечисляет отсутствующие в базе таблицы и столбцы.
+type SchemaDriftError struct {
+	MissingTables  []string
+	MissingColumns map[string][]string // таблица -> столбцы
+}
+
+func (e *SchemaDriftError) Error() string {
+	var b strings.Builder
+	b.WriteString("database schema does not match repositories:")
+	for _, table := range e.MissingTables {
+		fmt.Fprintf(&b, "\n  table %q is missing", table)
+	}
+	tables := make([]string, 0, len(e.MissingColumns))
+	for table := range e.MissingColumns {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		fmt.Fprintf(&b, "\n  table %q is missing columns: %s", table, strings.Join(e.MissingColumns[table], ", "))
+	}
+	return b.String()
+}
+
+// CheckSchema сравнивает ожидания репозиториев со столбцами из information_schema
+// текущей схемы. При расхождении возвращает *SchemaDriftError с полным отчетом.
+func CheckSchema(ctx context.Context, db *sql.DB, schemas ...TableSchema) error {
+	if len(schemas) == 0 {
+		schemas = ExpectedSchemas()
+	}
+
+	rows, err := db.QueryContext(ctx, `
+        SELECT table_name, column_name
+        FROM information_schema.columns
+        WHERE table_schema = current_schema()`)
+	if err != nil {
+		return fmt.Errorf("read information_schema: %w", err)
+	}
+	defer rows.Close()
+
+	actual := make(map[string]map[string]bool)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return fmt.Errorf("read information_schema: %w", err)
+		}
+		if actual[table] == nil {
+			actual[table] = make(map[string]bool)
+		}
+		actual[table][column] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("read information_schema: %w", err)
+	}
+
+	drift := &SchemaDriftError{MissingColumns: make(map[string][]string)}
+	for _, schema := range schemas {
+		columns, ok := actual[schema.Table]
+		if !ok {
+			drift.MissingTables = append(drift.MissingTables, schema.Table)
+			continue
+		}
+		for _, column := range schema.Columns {
+			if !columns[column] {
+				drift.MissingColumns[schema.Table] = append(drift.MissingColumns[schema.Table], column)
+			}
+		}
+	}
+	if len(drift.MissingTables) > 0 || len(drift.MissingColumns) > 0 {
+		return drift
+	}
+	return nil
+}
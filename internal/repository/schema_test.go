@@ -0,0 +1,63 @@
+package repository_test
+
+import (
+	"Projectapirest/internal/migrate"
+	"Projectapirest/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+)
+
+// openTestDB подключается к PostgreSQL из TEST_DATABASE_URL и применяет миграции.
+// Без переменной окружения тест пропускается.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrate.New(db)
+	if err != nil {
+		t.Fatalf("prepare migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+	return db
+}
+
+func TestCheckSchemaMatchesMigrations(t *testing.T) {
+	db := openTestDB(t)
+	if err := repository.CheckSchema(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckSchemaReportsDrift(t *testing.T) {
+	db := openTestDB(t)
+	err := repository.CheckSchema(context.Background(), db,
+		repository.TableSchema{Table: "users", Columns: []string{"id", "password"}},
+		repository.TableSchema{Table: "product", Columns: []string{"id"}},
+	)
+
+	var drift *repository.SchemaDriftError
+	if !errors.As(err, &drift) {
+		t.Fatalf("expected SchemaDriftError, got %v", err)
+	}
+	if got := drift.MissingColumns["users"]; len(got) != 1 || got[0] != "password" {
+		t.Errorf("missing users columns = %v, want [password]", got)
+	}
+	if len(drift.MissingTables) != 1 || drift.MissingTables[0] != "product" {
+		t.Errorf("missing tables = %v, want [product]", drift.MissingTables)
+	}
+}
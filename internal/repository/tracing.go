@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("Projectapirest/internal/repository")
+
+// sqlLiteral находит плейсхолдеры, строковые и числовые литералы в SQL.
+var sqlLiteral = regexp.MustCompile(`\$\d+|'(?:[^']|'')*'|\b\d+(?:\.\d+)?\b`)
+
+// sanitizeSQL приводит запрос к виду, безопасному для трассировки:
+// схлопывает пробелы и заменяет литералы на "?". Плейсхолдеры $N сохраняются.
+func sanitizeSQL(query string) string {
+	query = strings.Join(strings.Fields(query), " ")
+	return sqlLiteral.ReplaceAllStringFunc(query, func(match string) string {
+		if strings.HasPrefix(match, "$") {
+			return match
+		}
+		return "?"
+	})
+}
+
+// startQuerySpan открывает span для запроса к PostgreSQL.
+// name имеет вид "ProductRepository.FindByID".
+func startQuerySpan(ctx context.Context, name, operation, table, query string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", operation),
+			attribute.String("db.sql.table", table),
+			attribute.String("db.statement", sanitizeSQL(query)),
+		),
+	)
+}
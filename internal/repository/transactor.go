@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"Projectapirest/internal/clock"
+	"Projectapirest/internal/tracing"
+	"context"
+	"database/sql"
+)
+
+// Tx содержит репозитории, работающие внутри одной транзакции.
+type Tx struct {
+	Users    UserRepositoryInterface
+	Products ProductRepositoryInterface
+	Outbox   OutboxRepositoryInterface
+}
+
+// Transactor выполняет fn в транзакции: изменения фиксируются, если fn вернула
+// nil, и откатываются при ошибке или панике. Внутри fn к хранилищу можно
+// обращаться только через репозитории tx.
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error
+}
+
+// dbtx — общие методы *sql.DB и *sql.Tx, через которые репозитории выполняют
+// запросы как вне транзакции, так и внутри нее.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// sqlTransactor реализует Transactor поверх транзакций PostgreSQL.
+type sqlTransactor struct {
+	db    *sql.DB
+	clock clock.Clock
+}
+
+// NewTransactor создает Transactor для репозиториев PostgreSQL.
+func NewTransactor(db *sql.DB, clk clock.Clock) Transactor {
+	return &sqlTransactor{db: db, clock: clk}
+}
+
+// WithinTx выполняет fn в транзакции PostgreSQL.
+func (t *sqlTransactor) WithinTx(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error {
+	ctx, span := tracer.Start(ctx, "Transactor.WithinTx")
+	defer span.End()
+
+	sqlTx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+	committed := false
+	defer func() {
+		// Откат выполняется и при панике в fn
+		if !committed {
+			_ = sqlTx.Rollback()
+		}
+	}()
+
+	err = fn(ctx, Tx{
+		Users:    &UserRepository{db: sqlTx, clock: t.clock},
+		Products: &ProductRepository{db: sqlTx, clock: t.clock},
+		Outbox:   &OutboxRepository{db: sqlTx, clock: t.clock},
+	})
+	if err != nil {
+		return err
+	}
+	if err := sqlTx.Commit(); err != nil {
+		return tracing.RecordError(span, err)
+	}
+	committed = true
+	return nil
+}
@@ -1,53 +1,65 @@
 package repository
 
 import (
+	"Projectapirest/internal/clock"
 	"Projectapirest/internal/entity"
+	"Projectapirest/internal/tracing"
 	"context"
 	"database/sql"
-	"time"
+	"errors"
 )
 
 // UserRepositoryInterface описывает методы работы с пользователями.
 type UserRepositoryInterface interface {
 	Create(ctx context.Context, user entity.User) (entity.User, error)
 	FindByID(ctx context.Context, id int) (entity.User, error)
-	Update(ctx context.Context, user entity.User) error
+	Update(ctx context.Context, user entity.User) (entity.User, error)
 	Delete(ctx context.Context, id int) error
 	FindAll(ctx context.Context) ([]entity.User, error)
+	// FindPage возвращает не более limit пользователей, пропустив первые offset.
+	FindPage(ctx context.Context, limit, offset int) ([]entity.User, error)
+	// FindAfter возвращает не более limit пользователей с ID больше afterID
+	// по возрастанию ID. Курсор не сбивается от вставок и удалений между вызовами.
+	FindAfter(ctx context.Context, afterID, limit int) ([]entity.User, error)
 }
 
 // UserRepository содержит ссылку на базу данных и реализует интерфейс UserRepositoryInterface.
 type UserRepository struct {
-	db *sql.DB
+	db    dbtx
+	clock clock.Clock
 }
 
 // NewUserRepository создает новый репозиторий пользователей.
-func NewUserRepository(db *sql.DB) UserRepositoryInterface {
-	return &UserRepository{db: db} // Возвращаем интерфейс
+func NewUserRepository(db *sql.DB, clk clock.Clock) UserRepositoryInterface {
+	return &UserRepository{db: db, clock: clk} // Возвращаем интерфейс
 }
 
 // Create добавляет нового пользователя в базу данных.
 func (r *UserRepository) Create(ctx context.Context, user entity.User) (entity.User, error) {
 	query := `
-        INSERT INTO users (name, email, password, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5) RETURNING id`
+        INSERT INTO users (name, email, created_at, updated_at)
+        VALUES ($1, $2, $3, $3) RETURNING id, created_at, updated_at`
+	ctx, span := startQuerySpan(ctx, "UserRepository.Create", "INSERT", "users", query)
+	defer span.End()
 	err := r.db.QueryRowContext(
 		ctx,
 		query,
 		user.Name,
 		user.Email,
-		time.Now(),
-		time.Now(),
-	).Scan(&user.ID)
+		r.clock.Now(),
+	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
-		return user, err
+		return user, tracing.RecordError(span, translateError(err))
 	}
+	normalizeTimes(&user.CreatedAt, &user.UpdatedAt)
 	return user, nil
 }
 
 // FindByID находит пользователя по ID.
 func (r *UserRepository) FindByID(ctx context.Context, id int) (entity.User, error) {
-	query := `SELECT id, name, email, password, created_at, updated_at FROM users WHERE id = $1`
+	query := `SELECT id, name, email, created_at, updated_at FROM users WHERE id = $1`
+	ctx, span := startQuerySpan(ctx, "UserRepository.FindByID", "SELECT", "users", query)
+	defer span.End()
 	var user entity.User
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&user.ID,
@@ -56,43 +68,100 @@ func (r *UserRepository) FindByID(ctx context.Context, id int) (entity.User, err
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, ErrNotFound
+	}
 	if err != nil {
-		return user, err
+		return user, tracing.RecordError(span, translateError(err))
 	}
+	normalizeTimes(&user.CreatedAt, &user.UpdatedAt)
 	return user, nil
 }
 
-// Update обновляет информацию о пользователе.
-func (r *UserRepository) Update(ctx context.Context, user entity.User) error {
+// Update обновляет информацию о пользователе и возвращает сохраненную запись.
+// Если пользователя нет, возвращается ErrNotFound.
+func (r *UserRepository) Update(ctx context.Context, user entity.User) (entity.User, error) {
 	query := `
         UPDATE users
-        SET name = $1, email = $2, password = $3, updated_at = $4
-        WHERE id = $5`
-	_, err := r.db.ExecContext(
+        SET name = $1, email = $2, updated_at = $3
+        WHERE id = $4
+        RETURNING id, name, email, created_at, updated_at`
+	ctx, span := startQuerySpan(ctx, "UserRepository.Update", "UPDATE", "users", query)
+	defer span.End()
+	var updated entity.User
+	err := r.db.QueryRowContext(
 		ctx,
 		query,
 		user.Name,
 		user.Email,
-		time.Now(),
+		r.clock.Now(),
 		user.ID,
+	).Scan(
+		&updated.ID,
+		&updated.Name,
+		&updated.Email,
+		&updated.CreatedAt,
+		&updated.UpdatedAt,
 	)
-	return err
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.User{}, ErrNotFound
+	}
+	if err != nil {
+		return entity.User{}, tracing.RecordError(span, translateError(err))
+	}
+	normalizeTimes(&updated.CreatedAt, &updated.UpdatedAt)
+	return updated, nil
 }
 
-// Delete удаляет пользователя из базы данных.
+// Delete удаляет пользователя из базы данных. Если пользователя нет,
+// возвращается ErrNotFound.
 func (r *UserRepository) Delete(ctx context.Context, id int) error {
 	query := `DELETE FROM users WHERE id = $1`
-	_, err := r.db.ExecContext(ctx, query, id)
-	return err
+	ctx, span := startQuerySpan(ctx, "UserRepository.Delete", "DELETE", "users", query)
+	defer span.End()
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return tracing.RecordError(span, translateError(err))
+	}
+	err = requireAffected(result)
+	if errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return tracing.RecordError(span, err)
 }
 
 // FindAll возвращает список всех пользователей.
 func (r *UserRepository) FindAll(ctx context.Context) ([]entity.User, error) {
-	query := `SELECT id, name, email, password, created_at, updated_at FROM users`
-	rows, err := r.db.QueryContext(ctx, query)
-	if err != nil {
+	query := `SELECT id, name, email, created_at, updated_at FROM users ORDER BY id`
+	return r.list(ctx, "UserRepository.FindAll", query)
+}
+
+// FindPage возвращает страницу пользователей, упорядоченных по ID.
+func (r *UserRepository) FindPage(ctx context.Context, limit, offset int) ([]entity.User, error) {
+	if err := validatePage(limit, offset); err != nil {
+		return nil, err
+	}
+	query := `SELECT id, name, email, created_at, updated_at FROM users ORDER BY id LIMIT $1 OFFSET $2`
+	return r.list(ctx, "UserRepository.FindPage", query, limit, offset)
+}
+
+// FindAfter возвращает пользователей после курсора afterID, упорядоченных по ID.
+func (r *UserRepository) FindAfter(ctx context.Context, afterID, limit int) ([]entity.User, error) {
+	if err := validatePage(limit, 0); err != nil {
 		return nil, err
 	}
+	query := `SELECT id, name, email, created_at, updated_at FROM users WHERE id > $1 ORDER BY id LIMIT $2`
+	return r.list(ctx, "UserRepository.FindAfter", query, afterID, limit)
+}
+
+// list выполняет выборку пользователей и сканирует строки результата.
+func (r *UserRepository) list(ctx context.Context, spanName, query string, args ...interface{}) ([]entity.User, error) {
+	ctx, span := startQuerySpan(ctx, spanName, "SELECT", "users", query)
+	defer span.End()
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, tracing.RecordError(span, translateError(err))
+	}
 	defer rows.Close()
 
 	users := []entity.User{}
@@ -106,9 +175,10 @@ func (r *UserRepository) FindAll(ctx context.Context) ([]entity.User, error) {
 			&user.UpdatedAt,
 		)
 		if err != nil {
-			return nil, err
+			return nil, tracing.RecordError(span, translateError(err))
 		}
+		normalizeTimes(&user.CreatedAt, &user.UpdatedAt)
 		users = append(users, user)
 	}
-	return users, nil
+	return users, tracing.RecordError(span, rows.Err())
 }
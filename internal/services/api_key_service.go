@@ -0,0 +1,255 @@
+package service
+
+import (
+	"Projectapirest/internal/auth"
+	"Projectapirest/internal/cache"
+	"Projectapirest/internal/entity"
+	"Projectapirest/internal/repository"
+	"Projectapirest/internal/tracing"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidAPIKeyRequest возвращается при пустом имени, неизвестной
+	// области доступа или сроке действия в прошлом.
+	ErrInvalidAPIKeyRequest = errors.New("некорректные параметры API-ключа")
+	// ErrForbidden возвращается, когда клиент запроса управляет чужими ключами
+	// или выдает ключ с областями доступа шире собственных.
+	ErrForbidden = errors.New("недостаточно прав")
+	// ErrAuthenticationRequired возвращается при управлении ключами без ключа.
+	ErrAuthenticationRequired = errors.New("требуется аутентификация")
+)
+
+// Параметры API-ключей.
+const (
+	apiKeyTokenPrefix = "pk_"
+	apiKeySecretBytes = 32
+	// apiKeyDisplayLen — длина префикса ключа, который хранится для показа.
+	apiKeyDisplayLen = len(apiKeyTokenPrefix) + 8
+	apiKeyMaxName    = 100
+	apiKeyTTL        = 60 * time.Second
+	// lastUsedInterval ограничивает частоту записи last_used_at, чтобы
+	// проверка ключа не обращалась к базе на каждый запрос.
+	lastUsedInterval = time.Minute
+)
+
+// APIKeyService описывает управление API-ключами и их проверку.
+type APIKeyService interface {
+	// Create выпускает ключ пользователю и возвращает его вместе с открытым
+	// значением, которое больше нигде не хранится.
+	Create(ctx context.Context, key entity.APIKey) (entity.APIKey, string, error)
+	List(ctx context.Context, userID int) ([]entity.APIKey, error)
+	Revoke(ctx context.Context, userID, id int) error
+	// Authenticate проверяет открытое значение ключа.
+	Authenticate(ctx context.Context, token string) (auth.Principal, error)
+}
+
+// apiKeyCacheKey возвращает ключ кеша проверенного API-ключа по его хешу.
+func apiKeyCacheKey(hash []byte) string {
+	return "apikey:" + hex.EncodeToString(hash)
+}
+
+// missingAPIKeyKey возвращает ключ негативной записи кеша для хеша.
+func missingAPIKeyKey(hash []byte) string {
+	return apiKeyCacheKey(hash) + ":missing"
+}
+
+// userAPIKeysTag помечает кешированные ключи пользователя, чтобы удаление
+// пользователя сбрасывало их разом.
+func userAPIKeysTag(userID int) string {
+	return fmt.Sprintf("user:%d:apikeys", userID)
+}
+
+// apiKeyService реализует интерфейс APIKeyService.
+type apiKeyService struct {
+	options
+	repo repository.APIKeyRepositoryInterface
+	key  *cache.TypedCache[entity.APIKey]
+}
+
+// NewAPIKeyService создает новый экземпляр apiKeyService.
+func NewAPIKeyService(repo repository.APIKeyRepositoryInterface, opts ...Option) APIKeyService {
+	o := newOptions(options{
+		entityTTL:  apiKeyTTL,
+		missingTTL: missingTTL,
+	}, opts)
+
+	return &apiKeyService{
+		options: o,
+		repo:    repo,
+		key:     cache.NewTypedCache[entity.APIKey](o.cache, cache.JSONCodec{}),
+	}
+}
+
+// Create выпускает новый ключ.
+func (s *apiKeyService) Create(ctx context.Context, key entity.APIKey) (entity.APIKey, string, error) {
+	ctx, span := tracer.Start(ctx, "apiKeyService.Create")
+	defer span.End()
+
+	if err := authorize(ctx, key.UserID, key.Scopes...); err != nil {
+		return entity.APIKey{}, "", err
+	}
+	key.Name = strings.TrimSpace(key.Name)
+	if key.Name == "" || len(key.Name) > apiKeyMaxName || len(key.Scopes) == 0 {
+		return entity.APIKey{}, "", ErrInvalidAPIKeyRequest
+	}
+	for _, scope := range key.Scopes {
+		if !auth.ValidScope(scope) {
+			return entity.APIKey{}, "", fmt.Errorf("%w: неизвестная область доступа %q", ErrInvalidAPIKeyRequest, scope)
+		}
+	}
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(s.clock.Now()) {
+		return entity.APIKey{}, "", fmt.Errorf("%w: срок действия уже истек", ErrInvalidAPIKeyRequest)
+	}
+
+	token, err := newAPIKeyToken()
+	if err != nil {
+		return entity.APIKey{}, "", tracing.RecordError(span, err)
+	}
+	key.Prefix = token[:apiKeyDisplayLen]
+	key.Hash = hashAPIKey(token)
+
+	created, err := s.repo.Create(ctx, key)
+	if errors.Is(err, repository.ErrUserReference) {
+		return entity.APIKey{}, "", errUserNotFound
+	}
+	if err != nil {
+		return entity.APIKey{}, "", tracing.RecordError(span, err)
+	}
+	return created, token, nil
+}
+
+// List возвращает ключи пользователя.
+func (s *apiKeyService) List(ctx context.Context, userID int) ([]entity.APIKey, error) {
+	ctx, span := tracer.Start(ctx, "apiKeyService.List")
+	defer span.End()
+
+	if err := authorize(ctx, userID); err != nil {
+		return nil, err
+	}
+	keys, err := s.repo.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+	return keys, nil
+}
+
+// Revoke отзывает ключ пользователя и убирает его из кеша.
+func (s *apiKeyService) Revoke(ctx context.Context, userID, id int) error {
+	ctx, span := tracer.Start(ctx, "apiKeyService.Revoke")
+	defer span.End()
+
+	if err := authorize(ctx, userID); err != nil {
+		return err
+	}
+	revoked, err := s.repo.Revoke(ctx, userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	cacheKey := apiKeyCacheKey(revoked.Hash)
+	if err := s.cache.Delete(ctx, cacheKey); err != nil {
+		s.log(ctx).WarnContext(ctx, "failed to delete cache", "key", cacheKey, "error", err)
+	}
+	return nil
+}
+
+// Authenticate проверяет ключ. Проверенные ключи кешируются, поэтому
+// обычный запрос не обращается к базе.
+func (s *apiKeyService) Authenticate(ctx context.Context, token string) (auth.Principal, error) {
+	ctx, span := tracer.Start(ctx, "apiKeyService.Authenticate")
+	defer span.End()
+
+	if !strings.HasPrefix(token, apiKeyTokenPrefix) {
+		return auth.Principal{}, auth.ErrUnauthenticated
+	}
+	hash := hashAPIKey(token)
+	cacheKey := apiKeyCacheKey(hash)
+
+	key, err := s.key.Get(ctx, cacheKey)
+	if err != nil {
+		if _, err := s.cache.Get(ctx, missingAPIKeyKey(hash)); err == nil {
+			return auth.Principal{}, auth.ErrUnauthenticated
+		}
+		key, err = s.repo.FindByHash(ctx, hash)
+		if errors.Is(err, repository.ErrNotFound) {
+			_ = s.cache.Set(ctx, missingAPIKeyKey(hash), []byte{1}, s.missingTTL)
+			return auth.Principal{}, auth.ErrUnauthenticated
+		}
+		if err != nil {
+			return auth.Principal{}, tracing.RecordError(span, err)
+		}
+		s.cacheAPIKey(ctx, cacheKey, key)
+	}
+
+	now := s.clock.Now()
+	if !key.Active(now) {
+		return auth.Principal{}, auth.ErrUnauthenticated
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedInterval {
+		if err := s.repo.TouchLastUsed(ctx, key.ID, now); err != nil {
+			s.log(ctx).WarnContext(ctx, "failed to update API key usage", "api_key_id", key.ID, "error", err)
+		} else {
+			key.LastUsedAt = &now
+			s.cacheAPIKey(ctx, cacheKey, key)
+		}
+	}
+
+	return auth.Principal{UserID: key.UserID, APIKeyID: key.ID, Scopes: key.Scopes}, nil
+}
+
+// cacheAPIKey сохраняет проверенный ключ с тегом пользователя.
+func (s *apiKeyService) cacheAPIKey(ctx context.Context, cacheKey string, key entity.APIKey) {
+	if err := s.key.Set(ctx, cacheKey, key, s.entityTTL, userAPIKeysTag(key.UserID)); err != nil {
+		s.log(ctx).WarnContext(ctx, "failed to set cache", "key", cacheKey, "error", err)
+	}
+}
+
+// authorize проверяет, что клиент запроса аутентифицирован, управляет своими
+// ключами и не выдает областей доступа шире собственных. Область admin
+// снимает оба ограничения. Проверка не зависит от AUTH_REQUIRED: без нее
+// анонимный клиент мог бы выпустить ключ любому пользователю.
+func authorize(ctx context.Context, userID int, scopes ...string) error {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return ErrAuthenticationRequired
+	}
+	if principal.HasScope(auth.ScopeAdmin) {
+		return nil
+	}
+	if principal.UserID != userID {
+		return ErrForbidden
+	}
+	for _, scope := range scopes {
+		if !principal.HasScope(scope) {
+			return ErrForbidden
+		}
+	}
+	return nil
+}
+
+// newAPIKeyToken генерирует открытое значение ключа.
+func newAPIKeyToken() (string, error) {
+	secret := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("generate API key: %w", err)
+	}
+	return apiKeyTokenPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// hashAPIKey возвращает SHA-256 открытого значения ключа.
+func hashAPIKey(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"Projectapirest/internal/auth"
+	"Projectapirest/internal/clock"
+	"Projectapirest/internal/entity"
+	"Projectapirest/internal/repository"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestAPIKeyService собирает сервис с часами, которые переставляет тест.
+func newTestAPIKeyService(repo repository.APIKeyRepositoryInterface, c *fakeCache, now *time.Time) APIKeyService {
+	return NewAPIKeyService(repo, WithCache(c), WithClock(clock.Func(func() time.Time { return *now })))
+}
+
+// asUser возвращает контекст клиента userID с областями scopes.
+func asUser(userID int, scopes ...string) context.Context {
+	return auth.NewContext(context.Background(), auth.Principal{UserID: userID, Scopes: scopes})
+}
+
+func TestAPIKeyServiceCreate(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		key       entity.APIKey
+		wantErr   error
+		wantCalls int
+	}{
+		{
+			name:      "valid key",
+			key:       entity.APIKey{UserID: 1, Name: "ci", Scopes: []string{auth.ScopeRead}},
+			wantCalls: 1,
+		},
+		{
+			name:      "admin issues key to another user",
+			ctx:       asUser(2, auth.ScopeAdmin),
+			key:       entity.APIKey{UserID: 1, Name: "ci", Scopes: []string{auth.ScopeRead, auth.ScopeWrite}},
+			wantCalls: 1,
+		},
+		{
+			name:    "anonymous",
+			ctx:     context.Background(),
+			key:     entity.APIKey{UserID: 1, Name: "ci", Scopes: []string{auth.ScopeRead}},
+			wantErr: ErrAuthenticationRequired,
+		},
+		{
+			name:    "empty name",
+			key:     entity.APIKey{UserID: 1, Name: " ", Scopes: []string{auth.ScopeRead}},
+			wantErr: ErrInvalidAPIKeyRequest,
+		},
+		{
+			name:    "no scopes",
+			key:     entity.APIKey{UserID: 1, Name: "ci"},
+			wantErr: ErrInvalidAPIKeyRequest,
+		},
+		{
+			name:    "unknown scope",
+			ctx:     asUser(1, auth.ScopeAdmin),
+			key:     entity.APIKey{UserID: 1, Name: "ci", Scopes: []string{"superuser"}},
+			wantErr: ErrInvalidAPIKeyRequest,
+		},
+		{
+			name:    "expired",
+			key:     entity.APIKey{UserID: 1, Name: "ci", Scopes: []string{auth.ScopeRead}, ExpiresAt: &past},
+			wantErr: ErrInvalidAPIKeyRequest,
+		},
+		{
+			name:      "missing user",
+			ctx:       asUser(1, auth.ScopeAdmin),
+			key:       entity.APIKey{UserID: 404, Name: "ci", Scopes: []string{auth.ScopeRead}},
+			wantErr:   repository.ErrNotFound,
+			wantCalls: 1,
+		},
+		{
+			name:    "key of another user",
+			ctx:     asUser(2, auth.ScopeWrite),
+			key:     entity.APIKey{UserID: 1, Name: "ci", Scopes: []string{auth.ScopeWrite}},
+			wantErr: ErrForbidden,
+		},
+		{
+			name:    "scope wider than caller",
+			ctx:     asUser(1, auth.ScopeWrite),
+			key:     entity.APIKey{UserID: 1, Name: "ci", Scopes: []string{auth.ScopeRead, auth.ScopeWrite}},
+			wantErr: ErrForbidden,
+		},
+		{
+			name:    "admin scope without admin",
+			ctx:     asUser(1, auth.ScopeRead, auth.ScopeWrite),
+			key:     entity.APIKey{UserID: 1, Name: "ci", Scopes: []string{auth.ScopeAdmin}},
+			wantErr: ErrForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = asUser(1, auth.ScopeRead, auth.ScopeWrite)
+			}
+			repo := newFakeAPIKeyRepo()
+			svc := newTestAPIKeyService(repo, newFakeCache(), &now)
+
+			created, token, err := svc.Create(ctx, tt.key)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got := repo.calls["Create"]; got != tt.wantCalls {
+				t.Errorf("repository calls = %d, want %d", got, tt.wantCalls)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if !strings.HasPrefix(token, created.Prefix) || created.Prefix == token {
+				t.Errorf("prefix %q does not abbreviate token %q", created.Prefix, token)
+			}
+			if string(created.Hash) != string(hashAPIKey(token)) {
+				t.Error("stored hash does not match token")
+			}
+		})
+	}
+}
+
+func TestAPIKeyServiceAuthenticate(t *testing.T) {
+	ctx := asUser(1, auth.ScopeRead, auth.ScopeWrite)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := newFakeCache()
+	repo := newFakeAPIKeyRepo()
+	svc := newTestAPIKeyService(repo, c, &now)
+
+	expires := now.Add(2 * time.Hour)
+	created, token, err := svc.Create(ctx, entity.APIKey{UserID: 1, Name: "ci", Scopes: []string{auth.ScopeRead}, ExpiresAt: &expires})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	principal, err := svc.Authenticate(ctx, token)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if principal.UserID != 1 || principal.APIKeyID != created.ID || !principal.HasScope(auth.ScopeRead) {
+		t.Errorf("principal = %+v", principal)
+	}
+	if !c.has(apiKeyCacheKey(created.Hash)) {
+		t.Error("validated key was not cached")
+	}
+
+	// Повторная проверка обслуживается из кеша, а last_used_at не переписывается чаще раза в минуту
+	now = now.Add(time.Second)
+	if _, err := svc.Authenticate(ctx, token); err != nil {
+		t.Fatalf("second Authenticate: %v", err)
+	}
+	if got := repo.calls["FindByHash"]; got != 1 {
+		t.Errorf("FindByHash calls = %d, want 1", got)
+	}
+	if got := repo.calls["TouchLastUsed"]; got != 1 {
+		t.Errorf("TouchLastUsed calls = %d, want 1", got)
+	}
+	now = now.Add(lastUsedInterval)
+	if _, err := svc.Authenticate(ctx, token); err != nil {
+		t.Fatalf("third Authenticate: %v", err)
+	}
+	if got := repo.calls["TouchLastUsed"]; got != 2 {
+		t.Errorf("TouchLastUsed calls = %d, want 2", got)
+	}
+
+	// Просроченный ключ отклоняется даже из кеша
+	now = expires
+	if _, err := svc.Authenticate(ctx, token); !errors.Is(err, auth.ErrUnauthenticated) {
+		t.Errorf("expired key error = %v, want ErrUnauthenticated", err)
+	}
+}
+
+func TestAPIKeyServiceAuthenticateRejectsUnknownKeys(t *testing.T) {
+	ctx := asUser(1, auth.ScopeRead, auth.ScopeWrite)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := newFakeCache()
+	repo := newFakeAPIKeyRepo()
+	svc := newTestAPIKeyService(repo, c, &now)
+
+	for _, token := range []string{"garbage", "pk_unknown", "pk_unknown"} {
+		if _, err := svc.Authenticate(ctx, token); !errors.Is(err, auth.ErrUnauthenticated) {
+			t.Errorf("Authenticate(%q) error = %v, want ErrUnauthenticated", token, err)
+		}
+	}
+	// Неизвестный ключ запоминается, а значения без префикса не доходят до базы
+	if got := repo.calls["FindByHash"]; got != 1 {
+		t.Errorf("FindByHash calls = %d, want 1", got)
+	}
+
+	repo.err = errBoom
+	if _, err := svc.Authenticate(ctx, "pk_other"); !errors.Is(err, errBoom) {
+		t.Errorf("repository error = %v, want errBoom", err)
+	}
+	if c.has(missingAPIKeyKey(hashAPIKey("pk_other"))) {
+		t.Error("infrastructure error was cached as missing key")
+	}
+}
+
+func TestAPIKeyServiceRevoke(t *testing.T) {
+	ctx := asUser(1, auth.ScopeRead, auth.ScopeWrite)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := newFakeCache()
+	repo := newFakeAPIKeyRepo()
+	svc := newTestAPIKeyService(repo, c, &now)
+
+	created, token, err := svc.Create(ctx, entity.APIKey{UserID: 1, Name: "ci", Scopes: []string{auth.ScopeRead}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Authenticate(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+
+	other := auth.NewContext(ctx, auth.Principal{UserID: 2, Scopes: []string{auth.ScopeWrite}})
+	if err := svc.Revoke(other, 1, created.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Revoke by another user error = %v, want ErrForbidden", err)
+	}
+	if err := svc.Revoke(ctx, 1, created.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if !equalStrings(c.deleted, []string{apiKeyCacheKey(created.Hash)}) {
+		t.Errorf("deleted keys = %v, want cached key", c.deleted)
+	}
+	if _, err := svc.Authenticate(ctx, token); !errors.Is(err, auth.ErrUnauthenticated) {
+		t.Errorf("revoked key error = %v, want ErrUnauthenticated", err)
+	}
+	if err := svc.Revoke(ctx, 1, created.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("second Revoke error = %v, want ErrNotFound", err)
+	}
+
+	keys, err := svc.List(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].RevokedAt == nil {
+		t.Errorf("List = %+v, want one revoked key", keys)
+	}
+}
+
+func TestAPIKeyServiceListAndRevokeRequireOwner(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+	}{
+		{name: "anonymous", ctx: context.Background(), wantErr: ErrAuthenticationRequired},
+		{name: "another user", ctx: asUser(2, auth.ScopeRead, auth.ScopeWrite), wantErr: ErrForbidden},
+		{name: "owner", ctx: asUser(1, auth.ScopeRead, auth.ScopeWrite)},
+		{name: "admin", ctx: asUser(2, auth.ScopeAdmin)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeAPIKeyRepo()
+			svc := newTestAPIKeyService(repo, newFakeCache(), &now)
+			created, _, err := svc.Create(asUser(1, auth.ScopeRead), entity.APIKey{UserID: 1, Name: "ci", Scopes: []string{auth.ScopeRead}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := svc.List(tt.ctx, 1); !errors.Is(err, tt.wantErr) {
+				t.Errorf("List error = %v, want %v", err, tt.wantErr)
+			}
+			if err := svc.Revoke(tt.ctx, 1, created.ID); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Revoke error = %v, want %v", err, tt.wantErr)
+			}
+			wantCalls := 0
+			if tt.wantErr == nil {
+				wantCalls = 1
+			}
+			if got := repo.calls["FindByUser"] + repo.calls["Revoke"]; got != 2*wantCalls {
+				t.Errorf("repository calls = %d, want %d", got, 2*wantCalls)
+			}
+		})
+	}
+}
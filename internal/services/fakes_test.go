@@ -0,0 +1,369 @@
+package service
+
+import (
+	"Projectapirest/internal/cache"
+	"Projectapirest/internal/clock"
+	"Projectapirest/internal/entity"
+	"Projectapirest/internal/repository"
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errBoom = errors.New("boom")
+
+// fakeCache — кеш в памяти, который запоминает удаления и инвалидации
+// и позволяет подставить ошибки чтения и записи.
+type fakeCache struct {
+	mu          sync.Mutex
+	data        map[string][]byte
+	tags        map[string][]string
+	ttls        map[string]time.Duration
+	deleted     []string
+	invalidated []string
+	getErr      error
+	setErr      error
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{
+		data: make(map[string][]byte),
+		tags: make(map[string][]string),
+		ttls: make(map[string]time.Duration),
+	}
+}
+
+func (c *fakeCache) Get(_ context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.getErr != nil {
+		return nil, c.getErr
+	}
+	value, ok := c.data[key]
+	if !ok {
+		return nil, cache.ErrMiss
+	}
+	return value, nil
+}
+
+func (c *fakeCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.SetWithTags(ctx, key, value, ttl)
+}
+
+func (c *fakeCache) SetWithTags(_ context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.setErr != nil {
+		return c.setErr
+	}
+	c.data[key] = value
+	c.ttls[key] = ttl
+	for _, tag := range tags {
+		c.tags[tag] = append(c.tags[tag], key)
+	}
+	return nil
+}
+
+func (c *fakeCache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.data, key)
+	c.deleted = append(c.deleted, key)
+	return nil
+}
+
+func (c *fakeCache) InvalidateTag(_ context.Context, tag string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range c.tags[tag] {
+		delete(c.data, key)
+	}
+	delete(c.tags, tag)
+	c.invalidated = append(c.invalidated, tag)
+	return nil
+}
+
+func (c *fakeCache) has(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.data[key]
+	return ok
+}
+
+func (c *fakeCache) put(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data[key] = value
+}
+
+// fakeUserRepo — репозиторий пользователей в памяти со счетчиком вызовов
+// и подставляемой ошибкой.
+type fakeUserRepo struct {
+	repository.UserRepositoryInterface
+	calls map[string]int
+	err   error
+}
+
+func newFakeUserRepo(users ...entity.User) *fakeUserRepo {
+	repo := &fakeUserRepo{
+		UserRepositoryInterface: repository.NewMemoryUserRepository(repository.NewMemoryStore(clock.Real{})),
+		calls:                   make(map[string]int),
+	}
+	for _, user := range users {
+		if _, err := repo.UserRepositoryInterface.Create(context.Background(), user); err != nil {
+			panic(err)
+		}
+	}
+	return repo
+}
+
+func (r *fakeUserRepo) call(method string) error {
+	r.calls[method]++
+	return r.err
+}
+
+func (r *fakeUserRepo) Create(ctx context.Context, user entity.User) (entity.User, error) {
+	if err := r.call("Create"); err != nil {
+		return entity.User{}, err
+	}
+	return r.UserRepositoryInterface.Create(ctx, user)
+}
+
+func (r *fakeUserRepo) FindByID(ctx context.Context, id int) (entity.User, error) {
+	if err := r.call("FindByID"); err != nil {
+		return entity.User{}, err
+	}
+	return r.UserRepositoryInterface.FindByID(ctx, id)
+}
+
+func (r *fakeUserRepo) Update(ctx context.Context, user entity.User) (entity.User, error) {
+	if err := r.call("Update"); err != nil {
+		return entity.User{}, err
+	}
+	return r.UserRepositoryInterface.Update(ctx, user)
+}
+
+func (r *fakeUserRepo) Delete(ctx context.Context, id int) error {
+	if err := r.call("Delete"); err != nil {
+		return err
+	}
+	return r.UserRepositoryInterface.Delete(ctx, id)
+}
+
+func (r *fakeUserRepo) FindAll(ctx context.Context) ([]entity.User, error) {
+	if err := r.call("FindAll"); err != nil {
+		return nil, err
+	}
+	return r.UserRepositoryInterface.FindAll(ctx)
+}
+
+func (r *fakeUserRepo) FindPage(ctx context.Context, limit, offset int) ([]entity.User, error) {
+	if err := r.call("FindPage"); err != nil {
+		return nil, err
+	}
+	return r.UserRepositoryInterface.FindPage(ctx, limit, offset)
+}
+
+func (r *fakeUserRepo) FindAfter(ctx context.Context, afterID, limit int) ([]entity.User, error) {
+	if err := r.call("FindAfter"); err != nil {
+		return nil, err
+	}
+	return r.UserRepositoryInterface.FindAfter(ctx, afterID, limit)
+}
+
+// fakeProductRepo — репозиторий продуктов в памяти со счетчиком вызовов
+// и подставляемой ошибкой.
+type fakeProductRepo struct {
+	repository.ProductRepositoryInterface
+	calls map[string]int
+	err   error
+}
+
+func newFakeProductRepo(products ...entity.Product) *fakeProductRepo {
+	repo := &fakeProductRepo{
+		ProductRepositoryInterface: repository.NewMemoryProductRepository(repository.NewMemoryStore(clock.Real{})),
+		calls:                      make(map[string]int),
+	}
+	for _, product := range products {
+		if _, err := repo.ProductRepositoryInterface.Create(context.Background(), product); err != nil {
+			panic(err)
+		}
+	}
+	return repo
+}
+
+func (r *fakeProductRepo) call(method string) error {
+	r.calls[method]++
+	return r.err
+}
+
+func (r *fakeProductRepo) Create(ctx context.Context, product entity.Product) (entity.Product, error) {
+	if err := r.call("Create"); err != nil {
+		return entity.Product{}, err
+	}
+	return r.ProductRepositoryInterface.Create(ctx, product)
+}
+
+func (r *fakeProductRepo) FindByID(ctx context.Context, id int) (entity.Product, error) {
+	if err := r.call("FindByID"); err != nil {
+		return entity.Product{}, err
+	}
+	return r.ProductRepositoryInterface.FindByID(ctx, id)
+}
+
+func (r *fakeProductRepo) FindByIDForUpdate(ctx context.Context, id int) (entity.Product, error) {
+	if err := r.call("FindByIDForUpdate"); err != nil {
+		return entity.Product{}, err
+	}
+	return r.ProductRepositoryInterface.FindByIDForUpdate(ctx, id)
+}
+
+func (r *fakeProductRepo) Update(ctx context.Context, product entity.Product) (entity.Product, error) {
+	if err := r.call("Update"); err != nil {
+		return entity.Product{}, err
+	}
+	return r.ProductRepositoryInterface.Update(ctx, product)
+}
+
+func (r *fakeProductRepo) Delete(ctx context.Context, id int) error {
+	if err := r.call("Delete"); err != nil {
+		return err
+	}
+	return r.ProductRepositoryInterface.Delete(ctx, id)
+}
+
+func (r *fakeProductRepo) FindAll(ctx context.Context) ([]entity.Product, error) {
+	if err := r.call("FindAll"); err != nil {
+		return nil, err
+	}
+	return r.ProductRepositoryInterface.FindAll(ctx)
+}
+
+func (r *fakeProductRepo) FindPage(ctx context.Context, limit, offset int) ([]entity.Product, error) {
+	if err := r.call("FindPage"); err != nil {
+		return nil, err
+	}
+	return r.ProductRepositoryInterface.FindPage(ctx, limit, offset)
+}
+
+func (r *fakeProductRepo) FindAfter(ctx context.Context, afterID, limit int) ([]entity.Product, error) {
+	if err := r.call("FindAfter"); err != nil {
+		return nil, err
+	}
+	return r.ProductRepositoryInterface.FindAfter(ctx, afterID, limit)
+}
+
+// sorted возвращает отсортированную копию среза строк для сравнения без учета порядка.
+func sorted(values []string) []string {
+	out := append([]string(nil), values...)
+	sort.Strings(out)
+	return out
+}
+
+func equalStrings(a, b []string) bool {
+	a, b = sorted(a), sorted(b)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// fakeAPIKeyRepo — репозиторий API-ключей в памяти со счетчиком вызовов
+// и подставляемой ошибкой. Хранилище содержит пользователей 1 и 2.
+type fakeAPIKeyRepo struct {
+	repository.APIKeyRepositoryInterface
+	calls map[string]int
+	err   error
+}
+
+func newFakeAPIKeyRepo() *fakeAPIKeyRepo {
+	store := repository.NewMemoryStore(clock.Real{})
+	users := repository.NewMemoryUserRepository(store)
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		if _, err := users.Create(context.Background(), entity.User{Name: email, Email: email}); err != nil {
+			panic(err)
+		}
+	}
+	return &fakeAPIKeyRepo{
+		APIKeyRepositoryInterface: repository.NewMemoryAPIKeyRepository(store),
+		calls:                     make(map[string]int),
+	}
+}
+
+func (r *fakeAPIKeyRepo) call(method string) error {
+	r.calls[method]++
+	return r.err
+}
+
+func (r *fakeAPIKeyRepo) Create(ctx context.Context, key entity.APIKey) (entity.APIKey, error) {
+	if err := r.call("Create"); err != nil {
+		return entity.APIKey{}, err
+	}
+	return r.APIKeyRepositoryInterface.Create(ctx, key)
+}
+
+func (r *fakeAPIKeyRepo) FindByHash(ctx context.Context, hash []byte) (entity.APIKey, error) {
+	if err := r.call("FindByHash"); err != nil {
+		return entity.APIKey{}, err
+	}
+	return r.APIKeyRepositoryInterface.FindByHash(ctx, hash)
+}
+
+func (r *fakeAPIKeyRepo) FindByUser(ctx context.Context, userID int) ([]entity.APIKey, error) {
+	if err := r.call("FindByUser"); err != nil {
+		return nil, err
+	}
+	return r.APIKeyRepositoryInterface.FindByUser(ctx, userID)
+}
+
+func (r *fakeAPIKeyRepo) Revoke(ctx context.Context, userID, id int) (entity.APIKey, error) {
+	if err := r.call("Revoke"); err != nil {
+		return entity.APIKey{}, err
+	}
+	return r.APIKeyRepositoryInterface.Revoke(ctx, userID, id)
+}
+
+func (r *fakeAPIKeyRepo) TouchLastUsed(ctx context.Context, id int, at time.Time) error {
+	if err := r.call("TouchLastUsed"); err != nil {
+		return err
+	}
+	return r.APIKeyRepositoryInterface.TouchLastUsed(ctx, id, at)
+}
+
+// claimEvents забирает все ожидающие отправки события outbox.
+func claimEvents(t *testing.T, outbox repository.OutboxRepositoryInterface) []entity.DomainEvent {
+	t.Helper()
+	events, err := outbox.Claim(context.Background(), 100, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+// eventTypes возвращает виды событий в порядке записи.
+func eventTypes(events []entity.DomainEvent) []entity.DomainEventType {
+	types := []entity.DomainEventType{}
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+// mustJSON сериализует значение для записи в кеш напрямую.
+func mustJSON(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
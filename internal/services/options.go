@@ -0,0 +1,112 @@
+package service
+
+import (
+	"Projectapirest/internal/cache"
+	"Projectapirest/internal/clock"
+	"Projectapirest/internal/entity"
+	"Projectapirest/internal/logger"
+	"Projectapirest/internal/repository"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Option настраивает сервис при создании.
+type Option func(*options)
+
+// options содержит зависимости и параметры кеширования сервиса.
+type options struct {
+	cache      cache.Cache
+	logger     *slog.Logger
+	clock      clock.Clock
+	entityTTL  time.Duration // время жизни записи одной сущности
+	listTTL    time.Duration // время жизни списков
+	missingTTL time.Duration // время жизни негативных записей
+	// productEvents получает события изменений продуктов; nil — не отправлять
+	productEvents func(entity.ProductEvent)
+	// outbox сохраняет доменные события в одной транзакции с изменением; nil — не сохранять
+	outbox repository.Transactor
+}
+
+// WithCache задает кеш сервиса. Без него используется отдельный кеш в памяти.
+func WithCache(c cache.Cache) Option {
+	return func(o *options) { o.cache = c }
+}
+
+// WithLogger задает логгер для вызовов без логгера запроса в контексте.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) { o.logger = l }
+}
+
+// WithClock задает часы, по которым сервис проверяет сроки действия.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) { o.clock = c }
+}
+
+// WithEntityTTL задает время жизни кешированной сущности.
+func WithEntityTTL(ttl time.Duration) Option {
+	return func(o *options) { o.entityTTL = ttl }
+}
+
+// WithListTTL задает время жизни кешированных списков.
+func WithListTTL(ttl time.Duration) Option {
+	return func(o *options) { o.listTTL = ttl }
+}
+
+// WithMissingTTL задает время жизни негативных записей об отсутствующих ID.
+func WithMissingTTL(ttl time.Duration) Option {
+	return func(o *options) { o.missingTTL = ttl }
+}
+
+// WithProductEvents задает получателя событий об изменениях продуктов,
+// например рассылку для потоков WatchProducts. Вызов не должен блокироваться.
+func WithProductEvents(publish func(entity.ProductEvent)) Option {
+	return func(o *options) { o.productEvents = publish }
+}
+
+// WithOutbox включает доменные события: изменения сущностей выполняются
+// в транзакциях transactor вместе с записью событий в outbox. transactor
+// должен работать с тем же хранилищем, что и репозиторий сервиса.
+func WithOutbox(transactor repository.Transactor) Option {
+	return func(o *options) { o.outbox = transactor }
+}
+
+// newOptions применяет opts поверх значений по умолчанию конкретного сервиса.
+func newOptions(defaults options, opts []Option) options {
+	o := defaults
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.cache == nil {
+		o.cache = cache.NewInMemoryCache()
+	}
+	if o.logger == nil {
+		o.logger = slog.Default()
+	}
+	if o.clock == nil {
+		o.clock = clock.Real{}
+	}
+	return o
+}
+
+// log возвращает логгер запроса, а без него — логгер сервиса.
+func (o *options) log(ctx context.Context) *slog.Logger {
+	return logger.FromContextOr(ctx, o.logger)
+}
+
+// write выполняет change и сохраняет возвращенные события в outbox в той же
+// транзакции. Без outbox change получает direct — репозитории вне транзакции
+// с пустым Outbox, а события отбрасываются.
+func (o *options) write(ctx context.Context, direct repository.Tx, change func(ctx context.Context, tx repository.Tx) ([]entity.DomainEvent, error)) error {
+	if o.outbox == nil {
+		_, err := change(ctx, direct)
+		return err
+	}
+	return o.outbox.WithinTx(ctx, func(ctx context.Context, tx repository.Tx) error {
+		events, err := change(ctx, tx)
+		if err != nil {
+			return err
+		}
+		return tx.Outbox.Add(ctx, events...)
+	})
+}
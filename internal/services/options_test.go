@@ -0,0 +1,76 @@
+package service
+
+import (
+	"Projectapirest/internal/entity"
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOptionsApplyToServices(t *testing.T) {
+	ctx := context.Background()
+	opts := func(c *fakeCache, logs *bytes.Buffer) []Option {
+		return []Option{
+			WithCache(c),
+			WithLogger(slog.New(slog.NewTextHandler(logs, nil))),
+			WithEntityTTL(time.Minute),
+			WithListTTL(2 * time.Minute),
+			WithMissingTTL(3 * time.Second),
+		}
+	}
+
+	t.Run("users", func(t *testing.T) {
+		c, logs := newFakeCache(), &bytes.Buffer{}
+		svc := NewUserService(newFakeUserRepo(), opts(c, logs)...)
+
+		created, err := svc.Create(ctx, entity.User{Name: "alice", Email: "alice@example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = svc.FindByID(ctx, created.ID)
+		_, _ = svc.FindByID(ctx, 404)
+		_, _ = svc.FindAll(ctx)
+		checkTTLs(t, c, map[string]time.Duration{
+			"user:1":            time.Minute,
+			missingUserKey(404): 3 * time.Second,
+			"users:all":         2 * time.Minute,
+		})
+
+		c.setErr = errBoom
+		c.put("users:all", []byte("garbage"))
+		_, _ = svc.FindAll(ctx)
+		if !strings.Contains(logs.String(), "failed to set cache") {
+			t.Errorf("service logger was not used, logs: %q", logs.String())
+		}
+	})
+
+	t.Run("products", func(t *testing.T) {
+		c, logs := newFakeCache(), &bytes.Buffer{}
+		svc := NewProductService(newFakeProductRepo(), opts(c, logs)...)
+
+		created, err := svc.Create(ctx, entity.Product{Name: "book", Price: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = svc.FindByID(ctx, created.ID)
+		_, _ = svc.FindByID(ctx, 404)
+		_, _ = svc.FindAll(ctx)
+		checkTTLs(t, c, map[string]time.Duration{
+			"product:1":            time.Minute,
+			missingProductKey(404): 3 * time.Second,
+			"products:all":         2 * time.Minute,
+		})
+	})
+}
+
+func checkTTLs(t *testing.T, c *fakeCache, want map[string]time.Duration) {
+	t.Helper()
+	for key, ttl := range want {
+		if got := c.ttls[key]; got != ttl {
+			t.Errorf("TTL of %s = %v, want %v", key, got, ttl)
+		}
+	}
+}
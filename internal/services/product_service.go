@@ -4,8 +4,10 @@ import (
 	"Projectapirest/internal/cache"
 	"Projectapirest/internal/entity"
 	"Projectapirest/internal/repository"
+	"Projectapirest/internal/tracing"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -18,6 +20,13 @@ type ProductService interface {
 	Update(ctx context.Context, product entity.Product) (entity.Product, error)
 	Delete(ctx context.Context, id int) error
 	FindAll(ctx context.Context) ([]entity.Product, error)
+	// FindPage возвращает не более limit записей, пропустив первые offset.
+	FindPage(ctx context.Context, limit, offset int) ([]entity.Product, error)
+	// FindByUser возвращает продукты пользователя в порядке полного списка.
+	FindByUser(ctx context.Context, userID int) ([]entity.Product, error)
+	// FindAfter возвращает не более limit продуктов с ID больше afterID в обход
+	// кеша; используется для потоковой выдачи.
+	FindAfter(ctx context.Context, afterID, limit int) ([]entity.Product, error)
 }
 
 // DecodeRequestBody десериализует тело запроса в структуру.
@@ -33,119 +42,302 @@ func EncodeResponse(w http.ResponseWriter, v interface{}, statusCode int) {
 	json.NewEncoder(w).Encode(v)
 }
 
+// productsTag помечает в кеше все списки продуктов, чтобы одна запись
+// инвалидировала их разом.
+const productsTag = "products"
+
+// userProductsTag возвращает тег списков продуктов конкретного пользователя.
+func userProductsTag(userID int) string {
+	return fmt.Sprintf("user:%d:products", userID)
+}
+
+// Время жизни записей кеша продуктов по умолчанию.
+const (
+	productTTL  = 300 * time.Second
+	productsTTL = 300 * time.Second
+	// missingTTL — время жизни негативных записей об отсутствующих ID.
+	missingTTL = 30 * time.Second
+)
+
+// missingProductKey возвращает ключ негативной записи кеша для продукта.
+func missingProductKey(id int) string {
+	return fmt.Sprintf("product:%d:missing", id)
+}
+
 // productService реализует интерфейс ProductService.
 type productService struct {
-	repo  repository.ProductRepositoryInterface
-	cache cache.Cache
+	options
+	repo     repository.ProductRepositoryInterface
+	product  *cache.TypedCache[entity.Product]
+	products *cache.TypedCache[[]entity.Product]
 }
 
 // NewProductService создает новый экземпляр productService.
-func NewProductService(repo repository.ProductRepositoryInterface, inMemoryCache, redisCache cache.Cache) ProductService {
-	// Создаем многослойный кеш
-	multiCache := cache.NewMultiLevelCache(inMemoryCache, redisCache)
+func NewProductService(repo repository.ProductRepositoryInterface, opts ...Option) ProductService {
+	o := newOptions(options{
+		entityTTL:  productTTL,
+		listTTL:    productsTTL,
+		missingTTL: missingTTL,
+	}, opts)
 
 	return &productService{
-		repo:  repo,
-		cache: multiCache,
+		options:  o,
+		repo:     repo,
+		product:  cache.NewTypedCache[entity.Product](o.cache, cache.JSONCodec{}),
+		products: cache.NewTypedCache[[]entity.Product](o.cache, cache.JSONCodec{}),
 	}
 }
 
 // Create добавляет новый продукт.
 func (s *productService) Create(ctx context.Context, product entity.Product) (entity.Product, error) {
-	product.CreatedAt = time.Now()
-	product.UpdatedAt = time.Now()
+	ctx, span := tracer.Start(ctx, "productService.Create")
+	defer span.End()
 
-	createdProduct, err := s.repo.Create(ctx, product)
+	var createdProduct entity.Product
+	err := s.write(ctx, repository.Tx{Products: s.repo}, func(ctx context.Context, tx repository.Tx) ([]entity.DomainEvent, error) {
+		var err error
+		createdProduct, err = tx.Products.Create(ctx, product)
+		if err != nil {
+			return nil, err
+		}
+		event, err := entity.NewDomainEvent(entity.EventProductCreated, entity.AggregateProduct, createdProduct.ID, createdProduct, createdProduct.CreatedAt)
+		return []entity.DomainEvent{event}, err
+	})
 	if err != nil {
-		return entity.Product{}, err
+		return entity.Product{}, tracing.RecordError(span, err)
 	}
 
-	// Инвалидация кеша списка продуктов
-	_ = s.cache.Delete("products:all")
+	// Инвалидация негативной записи и кеша списков продуктов
+	_ = s.cache.Delete(ctx, missingProductKey(createdProduct.ID))
+	_ = s.cache.InvalidateTag(ctx, productsTag)
+	_ = s.cache.InvalidateTag(ctx, userProductsTag(createdProduct.UserID))
 
+	s.notify(entity.ProductCreated, createdProduct, createdProduct.CreatedAt)
 	return createdProduct, nil
 }
 
 // FindByID находит продукт по ID.
 func (s *productService) FindByID(ctx context.Context, id int) (entity.Product, error) {
+	ctx, span := tracer.Start(ctx, "productService.FindByID")
+	defer span.End()
+
 	cacheKey := fmt.Sprintf("product:%d", id)
 
 	// Попытка извлечь из кеша
-	if cached, err := s.cache.Get(cacheKey); err == nil && cached != "" {
-		var product entity.Product
-		if err := json.Unmarshal([]byte(cached), &product); err == nil {
-			return product, nil
-		}
+	if product, err := s.product.Get(ctx, cacheKey); err == nil {
+		return product, nil
+	}
+	if _, err := s.cache.Get(ctx, missingProductKey(id)); err == nil {
+		return entity.Product{}, repository.ErrNotFound
 	}
 
 	// Если кеш пуст, получаем из базы
 	product, err := s.repo.FindByID(ctx, id)
-	if err != nil {
+	if errors.Is(err, repository.ErrNotFound) {
+		// Запоминаем отсутствие продукта; ошибки инфраструктуры не кешируются
+		_ = s.cache.Set(ctx, missingProductKey(id), []byte{1}, s.missingTTL)
 		return entity.Product{}, err
 	}
+	if err != nil {
+		return entity.Product{}, tracing.RecordError(span, err)
+	}
 
 	// Сохраняем результат в кеш
-	productJSON, _ := json.Marshal(product)
-	if err := s.cache.Set(cacheKey, string(productJSON), 300); err != nil {
-		fmt.Printf("Failed to set cache for FindByID: %v\n", err)
+	if err := s.product.Set(ctx, cacheKey, product, s.entityTTL); err != nil {
+		s.log(ctx).WarnContext(ctx, "failed to set cache", "key", cacheKey, "error", err)
 	}
 
 	return product, nil
 }
 
-// Update обновляет информацию о продукте.
+// Update обновляет информацию о продукте и возвращает сохраненное состояние.
 func (s *productService) Update(ctx context.Context, product entity.Product) (entity.Product, error) {
-	product.UpdatedAt = time.Now()
+	ctx, span := tracer.Start(ctx, "productService.Update")
+	defer span.End()
 
-	err := s.repo.Update(ctx, product)
-	if err != nil {
+	var updated entity.Product
+	err := s.write(ctx, repository.Tx{Products: s.repo}, func(ctx context.Context, tx repository.Tx) ([]entity.DomainEvent, error) {
+		// Прежняя цена нужна только для события, поэтому без outbox не читается.
+		// Строка блокируется до записи, иначе параллельное изменение между
+		// чтением и Update дало бы событие с неверной прежней ценой
+		var previous entity.Product
+		if tx.Outbox != nil {
+			var err error
+			if previous, err = tx.Products.FindByIDForUpdate(ctx, product.ID); err != nil {
+				return nil, err
+			}
+		}
+		var err error
+		updated, err = tx.Products.Update(ctx, product)
+		if err != nil {
+			return nil, err
+		}
+		return productUpdateEvents(previous, updated)
+	})
+	if errors.Is(err, repository.ErrNotFound) {
 		return entity.Product{}, err
 	}
+	if err != nil {
+		return entity.Product{}, tracing.RecordError(span, err)
+	}
 
-	// Инвалидация кеша продукта и списка продуктов
-	_ = s.cache.Delete(fmt.Sprintf("product:%d", product.ID))
-	_ = s.cache.Delete("products:all")
+	// Кешируем сохраненное состояние; если запись не удалась, удаляем
+	// ключ, чтобы не отдавать устаревшие данные
+	cacheKey := fmt.Sprintf("product:%d", updated.ID)
+	if err := s.product.Set(ctx, cacheKey, updated, s.entityTTL); err != nil {
+		s.log(ctx).WarnContext(ctx, "failed to set cache", "key", cacheKey, "error", err)
+		_ = s.cache.Delete(ctx, cacheKey)
+	}
+	_ = s.cache.InvalidateTag(ctx, productsTag)
+	_ = s.cache.InvalidateTag(ctx, userProductsTag(updated.UserID))
 
-	return product, nil
+	s.notify(entity.ProductUpdated, updated, updated.UpdatedAt)
+	return updated, nil
 }
 
 // Delete удаляет продукт.
 func (s *productService) Delete(ctx context.Context, id int) error {
-	err := s.repo.Delete(ctx, id)
-	if err != nil {
+	ctx, span := tracer.Start(ctx, "productService.Delete")
+	defer span.End()
+
+	deletedAt := s.clock.Now().UTC()
+	err := s.write(ctx, repository.Tx{Products: s.repo}, func(ctx context.Context, tx repository.Tx) ([]entity.DomainEvent, error) {
+		if err := tx.Products.Delete(ctx, id); err != nil {
+			return nil, err
+		}
+		event, err := entity.NewDomainEvent(entity.EventProductDeleted, entity.AggregateProduct, id, entity.Deleted{ID: id}, deletedAt)
+		return []entity.DomainEvent{event}, err
+	})
+	if errors.Is(err, repository.ErrNotFound) {
 		return err
 	}
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
 
-	// Инвалидация кеша продукта и списка продуктов
-	_ = s.cache.Delete(fmt.Sprintf("product:%d", id))
-	_ = s.cache.Delete("products:all")
+	// Инвалидация кеша продукта и списков продуктов
+	_ = s.cache.Delete(ctx, fmt.Sprintf("product:%d", id))
+	_ = s.cache.InvalidateTag(ctx, productsTag)
 
+	s.notify(entity.ProductDeleted, entity.Product{ID: id}, deletedAt)
 	return nil
 }
 
 // FindAll возвращает всех продуктов.
 func (s *productService) FindAll(ctx context.Context) ([]entity.Product, error) {
+	ctx, span := tracer.Start(ctx, "productService.FindAll")
+	defer span.End()
+
 	cacheKey := "products:all"
 
 	// Попытка извлечь данные из кеша
-	if cached, err := s.cache.Get(cacheKey); err == nil && cached != "" {
-		var products []entity.Product
-		if err := json.Unmarshal([]byte(cached), &products); err == nil {
-			return products, nil
-		}
+	if products, err := s.products.Get(ctx, cacheKey); err == nil {
+		return products, nil
 	}
 
 	// Если кеш пуст, извлекаем данные из репозитория
 	products, err := s.repo.FindAll(ctx)
 	if err != nil {
-		return nil, err
+		return nil, tracing.RecordError(span, err)
 	}
 
 	// Сохраняем результат в кеш
-	productsJSON, _ := json.Marshal(products)
-	if err := s.cache.Set(cacheKey, string(productsJSON), 300); err != nil {
-		fmt.Printf("Failed to set cache for FindAll: %v\n", err)
+	if err := s.products.Set(ctx, cacheKey, products, s.listTTL, productsTag); err != nil {
+		s.log(ctx).WarnContext(ctx, "failed to set cache", "key", cacheKey, "error", err)
 	}
 
 	return products, nil
 }
+
+// FindPage возвращает страницу продуктов. Страницы кешируются с тем же тегом,
+// что и полный список, поэтому сбрасываются вместе с ним.
+func (s *productService) FindPage(ctx context.Context, limit, offset int) ([]entity.Product, error) {
+	ctx, span := tracer.Start(ctx, "productService.FindPage")
+	defer span.End()
+
+	cacheKey := fmt.Sprintf("products:page:%d:%d", limit, offset)
+
+	if products, err := s.products.Get(ctx, cacheKey); err == nil {
+		return products, nil
+	}
+
+	products, err := s.repo.FindPage(ctx, limit, offset)
+	if errors.Is(err, repository.ErrInvalidPage) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	if err := s.products.Set(ctx, cacheKey, products, s.listTTL, productsTag); err != nil {
+		s.log(ctx).WarnContext(ctx, "failed to set cache", "key", cacheKey, "error", err)
+	}
+
+	return products, nil
+}
+
+// FindByUser возвращает продукты пользователя, выбирая их из полного списка.
+// Результат кешируется с тегом пользователя и общим тегом списков, поэтому
+// сбрасывается и при изменении продуктов, и при удалении пользователя.
+func (s *productService) FindByUser(ctx context.Context, userID int) ([]entity.Product, error) {
+	ctx, span := tracer.Start(ctx, "productService.FindByUser")
+	defer span.End()
+
+	cacheKey := fmt.Sprintf("products:user:%d", userID)
+
+	if products, err := s.products.Get(ctx, cacheKey); err == nil {
+		return products, nil
+	}
+
+	all, err := s.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	products := []entity.Product{}
+	for _, product := range all {
+		if product.UserID == userID {
+			products = append(products, product)
+		}
+	}
+
+	if err := s.products.Set(ctx, cacheKey, products, s.listTTL, productsTag, userProductsTag(userID)); err != nil {
+		s.log(ctx).WarnContext(ctx, "failed to set cache", "key", cacheKey, "error", err)
+	}
+
+	return products, nil
+}
+
+// FindAfter читает продукты после курсора напрямую из хранилища: потоковая
+// выдача проходит весь набор один раз, и кешировать ее страницы незачем.
+func (s *productService) FindAfter(ctx context.Context, afterID, limit int) ([]entity.Product, error) {
+	ctx, span := tracer.Start(ctx, "productService.FindAfter")
+	defer span.End()
+
+	products, err := s.repo.FindAfter(ctx, afterID, limit)
+	if errors.Is(err, repository.ErrInvalidPage) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+	return products, nil
+}
+
+// notify отправляет событие об изменении продукта, если задан получатель.
+func (s *productService) notify(eventType entity.ProductEventType, product entity.Product, at time.Time) {
+	if s.productEvents != nil {
+		s.productEvents(entity.ProductEvent{Type: eventType, Product: product, OccurredAt: at})
+	}
+}
+
+// productUpdateEvents возвращает событие обновления продукта и, если цена
+// изменилась, событие изменения цены.
+func productUpdateEvents(previous, updated entity.Product) ([]entity.DomainEvent, error) {
+	event, err := entity.NewDomainEvent(entity.EventProductUpdated, entity.AggregateProduct, updated.ID, updated, updated.UpdatedAt)
+	if err != nil || previous.Price == updated.Price {
+		return []entity.DomainEvent{event}, err
+	}
+	change := entity.ProductPriceChange{ProductID: updated.ID, OldPrice: previous.Price, NewPrice: updated.Price}
+	priceEvent, err := entity.NewDomainEvent(entity.EventProductPriceChanged, entity.AggregateProduct, updated.ID, change, updated.UpdatedAt)
+	return []entity.DomainEvent{event, priceEvent}, err
+}
@@ -0,0 +1,520 @@
+package service
+
+import (
+	"Projectapirest/internal/clock"
+	"Projectapirest/internal/entity"
+	"Projectapirest/internal/repository"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// newTestProductService собирает сервис поверх fakeCache.
+func newTestProductService(repo repository.ProductRepositoryInterface, c *fakeCache) ProductService {
+	return NewProductService(repo, WithCache(c))
+}
+
+func TestProductServiceFindByID(t *testing.T) {
+	book := entity.Product{Name: "book", Price: 10}
+
+	tests := []struct {
+		name      string
+		prepare   func(c *fakeCache, repo *fakeProductRepo)
+		id        int
+		wantErr   error
+		wantName  string
+		wantCalls int
+		wantCache bool
+	}{
+		{
+			name:      "miss loads from repository and fills cache",
+			id:        1,
+			wantName:  "book",
+			wantCalls: 1,
+			wantCache: true,
+		},
+		{
+			name: "hit skips repository",
+			prepare: func(c *fakeCache, _ *fakeProductRepo) {
+				c.put("product:1", mustJSON(t, entity.Product{ID: 1, Name: "cached"}))
+			},
+			id:        1,
+			wantName:  "cached",
+			wantCalls: 0,
+			wantCache: true,
+		},
+		{
+			name: "corrupted payload falls back to repository and is overwritten",
+			prepare: func(c *fakeCache, _ *fakeProductRepo) {
+				c.put("product:1", []byte("\x00\x01"))
+			},
+			id:        1,
+			wantName:  "book",
+			wantCalls: 1,
+			wantCache: true,
+		},
+		{
+			name: "cache failure falls back to repository",
+			prepare: func(c *fakeCache, _ *fakeProductRepo) {
+				c.getErr = errBoom
+			},
+			id:        1,
+			wantName:  "book",
+			wantCalls: 1,
+			wantCache: true,
+		},
+		{
+			name:      "not found is reported and cached",
+			id:        2,
+			wantErr:   repository.ErrNotFound,
+			wantCalls: 1,
+		},
+		{
+			name: "negative entry skips repository",
+			prepare: func(c *fakeCache, _ *fakeProductRepo) {
+				c.put(missingProductKey(2), []byte{1})
+			},
+			id:        2,
+			wantErr:   repository.ErrNotFound,
+			wantCalls: 0,
+		},
+		{
+			name: "repository error is propagated",
+			prepare: func(_ *fakeCache, repo *fakeProductRepo) {
+				repo.err = errBoom
+			},
+			id:        1,
+			wantErr:   errBoom,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCache()
+			repo := newFakeProductRepo(book)
+			if tt.prepare != nil {
+				tt.prepare(c, repo)
+			}
+			svc := newTestProductService(repo, c)
+
+			product, err := svc.FindByID(context.Background(), tt.id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if product.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", product.Name, tt.wantName)
+			}
+			if got := repo.calls["FindByID"]; got != tt.wantCalls {
+				t.Errorf("repository calls = %d, want %d", got, tt.wantCalls)
+			}
+			c.getErr = nil
+			if tt.wantCache {
+				if _, err := svc.(*productService).product.Get(context.Background(), "product:1"); err != nil {
+					t.Errorf("cached value is not decodable: %v", err)
+				}
+			}
+			if errors.Is(tt.wantErr, repository.ErrNotFound) && !c.has(missingProductKey(tt.id)) {
+				t.Error("missing product was not cached")
+			}
+			if errors.Is(tt.wantErr, errBoom) && c.has(missingProductKey(tt.id)) {
+				t.Error("infrastructure error was cached as missing product")
+			}
+		})
+	}
+}
+
+func TestProductServiceFindAll(t *testing.T) {
+	tests := []struct {
+		name      string
+		prepare   func(c *fakeCache, repo *fakeProductRepo)
+		wantErr   error
+		wantLen   int
+		wantCalls int
+	}{
+		{name: "miss loads from repository", wantLen: 2, wantCalls: 1},
+		{
+			name: "hit skips repository",
+			prepare: func(c *fakeCache, _ *fakeProductRepo) {
+				c.put("products:all", mustJSON(t, []entity.Product{{ID: 9}}))
+			},
+			wantLen:   1,
+			wantCalls: 0,
+		},
+		{
+			name: "corrupted payload falls back to repository",
+			prepare: func(c *fakeCache, _ *fakeProductRepo) {
+				c.put("products:all", []byte(`{"ID":`))
+			},
+			wantLen:   2,
+			wantCalls: 1,
+		},
+		{
+			name: "cache write failure still returns products",
+			prepare: func(c *fakeCache, _ *fakeProductRepo) {
+				c.setErr = errBoom
+			},
+			wantLen:   2,
+			wantCalls: 1,
+		},
+		{
+			name: "repository error is propagated",
+			prepare: func(_ *fakeCache, repo *fakeProductRepo) {
+				repo.err = errBoom
+			},
+			wantErr:   errBoom,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCache()
+			repo := newFakeProductRepo(entity.Product{Name: "book", Price: 10}, entity.Product{Name: "pen", Price: 1})
+			if tt.prepare != nil {
+				tt.prepare(c, repo)
+			}
+			svc := newTestProductService(repo, c)
+
+			products, err := svc.FindAll(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if len(products) != tt.wantLen {
+				t.Errorf("len = %d, want %d", len(products), tt.wantLen)
+			}
+			if got := repo.calls["FindAll"]; got != tt.wantCalls {
+				t.Errorf("repository calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestProductServiceMutationsInvalidateCache(t *testing.T) {
+	tests := []struct {
+		name            string
+		mutate          func(svc ProductService) error
+		repoErr         error
+		wantDeleted     []string
+		wantInvalidated []string
+	}{
+		{
+			name: "create",
+			mutate: func(svc ProductService) error {
+				_, err := svc.Create(context.Background(), entity.Product{Name: "pen", Price: 1})
+				return err
+			},
+			wantDeleted:     []string{missingProductKey(2)},
+			wantInvalidated: []string{productsTag, userProductsTag(0)},
+		},
+		{
+			name: "update",
+			mutate: func(svc ProductService) error {
+				_, err := svc.Update(context.Background(), entity.Product{ID: 1, Name: "ebook", Price: 5})
+				return err
+			},
+			wantInvalidated: []string{productsTag, userProductsTag(0)},
+		},
+		{
+			name: "delete",
+			mutate: func(svc ProductService) error {
+				return svc.Delete(context.Background(), 1)
+			},
+			wantDeleted:     []string{"product:1"},
+			wantInvalidated: []string{productsTag},
+		},
+		{
+			name: "failed create keeps cache",
+			mutate: func(svc ProductService) error {
+				_, err := svc.Create(context.Background(), entity.Product{Name: "pen"})
+				return err
+			},
+			repoErr: errBoom,
+		},
+		{
+			name: "failed update keeps cache",
+			mutate: func(svc ProductService) error {
+				_, err := svc.Update(context.Background(), entity.Product{ID: 1})
+				return err
+			},
+			repoErr: errBoom,
+		},
+		{
+			name: "failed delete keeps cache",
+			mutate: func(svc ProductService) error {
+				return svc.Delete(context.Background(), 1)
+			},
+			repoErr: errBoom,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCache()
+			repo := newFakeProductRepo(entity.Product{Name: "book", Price: 10})
+			svc := newTestProductService(repo, c)
+
+			// Прогреваем кеш, чтобы проверить, что списки действительно сбрасываются
+			if _, err := svc.FindAll(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			repo.err = tt.repoErr
+
+			err := tt.mutate(svc)
+			if !errors.Is(err, tt.repoErr) {
+				t.Fatalf("error = %v, want %v", err, tt.repoErr)
+			}
+			if !equalStrings(c.deleted, tt.wantDeleted) {
+				t.Errorf("deleted keys = %v, want %v", c.deleted, tt.wantDeleted)
+			}
+			if !equalStrings(c.invalidated, tt.wantInvalidated) {
+				t.Errorf("invalidated tags = %v, want %v", c.invalidated, tt.wantInvalidated)
+			}
+			if got, want := c.has("products:all"), tt.repoErr != nil; got != want {
+				t.Errorf("products:all cached = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestProductServiceUpdateCachesStoredState(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeCache()
+	repo := newFakeProductRepo(entity.Product{Name: "book", Price: 10})
+	svc := newTestProductService(repo, c)
+
+	stored, err := repo.ProductRepositoryInterface.FindByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := svc.Update(ctx, entity.Product{ID: 1, Name: "ebook", Price: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.CreatedAt.Equal(stored.CreatedAt) {
+		t.Errorf("CreatedAt = %v, want stored %v", updated.CreatedAt, stored.CreatedAt)
+	}
+
+	// Следующее чтение обслуживается из кеша и видит сохраненное состояние
+	got, err := svc.FindByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != updated {
+		t.Errorf("FindByID = %+v, want %+v", got, updated)
+	}
+	if calls := repo.calls["FindByID"]; calls != 0 {
+		t.Errorf("repository FindByID calls = %d, want 0", calls)
+	}
+}
+
+func TestProductServiceMutationsReportNotFound(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeCache()
+	repo := newFakeProductRepo(entity.Product{Name: "book", Price: 10})
+	svc := newTestProductService(repo, c)
+
+	if _, err := svc.Update(ctx, entity.Product{ID: 404}); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Update(missing) error = %v, want ErrNotFound", err)
+	}
+	if err := svc.Delete(ctx, 404); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Delete(missing) error = %v, want ErrNotFound", err)
+	}
+	if len(c.deleted) != 0 || len(c.invalidated) != 0 {
+		t.Errorf("cache touched on not found: deleted %v, invalidated %v", c.deleted, c.invalidated)
+	}
+}
+
+func TestProductServiceFindPage(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeCache()
+	repo := newFakeProductRepo(entity.Product{Name: "book", Price: 10}, entity.Product{Name: "pen", Price: 1})
+	svc := newTestProductService(repo, c)
+
+	for i := 0; i < 2; i++ {
+		products, err := svc.FindPage(ctx, 1, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(products) != 1 || products[0].Name != "pen" {
+			t.Errorf("call %d: FindPage = %+v, want pen", i, products)
+		}
+	}
+	if got := repo.calls["FindPage"]; got != 1 {
+		t.Errorf("repository calls = %d, want 1", got)
+	}
+
+	if err := svc.Delete(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if c.has("products:page:1:1") {
+		t.Error("page was not invalidated by delete")
+	}
+
+	repo.err = errBoom
+	if _, err := svc.FindPage(ctx, 1, 0); !errors.Is(err, errBoom) {
+		t.Errorf("repository error = %v, want errBoom", err)
+	}
+}
+
+func TestProductServiceFindByUser(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore(clock.Real{})
+	users := repository.NewMemoryUserRepository(store)
+	repo := &fakeProductRepo{ProductRepositoryInterface: repository.NewMemoryProductRepository(store), calls: make(map[string]int)}
+	for _, email := range []string{"ann@example.com", "bob@example.com"} {
+		if _, err := users.Create(ctx, entity.User{Name: "user", Email: email}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, product := range []entity.Product{{Name: "book", UserID: 1}, {Name: "pen", UserID: 2}, {Name: "lamp", UserID: 1}} {
+		if _, err := repo.ProductRepositoryInterface.Create(ctx, product); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := newFakeCache()
+	svc := newTestProductService(repo, c)
+
+	for i := 0; i < 2; i++ {
+		products, err := svc.FindByUser(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(products) != 2 || products[0].Name != "book" || products[1].Name != "lamp" {
+			t.Errorf("call %d: FindByUser = %+v, want book and lamp", i, products)
+		}
+	}
+	if got := repo.calls["FindAll"]; got != 1 {
+		t.Errorf("repository calls = %d, want 1", got)
+	}
+
+	// Список пользователя сбрасывается по его тегу
+	if !c.has("products:user:1") {
+		t.Fatal("user list was not cached")
+	}
+	if err := c.InvalidateTag(ctx, userProductsTag(1)); err != nil {
+		t.Fatal(err)
+	}
+	if c.has("products:user:1") {
+		t.Error("user list survived invalidation of its user tag")
+	}
+
+	products, err := svc.FindByUser(ctx, 3)
+	if err != nil || products == nil || len(products) != 0 {
+		t.Errorf("FindByUser(3) = %+v, %v, want empty list", products, err)
+	}
+}
+
+func TestProductServiceFindAfterBypassesCache(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeProductRepo(entity.Product{Name: "book"}, entity.Product{Name: "pen"}, entity.Product{Name: "lamp"})
+	svc := newTestProductService(repo, newFakeCache())
+
+	for i := 0; i < 2; i++ {
+		products, err := svc.FindAfter(ctx, 1, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(products) != 1 || products[0].Name != "pen" {
+			t.Errorf("FindAfter = %+v, want pen", products)
+		}
+	}
+	if got := repo.calls["FindAfter"]; got != 2 {
+		t.Errorf("repository calls = %d, want 2", got)
+	}
+
+	repo.err = errBoom
+	if _, err := svc.FindAfter(ctx, 0, 10); !errors.Is(err, errBoom) {
+		t.Errorf("repository error = %v, want errBoom", err)
+	}
+}
+
+func TestProductServicePublishesEvents(t *testing.T) {
+	ctx := context.Background()
+	var events []entity.ProductEvent
+	repo := newFakeProductRepo()
+	svc := NewProductService(repo, WithCache(newFakeCache()), WithProductEvents(func(e entity.ProductEvent) {
+		events = append(events, e)
+	}))
+
+	created, err := svc.Create(ctx, entity.Product{Name: "book", Price: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Update(ctx, entity.Product{ID: created.ID, Name: "ebook", Price: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Delete(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	// Неудачные изменения событий не порождают
+	if err := svc.Delete(ctx, created.ID); err == nil {
+		t.Fatal("second delete succeeded")
+	}
+
+	want := []struct {
+		typ  entity.ProductEventType
+		name string
+	}{{entity.ProductCreated, "book"}, {entity.ProductUpdated, "ebook"}, {entity.ProductDeleted, ""}}
+	if len(events) != len(want) {
+		t.Fatalf("events = %+v, want %d events", events, len(want))
+	}
+	for i, w := range want {
+		if events[i].Type != w.typ || events[i].Product.ID != created.ID || events[i].Product.Name != w.name || events[i].OccurredAt.IsZero() {
+			t.Errorf("event %d = %+v, want %s of %q", i, events[i], w.typ, w.name)
+		}
+	}
+}
+
+func TestProductServiceWritesDomainEvents(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore(clock.Real{})
+	outbox := repository.NewMemoryOutboxRepository(store)
+	svc := NewProductService(repository.NewMemoryProductRepository(store),
+		WithCache(newFakeCache()), WithOutbox(repository.NewMemoryTransactor(store)))
+
+	created, err := svc.Create(ctx, entity.Product{Name: "book", Price: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Событие об изменении цены появляется, только когда цена изменилась
+	if _, err := svc.Update(ctx, entity.Product{ID: created.ID, Name: "ebook", Price: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Update(ctx, entity.Product{ID: created.ID, Name: "ebook", Price: 12.5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Delete(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	// Неудачные изменения событий не оставляют
+	if _, err := svc.Update(ctx, entity.Product{ID: created.ID, Name: "gone"}); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Update(deleted) error = %v, want ErrNotFound", err)
+	}
+
+	events := claimEvents(t, outbox)
+	want := []entity.DomainEventType{
+		entity.EventProductCreated,
+		entity.EventProductUpdated,
+		entity.EventProductUpdated,
+		entity.EventProductPriceChanged,
+		entity.EventProductDeleted,
+	}
+	if got := eventTypes(events); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	for _, event := range events {
+		if event.AggregateType != entity.AggregateProduct || event.AggregateID != created.ID {
+			t.Errorf("event %s belongs to %s %d, want product %d", event.Type, event.AggregateType, event.AggregateID, created.ID)
+		}
+	}
+	var change entity.ProductPriceChange
+	if err := json.Unmarshal(events[3].Payload, &change); err != nil {
+		t.Fatal(err)
+	}
+	if change != (entity.ProductPriceChange{ProductID: created.ID, OldPrice: 10, NewPrice: 12.5}) {
+		t.Errorf("price change = %+v", change)
+	}
+}
@@ -0,0 +1,5 @@
+package service
+
+import "go.opentelemetry.io/otel"
+
+var tracer = otel.Tracer("Projectapirest/internal/services")
@@ -4,6 +4,7 @@ import (
 	"Projectapirest/internal/cache"
 	"Projectapirest/internal/entity"
 	"Projectapirest/internal/repository"
+	"Projectapirest/internal/tracing"
 	"context"
 	"encoding/json"
 	"errors"
@@ -19,6 +20,11 @@ type UserService interface {
 	Update(ctx context.Context, user entity.User) (entity.User, error)
 	Delete(ctx context.Context, id int) error
 	FindAll(ctx context.Context) ([]entity.User, error)
+	// FindPage возвращает не более limit записей, пропустив первые offset.
+	FindPage(ctx context.Context, limit, offset int) ([]entity.User, error)
+	// FindAfter возвращает не более limit пользователей с ID больше afterID
+	// в обход кеша; используется для потоковой выдачи.
+	FindAfter(ctx context.Context, afterID, limit int) ([]entity.User, error)
 }
 
 // DecodeUserRequestBody десериализует тело запроса в структуру.
@@ -34,115 +40,235 @@ func EncodeUserResponse(w http.ResponseWriter, v interface{}, statusCode int) {
 	json.NewEncoder(w).Encode(v)
 }
 
+// errUserNotFound возвращается, когда пользователь отсутствует.
+var errUserNotFound = fmt.Errorf("пользователь не найден: %w", repository.ErrNotFound)
+
+// usersTag помечает в кеше все списки пользователей.
+const usersTag = "users"
+
+// Время жизни записей кеша пользователей по умолчанию.
+const (
+	userTTL  = 60 * time.Second
+	usersTTL = 300 * time.Second
+)
+
+// missingUserKey возвращает ключ негативной записи кеша для пользователя.
+func missingUserKey(id int) string {
+	return fmt.Sprintf("user:%d:missing", id)
+}
+
+// userService реализует интерфейс UserService.
 type userService struct {
-	repo  repository.UserRepository
-	cache cache.Cache
+	options
+	repo  repository.UserRepositoryInterface
+	user  *cache.TypedCache[entity.User]
+	users *cache.TypedCache[[]entity.User]
 }
 
-func NewUserService(repo repository.UserRepository, cache cache.Cache) UserService {
+// NewUserService создает новый экземпляр userService.
+func NewUserService(repo repository.UserRepositoryInterface, opts ...Option) UserService {
+	o := newOptions(options{
+		entityTTL:  userTTL,
+		listTTL:    usersTTL,
+		missingTTL: missingTTL,
+	}, opts)
+
 	return &userService{
-		repo:  repo,
-		cache: cache,
+		options: o,
+		repo:    repo,
+		user:    cache.NewTypedCache[entity.User](o.cache, cache.JSONCodec{}),
+		users:   cache.NewTypedCache[[]entity.User](o.cache, cache.JSONCodec{}),
 	}
 }
 
 // Create добавляет нового пользователя.
 func (s *userService) Create(ctx context.Context, user entity.User) (entity.User, error) {
-	user.CreatedAt = time.Now()
-	user.UpdatedAt = time.Now()
+	ctx, span := tracer.Start(ctx, "userService.Create")
+	defer span.End()
 
-	createdUser, err := s.repo.Create(ctx, user)
+	var createdUser entity.User
+	err := s.write(ctx, repository.Tx{Users: s.repo}, func(ctx context.Context, tx repository.Tx) ([]entity.DomainEvent, error) {
+		var err error
+		createdUser, err = tx.Users.Create(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		event, err := entity.NewDomainEvent(entity.EventUserCreated, entity.AggregateUser, createdUser.ID, createdUser, createdUser.CreatedAt)
+		return []entity.DomainEvent{event}, err
+	})
 	if err != nil {
-		return entity.User{}, err
+		return entity.User{}, tracing.RecordError(span, err)
 	}
 
-	// Инвалидация кеша списка пользователей
-	_ = s.cache.Delete("users:all")
+	// Инвалидация негативной записи и кеша списков пользователей
+	_ = s.cache.Delete(ctx, missingUserKey(createdUser.ID))
+	_ = s.cache.InvalidateTag(ctx, usersTag)
 
 	return createdUser, nil
 }
 
 // FindByID находит пользователя по ID.
 func (s *userService) FindByID(ctx context.Context, id int) (entity.User, error) {
+	ctx, span := tracer.Start(ctx, "userService.FindByID")
+	defer span.End()
+
 	cacheKey := fmt.Sprintf("user:%d", id)
 
 	// Попытка извлечь из кеша
-	if cached, err := s.cache.Get(cacheKey); err == nil && cached != "" {
-		var user entity.User
-		if err := json.Unmarshal([]byte(cached), &user); err == nil {
-			return user, nil
-		}
+	if user, err := s.user.Get(ctx, cacheKey); err == nil {
+		return user, nil
+	}
+	if _, err := s.cache.Get(ctx, missingUserKey(id)); err == nil {
+		return entity.User{}, errUserNotFound
 	}
 
 	// Если не найдено в кеше, обращаемся к репозиторию
 	user, err := s.repo.FindByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		// Запоминаем отсутствие пользователя; ошибки инфраструктуры не кешируются
+		_ = s.cache.Set(ctx, missingUserKey(id), []byte{1}, s.missingTTL)
+		return entity.User{}, errUserNotFound
+	}
 	if err != nil {
-		return entity.User{}, errors.New("пользователь не найден")
+		return entity.User{}, tracing.RecordError(span, err)
 	}
 
 	// Сохраняем результат в кеш
-	userJSON, _ := json.Marshal(user)
-	if err := s.cache.Set(cacheKey, string(userJSON), 60); err != nil {
-		fmt.Printf("Failed to set cache for user %d: %v\n", id, err)
+	if err := s.user.Set(ctx, cacheKey, user, s.entityTTL); err != nil {
+		s.log(ctx).WarnContext(ctx, "failed to set cache", "key", cacheKey, "error", err)
 	}
 
 	return user, nil
 }
 
-// Update обновляет информацию о пользователе.
+// Update обновляет информацию о пользователе и возвращает сохраненное состояние.
 func (s *userService) Update(ctx context.Context, user entity.User) (entity.User, error) {
-	user.UpdatedAt = time.Now()
+	ctx, span := tracer.Start(ctx, "userService.Update")
+	defer span.End()
 
-	err := s.repo.Update(ctx, user)
+	var updated entity.User
+	err := s.write(ctx, repository.Tx{Users: s.repo}, func(ctx context.Context, tx repository.Tx) ([]entity.DomainEvent, error) {
+		var err error
+		updated, err = tx.Users.Update(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		event, err := entity.NewDomainEvent(entity.EventUserUpdated, entity.AggregateUser, updated.ID, updated, updated.UpdatedAt)
+		return []entity.DomainEvent{event}, err
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return entity.User{}, errUserNotFound
+	}
 	if err != nil {
-		return entity.User{}, err
+		return entity.User{}, tracing.RecordError(span, err)
 	}
 
-	// Инвалидация кеша пользователя и списка пользователей
-	cacheKey := fmt.Sprintf("user:%d", user.ID)
-	_ = s.cache.Delete(cacheKey)
-	_ = s.cache.Delete("users:all")
+	// Кешируем сохраненное состояние; если запись не удалась, удаляем
+	// ключ, чтобы не отдавать устаревшие данные
+	cacheKey := fmt.Sprintf("user:%d", updated.ID)
+	if err := s.user.Set(ctx, cacheKey, updated, s.entityTTL); err != nil {
+		s.log(ctx).WarnContext(ctx, "failed to set cache", "key", cacheKey, "error", err)
+		_ = s.cache.Delete(ctx, cacheKey)
+	}
+	_ = s.cache.InvalidateTag(ctx, usersTag)
 
-	return user, nil
+	return updated, nil
 }
 
 // Delete удаляет пользователя.
 func (s *userService) Delete(ctx context.Context, id int) error {
-	if err := s.repo.Delete(ctx, id); err != nil {
-		return err
+	ctx, span := tracer.Start(ctx, "userService.Delete")
+	defer span.End()
+
+	err := s.write(ctx, repository.Tx{Users: s.repo}, func(ctx context.Context, tx repository.Tx) ([]entity.DomainEvent, error) {
+		if err := tx.Users.Delete(ctx, id); err != nil {
+			return nil, err
+		}
+		event, err := entity.NewDomainEvent(entity.EventUserDeleted, entity.AggregateUser, id, entity.Deleted{ID: id}, s.clock.Now().UTC())
+		return []entity.DomainEvent{event}, err
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return errUserNotFound
+	}
+	if err != nil {
+		return tracing.RecordError(span, err)
 	}
 
-	// Удаляем из кеша пользователя и списка пользователей
+	// Удаляем из кеша пользователя, списки пользователей, его продуктов
+	// и проверенные API-ключи, удаленные вместе с ним
 	cacheKey := fmt.Sprintf("user:%d", id)
-	_ = s.cache.Delete(cacheKey)
-	_ = s.cache.Delete("users:all")
+	_ = s.cache.Delete(ctx, cacheKey)
+	_ = s.cache.InvalidateTag(ctx, usersTag)
+	_ = s.cache.InvalidateTag(ctx, userProductsTag(id))
+	_ = s.cache.InvalidateTag(ctx, userAPIKeysTag(id))
 
 	return nil
 }
 
 // FindAll возвращает всех пользователей.
 func (s *userService) FindAll(ctx context.Context) ([]entity.User, error) {
+	ctx, span := tracer.Start(ctx, "userService.FindAll")
+	defer span.End()
+
 	cacheKey := "users:all"
 
 	// Попытка извлечь данные из кеша
-	if cached, err := s.cache.Get(cacheKey); err == nil && cached != "" {
-		var users []entity.User
-		if err := json.Unmarshal([]byte(cached), &users); err == nil {
-			return users, nil
-		}
+	if users, err := s.users.Get(ctx, cacheKey); err == nil {
+		return users, nil
 	}
 
 	// Если кеш пуст, извлекаем данные из репозитория
 	users, err := s.repo.FindAll(ctx)
 	if err != nil {
-		return nil, err
+		return nil, tracing.RecordError(span, err)
 	}
 
 	// Сохраняем результат в кеш
-	usersJSON, _ := json.Marshal(users)
-	if err := s.cache.Set(cacheKey, string(usersJSON), 300); err != nil {
-		fmt.Printf("Failed to set cache for FindAll: %v\n", err)
+	if err := s.users.Set(ctx, cacheKey, users, s.listTTL, usersTag); err != nil {
+		s.log(ctx).WarnContext(ctx, "failed to set cache", "key", cacheKey, "error", err)
 	}
 
 	return users, nil
 }
+
+// FindPage возвращает страницу пользователей. Страницы кешируются с тем же тегом,
+// что и полный список, поэтому сбрасываются вместе с ним.
+func (s *userService) FindPage(ctx context.Context, limit, offset int) ([]entity.User, error) {
+	ctx, span := tracer.Start(ctx, "userService.FindPage")
+	defer span.End()
+
+	cacheKey := fmt.Sprintf("users:page:%d:%d", limit, offset)
+
+	if users, err := s.users.Get(ctx, cacheKey); err == nil {
+		return users, nil
+	}
+
+	users, err := s.repo.FindPage(ctx, limit, offset)
+	if errors.Is(err, repository.ErrInvalidPage) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	if err := s.users.Set(ctx, cacheKey, users, s.listTTL, usersTag); err != nil {
+		s.log(ctx).WarnContext(ctx, "failed to set cache", "key", cacheKey, "error", err)
+	}
+
+	return users, nil
+}
+
+// FindAfter читает пользователей после курсора напрямую из хранилища.
+func (s *userService) FindAfter(ctx context.Context, afterID, limit int) ([]entity.User, error) {
+	ctx, span := tracer.Start(ctx, "userService.FindAfter")
+	defer span.End()
+
+	users, err := s.repo.FindAfter(ctx, afterID, limit)
+	if errors.Is(err, repository.ErrInvalidPage) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+	return users, nil
+}
@@ -0,0 +1,414 @@
+package service
+
+import (
+	"Projectapirest/internal/clock"
+	"Projectapirest/internal/entity"
+	"Projectapirest/internal/repository"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestUserServiceFindByID(t *testing.T) {
+	alice := entity.User{Name: "alice", Email: "alice@example.com"}
+
+	tests := []struct {
+		name string
+		// prepare настраивает кеш и репозиторий перед вызовом
+		prepare   func(c *fakeCache, repo *fakeUserRepo)
+		id        int
+		wantErr   error
+		wantName  string
+		wantCalls int  // ожидаемое число обращений к репозиторию
+		wantCache bool // ожидается ли запись user:<id> в кеше после вызова
+	}{
+		{
+			name:      "miss loads from repository and fills cache",
+			id:        1,
+			wantName:  "alice",
+			wantCalls: 1,
+			wantCache: true,
+		},
+		{
+			name: "hit skips repository",
+			prepare: func(c *fakeCache, _ *fakeUserRepo) {
+				c.put("user:1", mustJSON(t, entity.User{ID: 1, Name: "cached"}))
+			},
+			id:        1,
+			wantName:  "cached",
+			wantCalls: 0,
+			wantCache: true,
+		},
+		{
+			name: "corrupted payload falls back to repository and is overwritten",
+			prepare: func(c *fakeCache, _ *fakeUserRepo) {
+				c.put("user:1", []byte("{not json"))
+			},
+			id:        1,
+			wantName:  "alice",
+			wantCalls: 1,
+			wantCache: true,
+		},
+		{
+			name: "cache failure falls back to repository",
+			prepare: func(c *fakeCache, _ *fakeUserRepo) {
+				c.getErr = errBoom
+			},
+			id:        1,
+			wantName:  "alice",
+			wantCalls: 1,
+			wantCache: true,
+		},
+		{
+			name:      "not found is reported",
+			id:        2,
+			wantErr:   repository.ErrNotFound,
+			wantCalls: 1,
+		},
+		{
+			name: "negative entry skips repository",
+			prepare: func(c *fakeCache, _ *fakeUserRepo) {
+				c.put(missingUserKey(2), []byte{1})
+			},
+			id:        2,
+			wantErr:   repository.ErrNotFound,
+			wantCalls: 0,
+		},
+		{
+			name: "repository error is propagated",
+			prepare: func(_ *fakeCache, repo *fakeUserRepo) {
+				repo.err = errBoom
+			},
+			id:        1,
+			wantErr:   errBoom,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCache()
+			repo := newFakeUserRepo(alice)
+			if tt.prepare != nil {
+				tt.prepare(c, repo)
+			}
+			svc := NewUserService(repo, WithCache(c))
+
+			user, err := svc.FindByID(context.Background(), tt.id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if user.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", user.Name, tt.wantName)
+			}
+			if got := repo.calls["FindByID"]; got != tt.wantCalls {
+				t.Errorf("repository calls = %d, want %d", got, tt.wantCalls)
+			}
+			c.getErr = nil
+			if got := c.has("user:1"); tt.id == 1 && got != tt.wantCache {
+				t.Errorf("cached = %v, want %v", got, tt.wantCache)
+			}
+			if tt.wantCache {
+				if _, err := svc.(*userService).user.Get(context.Background(), "user:1"); err != nil {
+					t.Errorf("cached value is not decodable: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestUserServiceCachesNotFound(t *testing.T) {
+	c := newFakeCache()
+	repo := newFakeUserRepo()
+	svc := NewUserService(repo, WithCache(c))
+
+	for i := 0; i < 2; i++ {
+		if _, err := svc.FindByID(context.Background(), 7); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("call %d: error = %v, want ErrNotFound", i, err)
+		}
+	}
+	if got := repo.calls["FindByID"]; got != 1 {
+		t.Errorf("repository calls = %d, want 1", got)
+	}
+}
+
+func TestUserServiceDoesNotCacheRepositoryErrors(t *testing.T) {
+	c := newFakeCache()
+	repo := newFakeUserRepo()
+	repo.err = errBoom
+	svc := NewUserService(repo, WithCache(c))
+
+	if _, err := svc.FindByID(context.Background(), 7); !errors.Is(err, errBoom) {
+		t.Fatalf("error = %v, want errBoom", err)
+	}
+	if c.has(missingUserKey(7)) {
+		t.Error("infrastructure error was cached as missing user")
+	}
+}
+
+func TestUserServiceFindAll(t *testing.T) {
+	tests := []struct {
+		name      string
+		prepare   func(c *fakeCache, repo *fakeUserRepo)
+		wantErr   error
+		wantLen   int
+		wantCalls int
+	}{
+		{name: "miss loads from repository", wantLen: 2, wantCalls: 1},
+		{
+			name: "hit skips repository",
+			prepare: func(c *fakeCache, _ *fakeUserRepo) {
+				c.put("users:all", mustJSON(t, []entity.User{{ID: 9}}))
+			},
+			wantLen:   1,
+			wantCalls: 0,
+		},
+		{
+			name: "corrupted payload falls back to repository",
+			prepare: func(c *fakeCache, _ *fakeUserRepo) {
+				c.put("users:all", []byte("garbage"))
+			},
+			wantLen:   2,
+			wantCalls: 1,
+		},
+		{
+			name: "cache write failure still returns users",
+			prepare: func(c *fakeCache, _ *fakeUserRepo) {
+				c.setErr = errBoom
+			},
+			wantLen:   2,
+			wantCalls: 1,
+		},
+		{
+			name: "repository error is propagated",
+			prepare: func(_ *fakeCache, repo *fakeUserRepo) {
+				repo.err = errBoom
+			},
+			wantErr:   errBoom,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCache()
+			repo := newFakeUserRepo(
+				entity.User{Name: "alice", Email: "alice@example.com"},
+				entity.User{Name: "bob", Email: "bob@example.com"},
+			)
+			if tt.prepare != nil {
+				tt.prepare(c, repo)
+			}
+			svc := NewUserService(repo, WithCache(c))
+
+			users, err := svc.FindAll(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if len(users) != tt.wantLen {
+				t.Errorf("len = %d, want %d", len(users), tt.wantLen)
+			}
+			if got := repo.calls["FindAll"]; got != tt.wantCalls {
+				t.Errorf("repository calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestUserServiceMutationsInvalidateCache(t *testing.T) {
+	tests := []struct {
+		name            string
+		mutate          func(svc UserService) error
+		repoErr         error
+		wantDeleted     []string
+		wantInvalidated []string
+	}{
+		{
+			name: "create",
+			mutate: func(svc UserService) error {
+				_, err := svc.Create(context.Background(), entity.User{Name: "carol", Email: "carol@example.com"})
+				return err
+			},
+			wantDeleted:     []string{missingUserKey(2)},
+			wantInvalidated: []string{usersTag},
+		},
+		{
+			name: "update",
+			mutate: func(svc UserService) error {
+				_, err := svc.Update(context.Background(), entity.User{ID: 1, Name: "alice2", Email: "alice@example.com"})
+				return err
+			},
+			wantInvalidated: []string{usersTag},
+		},
+		{
+			name: "delete",
+			mutate: func(svc UserService) error {
+				return svc.Delete(context.Background(), 1)
+			},
+			wantDeleted:     []string{"user:1"},
+			wantInvalidated: []string{usersTag, userProductsTag(1), userAPIKeysTag(1)},
+		},
+		{
+			name: "failed update keeps cache",
+			mutate: func(svc UserService) error {
+				_, err := svc.Update(context.Background(), entity.User{ID: 1})
+				return err
+			},
+			repoErr: errBoom,
+		},
+		{
+			name: "failed delete keeps cache",
+			mutate: func(svc UserService) error {
+				return svc.Delete(context.Background(), 1)
+			},
+			repoErr: errBoom,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCache()
+			repo := newFakeUserRepo(entity.User{Name: "alice", Email: "alice@example.com"})
+			svc := NewUserService(repo, WithCache(c))
+
+			// Прогреваем кеш, чтобы проверить, что списки действительно сбрасываются
+			if _, err := svc.FindAll(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			repo.err = tt.repoErr
+
+			err := tt.mutate(svc)
+			if !errors.Is(err, tt.repoErr) {
+				t.Fatalf("error = %v, want %v", err, tt.repoErr)
+			}
+			if !equalStrings(c.deleted, tt.wantDeleted) {
+				t.Errorf("deleted keys = %v, want %v", c.deleted, tt.wantDeleted)
+			}
+			if !equalStrings(c.invalidated, tt.wantInvalidated) {
+				t.Errorf("invalidated tags = %v, want %v", c.invalidated, tt.wantInvalidated)
+			}
+			if got, want := c.has("users:all"), tt.repoErr != nil; got != want {
+				t.Errorf("users:all cached = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestUserServiceUpdateCachesStoredState(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeCache()
+	repo := newFakeUserRepo(entity.User{Name: "alice", Email: "alice@example.com"})
+	svc := NewUserService(repo, WithCache(c))
+
+	stored, err := repo.UserRepositoryInterface.FindByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := svc.Update(ctx, entity.User{ID: 1, Name: "alice2", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.CreatedAt.Equal(stored.CreatedAt) {
+		t.Errorf("CreatedAt = %v, want stored %v", updated.CreatedAt, stored.CreatedAt)
+	}
+
+	// Следующее чтение обслуживается из кеша и видит сохраненное состояние
+	got, err := svc.FindByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != updated {
+		t.Errorf("FindByID = %+v, want %+v", got, updated)
+	}
+	if calls := repo.calls["FindByID"]; calls != 0 {
+		t.Errorf("repository FindByID calls = %d, want 0", calls)
+	}
+}
+
+func TestUserServiceMutationsReportNotFound(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeCache()
+	repo := newFakeUserRepo(entity.User{Name: "alice", Email: "alice@example.com"})
+	svc := NewUserService(repo, WithCache(c))
+
+	if _, err := svc.Update(ctx, entity.User{ID: 404}); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Update(missing) error = %v, want ErrNotFound", err)
+	}
+	if err := svc.Delete(ctx, 404); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Delete(missing) error = %v, want ErrNotFound", err)
+	}
+	if len(c.deleted) != 0 || len(c.invalidated) != 0 {
+		t.Errorf("cache touched on not found: deleted %v, invalidated %v", c.deleted, c.invalidated)
+	}
+}
+
+func TestUserServiceFindPage(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeCache()
+	repo := newFakeUserRepo(
+		entity.User{Name: "alice", Email: "alice@example.com"},
+		entity.User{Name: "bob", Email: "bob@example.com"},
+		entity.User{Name: "carol", Email: "carol@example.com"},
+	)
+	svc := NewUserService(repo, WithCache(c))
+
+	for i := 0; i < 2; i++ {
+		users, err := svc.FindPage(ctx, 2, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(users) != 2 || users[0].Name != "bob" || users[1].Name != "carol" {
+			t.Errorf("call %d: FindPage = %+v, want bob and carol", i, users)
+		}
+	}
+	if got := repo.calls["FindPage"]; got != 1 {
+		t.Errorf("repository calls = %d, want 1", got)
+	}
+
+	// Изменение пользователей сбрасывает закешированные страницы
+	if _, err := svc.Create(ctx, entity.User{Name: "dave", Email: "dave@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.has("users:page:2:1") {
+		t.Error("page was not invalidated by create")
+	}
+
+	if _, err := svc.FindPage(ctx, 0, 0); !errors.Is(err, repository.ErrInvalidPage) {
+		t.Errorf("FindPage(0, 0) error = %v, want ErrInvalidPage", err)
+	}
+}
+
+func TestUserServiceWritesDomainEvents(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore(clock.Real{})
+	outbox := repository.NewMemoryOutboxRepository(store)
+	svc := NewUserService(repository.NewMemoryUserRepository(store),
+		WithCache(newFakeCache()), WithOutbox(repository.NewMemoryTransactor(store)))
+
+	alice, err := svc.Create(ctx, entity.User{Name: "alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Create(ctx, entity.User{Name: "copy", Email: "alice@example.com"}); !errors.Is(err, repository.ErrDuplicateEmail) {
+		t.Fatalf("duplicate Create error = %v, want ErrDuplicateEmail", err)
+	}
+	alice.Name = "alicia"
+	if _, err := svc.Update(ctx, alice); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Delete(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	events := claimEvents(t, outbox)
+	want := []entity.DomainEventType{entity.EventUserCreated, entity.EventUserUpdated, entity.EventUserDeleted}
+	if got := eventTypes(events); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	var updated entity.User
+	if err := json.Unmarshal(events[1].Payload, &updated); err != nil || updated.Name != "alicia" {
+		t.Errorf("update payload = %s (%v), want alicia", events[1].Payload, err)
+	}
+}
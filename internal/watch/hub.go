@@ -0,0 +1,112 @@
+// Package watch рассылает события изменений подписчикам внутри процесса,
+// например потокам WatchProducts.
+package watch
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrSlowSubscriber сообщает, что подписчик не успевал забирать события
+// и был отключен, чтобы не задерживать остальных.
+var ErrSlowSubscriber = errors.New("watch: subscriber is too slow")
+
+// ErrClosed сообщает, что Hub закрыт, например при остановке сервера.
+var ErrClosed = errors.New("watch: hub is closed")
+
+// Hub рассылает события всем подписчикам. Publish не блокируется: каждому
+// подписчику отводится буфер, и переполнивший его подписчик отключается.
+type Hub[T any] struct {
+	mu     sync.Mutex
+	buffer int
+	subs   map[*Subscription[T]]struct{}
+	closed bool
+}
+
+// NewHub создает Hub с буфером buffer событий на подписчика.
+func NewHub[T any](buffer int) *Hub[T] {
+	return &Hub[T]{buffer: buffer, subs: make(map[*Subscription[T]]struct{})}
+}
+
+// Subscription — подписка на события Hub.
+type Subscription[T any] struct {
+	hub    *Hub[T]
+	events chan T
+	err    error
+}
+
+// Subscribe добавляет подписчика. Подписку нужно закрыть через Close.
+func (h *Hub[T]) Subscribe() *Subscription[T] {
+	sub := &Subscription[T]{hub: h, events: make(chan T, h.buffer)}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		sub.err = ErrClosed
+		close(sub.events)
+		return sub
+	}
+	h.subs[sub] = struct{}{}
+	return sub
+}
+
+// Publish отправляет событие всем подписчикам.
+func (h *Hub[T]) Publish(event T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		select {
+		case sub.events <- event:
+		default:
+			sub.err = ErrSlowSubscriber
+			h.remove(sub)
+		}
+	}
+}
+
+// Close отключает всех подписчиков с ErrClosed; новые подписки сразу
+// получают закрытый канал.
+func (h *Hub[T]) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subs {
+		sub.err = ErrClosed
+		h.remove(sub)
+	}
+}
+
+// Subscribers возвращает число активных подписчиков.
+func (h *Hub[T]) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+// remove удаляет подписчика и закрывает его канал. Вызывается под блокировкой.
+func (h *Hub[T]) remove(sub *Subscription[T]) {
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.events)
+	}
+}
+
+// Events возвращает канал событий. Канал закрывается после Close или
+// отключения медленного подписчика; причину сообщает Err.
+func (s *Subscription[T]) Events() <-chan T {
+	return s.events
+}
+
+// Err возвращает ErrSlowSubscriber или ErrClosed, если подписчик был отключен Hub.
+// Вызывается после закрытия канала Events.
+func (s *Subscription[T]) Err() error {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.err
+}
+
+// Close отменяет подписку. Повторный вызов ничего не делает.
+func (s *Subscription[T]) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
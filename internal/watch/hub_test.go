@@ -0,0 +1,69 @@
+package watch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHubFanOut(t *testing.T) {
+	hub := NewHub[int](4)
+	a, b := hub.Subscribe(), hub.Subscribe()
+	defer a.Close()
+
+	hub.Publish(1)
+	b.Close()
+	hub.Publish(2)
+
+	if got := []int{<-a.Events(), <-a.Events()}; got[0] != 1 || got[1] != 2 {
+		t.Errorf("a received %v, want [1 2]", got)
+	}
+	if got := <-b.Events(); got != 1 {
+		t.Errorf("b received %d, want 1", got)
+	}
+	if _, open := <-b.Events(); open {
+		t.Error("closed subscription still receives events")
+	}
+	if b.Err() != nil {
+		t.Errorf("Err after Close = %v, want nil", b.Err())
+	}
+	if n := hub.Subscribers(); n != 1 {
+		t.Errorf("Subscribers = %d, want 1", n)
+	}
+	b.Close()
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	hub := NewHub[int](1)
+	slow := hub.Subscribe()
+
+	hub.Publish(1)
+	hub.Publish(2) // буфер полон
+
+	if got := <-slow.Events(); got != 1 {
+		t.Errorf("received %d, want 1", got)
+	}
+	if _, open := <-slow.Events(); open {
+		t.Error("slow subscriber was not disconnected")
+	}
+	if !errors.Is(slow.Err(), ErrSlowSubscriber) {
+		t.Errorf("Err = %v, want ErrSlowSubscriber", slow.Err())
+	}
+	slow.Close()
+}
+
+func TestHubClose(t *testing.T) {
+	hub := NewHub[int](1)
+	sub := hub.Subscribe()
+	hub.Close()
+
+	for _, s := range []*Subscription[int]{sub, hub.Subscribe()} {
+		if _, open := <-s.Events(); open {
+			t.Error("subscription is open after Close")
+		}
+		if !errors.Is(s.Err(), ErrClosed) {
+			t.Errorf("Err = %v, want ErrClosed", s.Err())
+		}
+		s.Close()
+	}
+	hub.Publish(1)
+}
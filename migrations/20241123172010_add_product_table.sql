@@ -9,7 +9,9 @@ CREATE TABLE products (
                           created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
                           updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
-DROP TABLE products;
 -- +goose StatementEnd
--- +goose Down
 
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE products;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE api_keys (
+                          id SERIAL PRIMARY KEY,
+                          user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+                          name VARCHAR(100) NOT NULL,
+                          prefix VARCHAR(16) NOT NULL,
+                          key_hash BYTEA UNIQUE NOT NULL,
+                          scopes TEXT[] NOT NULL DEFAULT '{}',
+                          expires_at TIMESTAMPTZ,
+                          last_used_at TIMESTAMPTZ,
+                          revoked_at TIMESTAMPTZ,
+                          created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE api_keys;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE outbox (
+                        id BIGSERIAL PRIMARY KEY,
+                        event_type VARCHAR(100) NOT NULL,
+                        aggregate_type VARCHAR(50) NOT NULL,
+                        aggregate_id INT NOT NULL,
+                        payload JSONB NOT NULL,
+                        occurred_at TIMESTAMPTZ NOT NULL,
+                        attempts INT NOT NULL DEFAULT 0,
+                        last_error TEXT,
+                        available_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                        published_at TIMESTAMPTZ
+);
+-- Ретранслятор выбирает только недоставленные события
+CREATE INDEX outbox_pending_idx ON outbox (available_at, id) WHERE published_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE outbox;
+-- +goose StatementEnd
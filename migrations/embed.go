@@ -0,0 +1,9 @@
+// Package migrations содержит SQL-миграции goose, встроенные в бинарный файл.
+package migrations
+
+import "embed"
+
+// FS содержит все файлы миграций каталога.
+//
+//go:embed *.sql
+var FS embed.FS